@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// parsedArgs holds the positional arguments and flags given to a command
+type parsedArgs struct {
+	positional []string
+	flags      map[string][]string
+}
+
+// parseArgs separates positional arguments from --flags. Flags listed in
+// valueFlags take a value (--name value or --name=value); flags listed in
+// boolFlags take none. Anything after a bare "--" is positional.
+func parseArgs(args []string, boolFlags, valueFlags []string) (parsedArgs, error) {
+	parsed := parsedArgs{flags: make(map[string][]string)}
+
+	isBool := make(map[string]bool, len(boolFlags))
+	for _, name := range boolFlags {
+		isBool[name] = true
+	}
+	takesValue := make(map[string]bool, len(valueFlags))
+	for _, name := range valueFlags {
+		takesValue[name] = true
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			parsed.positional = append(parsed.positional, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "--") {
+			parsed.positional = append(parsed.positional, arg)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		switch {
+		case isBool[name]:
+			if hasValue {
+				return parsed, fmt.Errorf("flag --%s does not take a value", name)
+			}
+			parsed.flags[name] = append(parsed.flags[name], "true")
+		case takesValue[name]:
+			if !hasValue {
+				if i+1 >= len(args) {
+					return parsed, fmt.Errorf("flag --%s requires a value", name)
+				}
+				i++
+				value = args[i]
+			}
+			parsed.flags[name] = append(parsed.flags[name], value)
+		default:
+			return parsed, fmt.Errorf("unknown flag --%s", name)
+		}
+	}
+
+	return parsed, nil
+}
+
+// has reports whether the flag was given
+func (p parsedArgs) has(name string) bool {
+	return len(p.flags[name]) > 0
+}
+
+// value returns the last value given for the flag, or an empty string
+func (p parsedArgs) value(name string) string {
+	values := p.flags[name]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// values returns every value given for a repeatable flag
+func (p parsedArgs) values(name string) []string {
+	return p.flags[name]
+}
+
+// printArgError reports a flag parsing error if there was one, or msg otherwise
+func printArgError(parseErr error, msg string) {
+	if parseErr != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", parseErr)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+}
@@ -3,53 +3,213 @@ package main
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spacebarlabs/lean_vault/pkg/commands"
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
 )
 
 const version = "0.1.0"
 
 func main() {
-	if len(os.Args) < 2 {
+	global, err := parseGlobalFlags(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(global) < 1 {
 		printUsage()
 		os.Exit(1)
 	}
 
-	cmd := os.Args[1]
-	args := os.Args[2:]
+	cmd := global[0]
+	args := global[1:]
 
-	var err error
 	switch cmd {
 	case "init":
-		if len(args) != 0 {
-			fmt.Fprintln(os.Stderr, "Error: init command takes no arguments")
-			fmt.Fprintf(os.Stderr, "\nUsage: %s init\n", os.Args[0])
+		parsed, parseErr := parseArgs(args, []string{"provisioning-key-stdin", "no-provisioning-key"},
+			[]string{"provisioning-key-env", "key-file"})
+		sources := 0
+		for _, flag := range []string{"provisioning-key-stdin", "provisioning-key-env", "no-provisioning-key"} {
+			if parsed.has(flag) {
+				sources++
+			}
+		}
+		if parseErr == nil && sources > 1 {
+			parseErr = fmt.Errorf("use only one of --provisioning-key-stdin, --provisioning-key-env and --no-provisioning-key")
+		}
+		if parseErr == nil && parsed.has("provisioning-key-env") && parsed.value("provisioning-key-env") == "" {
+			parseErr = fmt.Errorf("--provisioning-key-env requires a variable name")
+		}
+		if parseErr != nil || len(parsed.positional) != 0 {
+			printArgError(parseErr, "init command takes no arguments")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s init [--provisioning-key-stdin | --provisioning-key-env <VAR> | --no-provisioning-key] [--key-file <path>]\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nWithout options, the provisioning key is prompted for on the terminal.")
+			fmt.Fprintln(os.Stderr, "\nOptions:")
+			fmt.Fprintln(os.Stderr, "  --provisioning-key-stdin     Read the provisioning key from stdin")
+			fmt.Fprintln(os.Stderr, "  --provisioning-key-env <VAR> Read the provisioning key from an environment variable")
+			fmt.Fprintln(os.Stderr, "  --no-provisioning-key        Create a vault for static secrets only")
+			fmt.Fprintln(os.Stderr, "  --key-file <path>            Use a pre-generated 32-byte master key instead of a new one")
+			fmt.Fprintln(os.Stderr, "\nAn existing vault is never overwritten.")
+			fmt.Fprintln(os.Stderr, "\nExample:")
+			fmt.Fprintf(os.Stderr, "  echo \"$OPENROUTER_PROVISIONING_KEY\" | %s init --provisioning-key-stdin\n", os.Args[0])
 			os.Exit(1)
 		}
-		err = commands.Init()
+		err = commands.Init(commands.InitOptions{
+			ProvisioningKeyStdin: parsed.has("provisioning-key-stdin"),
+			ProvisioningKeyEnv:   parsed.value("provisioning-key-env"),
+			NoProvisioningKey:    parsed.has("no-provisioning-key"),
+			KeyFile:              parsed.value("key-file"),
+		})
 	case "add":
-		if len(args) != 1 {
-			fmt.Fprintln(os.Stderr, "Error: add command requires a key name")
-			fmt.Fprintf(os.Stderr, "\nUsage: %s add <key-name>\n", os.Args[0])
+		parsed, parseErr := parseArgs(args, nil, []string{"account"})
+		var account string
+		if parseErr == nil {
+			account, parseErr = parseAccount(parsed)
+		}
+		if parseErr != nil || len(parsed.positional) != 1 {
+			printArgError(parseErr, "add command requires a key name")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s add [--account <name>] <key-name>\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nOptions:")
+			fmt.Fprintln(os.Stderr, "  --account <name>    Provision the key on another account (see 'account add')")
 			fmt.Fprintln(os.Stderr, "\nExample:")
 			fmt.Fprintf(os.Stderr, "  %s add my-api-key\n", os.Args[0])
+			fmt.Fprintf(os.Stderr, "  %s add --account acme acme/ci\n", os.Args[0])
 			os.Exit(1)
 		}
-		err = commands.Add(args[0])
-	case "get":
+		err = commands.Add(parsed.positional[0], account)
+	case "set":
 		if len(args) != 1 {
-			fmt.Fprintln(os.Stderr, "Error: get command requires a key name")
-			fmt.Fprintf(os.Stderr, "\nUsage: %s get <key-name>\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "Error: set command requires a secret name")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s set <name>\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nThe value is read from a hidden prompt, or from stdin when piped.")
+			fmt.Fprintln(os.Stderr, "\nExample:")
+			fmt.Fprintf(os.Stderr, "  %s set database-url\n", os.Args[0])
+			fmt.Fprintf(os.Stderr, "  echo \"$WEBHOOK_SECRET\" | %s set webhook-secret\n", os.Args[0])
+			os.Exit(1)
+		}
+		err = commands.Set(args[0])
+	case "get":
+		parsed, parseErr := parseArgs(args, []string{"previous"}, nil)
+		if parseErr != nil || len(parsed.positional) != 1 {
+			printArgError(parseErr, "get command requires a key name")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s get <key-name> [--previous]\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nOptions:")
+			fmt.Fprintln(os.Stderr, "  --previous    Print the value replaced by 'rotate --grace' while it is still valid")
 			os.Exit(1)
 		}
-		err = commands.Get(args[0])
+		err = commands.Get(parsed.positional[0], parsed.has("previous"))
 	case "list":
-		if len(args) != 0 {
-			fmt.Fprintln(os.Stderr, "Error: list command takes no arguments")
-			fmt.Fprintf(os.Stderr, "\nUsage: %s list\n", os.Args[0])
+		parsed, parseErr := parseArgs(args, []string{"recursive"}, append(filterFlags, "sort"))
+		opts := commands.ListOptions{Sort: parsed.value("sort"), Recursive: parsed.has("recursive")}
+		if parseErr == nil {
+			opts.Filter, parseErr = parseFilter(parsed)
+		}
+		if parseErr == nil {
+			parseErr = parsePrefixArg(parsed, &opts.Filter)
+		}
+		if parseErr == nil {
+			switch opts.Sort {
+			case "", commands.SortName, commands.SortAge, commands.SortOwner:
+			default:
+				parseErr = fmt.Errorf("invalid --sort %q", opts.Sort)
+			}
+		}
+		if parseErr != nil {
+			printArgError(parseErr, "")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s list [<namespace>] [--recursive] [--tag <key=value>]... [--owner <name>] [--sort name|age|owner]\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nOptions:")
+			fmt.Fprintln(os.Stderr, "  <namespace>          Only keys under this namespace, e.g. prod/; same as --prefix")
+			fmt.Fprintln(os.Stderr, "  --recursive          List every key under the namespace instead of one line per sub-namespace")
+			printFilterOptions()
+			fmt.Fprintln(os.Stderr, "  --sort <order>       name (default), age (longest unchanged first) or owner")
+			os.Exit(1)
+		}
+		err = commands.List(opts)
+	case "annotate":
+		parsed, parseErr := parseArgs(args, nil, []string{"description", "owner", "tag", "untag"})
+		var opts commands.AnnotateOptions
+		if parsed.has("description") {
+			description := parsed.value("description")
+			opts.Description = &description
+		}
+		if parsed.has("owner") {
+			owner := parsed.value("owner")
+			opts.Owner = &owner
+		}
+		if parseErr == nil {
+			opts.Tags, parseErr = parseTags(parsed.values("tag"))
+		}
+		opts.Untag = parsed.values("untag")
+		if parseErr == nil && len(parsed.flags) == 0 {
+			parseErr = fmt.Errorf("nothing to change")
+		}
+		if parseErr != nil || len(parsed.positional) != 1 {
+			printArgError(parseErr, "annotate command requires a key name")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s annotate <key-name> [--description <text>] [--owner <name>] [--tag <key=value>]... [--untag <key>]...\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nOptions:")
+			fmt.Fprintln(os.Stderr, "  --description <text>    Describe what the key is for (empty to clear)")
+			fmt.Fprintln(os.Stderr, "  --owner <name>          Who is responsible for the key (empty to clear)")
+			fmt.Fprintln(os.Stderr, "  --tag <key=value>       Add or change a tag; repeatable")
+			fmt.Fprintln(os.Stderr, "  --untag <key>           Remove a tag; repeatable")
+			fmt.Fprintln(os.Stderr, "\nExample:")
+			fmt.Fprintf(os.Stderr, "  %s annotate chatbot --owner alice --tag team=ml --description \"Support bot\"\n", os.Args[0])
 			os.Exit(1)
 		}
-		err = commands.List()
+		err = commands.Annotate(parsed.positional[0], opts)
+	case "export":
+		parsed, parseErr := parseArgs(args, nil, append(filterFlags, "format"))
+		var filter vault.Filter
+		if parseErr == nil {
+			filter, parseErr = parseFilter(parsed)
+		}
+		if parseErr == nil {
+			parseErr = parsePrefixArg(parsed, &filter)
+		}
+		format := parsed.value("format")
+		if format == "" {
+			format = commands.ExportFormatEnv
+		}
+		if parseErr == nil && format != commands.ExportFormatEnv && format != commands.ExportFormatJSON {
+			parseErr = fmt.Errorf("invalid --format %q", format)
+		}
+		if parseErr != nil {
+			printArgError(parseErr, "")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s export [<namespace>] [--tag <key=value>]... [--owner <name>] [--format env|json]\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nOptions:")
+			fmt.Fprintln(os.Stderr, "  <namespace>          Only keys under this namespace, e.g. prod/chatbot/; same as --prefix")
+			printFilterOptions()
+			fmt.Fprintln(os.Stderr, "  --format <format>    env: shell export statements (default); json: an object keyed by secret name")
+			fmt.Fprintln(os.Stderr, "\nExample:")
+			fmt.Fprintf(os.Stderr, "  eval \"$(%s export --tag team=ml)\"\n", os.Args[0])
+			fmt.Fprintf(os.Stderr, "  %s export prod/chatbot/ --format json\n", os.Args[0])
+			os.Exit(1)
+		}
+		err = commands.Export(filter, format)
+	case "mv":
+		parsed, parseErr := parseArgs(args, []string{"relabel"}, nil)
+		if parseErr != nil || len(parsed.positional) != 2 {
+			printArgError(parseErr, "mv command requires an old and a new name")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s mv <old-name> <new-name> [--relabel]\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nRenames a secret, keeping its value, key, metadata and history.")
+			fmt.Fprintln(os.Stderr, "\nOptions:")
+			fmt.Fprintln(os.Stderr, "  --relabel    Also rename a provisioned key on OpenRouter")
+			fmt.Fprintln(os.Stderr, "\nExample:")
+			fmt.Fprintf(os.Stderr, "  %s mv chatbot prod/chatbot/openrouter --relabel\n", os.Args[0])
+			os.Exit(1)
+		}
+		err = commands.Move(parsed.positional[0], parsed.positional[1], parsed.has("relabel"))
+	case "cp":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, "Error: cp command requires a source and a destination name")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s cp <src-name> <dst-name>\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nCopies a static secret with its value, metadata and history.")
+			fmt.Fprintln(os.Stderr, "A copy of a provisioned key gets a new key on the same account.")
+			os.Exit(1)
+		}
+		err = commands.Copy(args[0], args[1])
 	case "remove":
 		if len(args) < 1 {
 			fmt.Fprintln(os.Stderr, "Error: remove command requires a key name")
@@ -69,22 +229,306 @@ func main() {
 		}
 		err = commands.Remove(keyName, force)
 	case "rotate":
-		if len(args) != 1 {
-			fmt.Fprintln(os.Stderr, "Error: rotate command requires a key name")
-			fmt.Fprintf(os.Stderr, "\nUsage: %s rotate <key-name>\n", os.Args[0])
+		parsed, parseErr := parseArgs(args, []string{"due", "all", "json"}, append(filterFlags, "parallel", "grace"))
+		opts := commands.BulkRotateOptions{JSON: parsed.has("json")}
+		if parseErr == nil {
+			opts.Filter, parseErr = parseFilter(parsed)
+		}
+		var grace time.Duration
+		if parseErr == nil && parsed.has("grace") {
+			grace, parseErr = vault.ParseDuration(parsed.value("grace"))
+			if parseErr == nil && grace <= 0 {
+				parseErr = fmt.Errorf("--grace must be positive; omit it to revoke the old key right away")
+			}
+			if parseErr == nil && (len(parsed.positional) != 1 || parsed.has("json") || parsed.has("parallel")) {
+				parseErr = fmt.Errorf("--grace rotates a single key")
+			}
+		}
+		if parseErr == nil && parsed.has("parallel") {
+			opts.Parallel, parseErr = strconv.Atoi(parsed.value("parallel"))
+			if parseErr == nil && opts.Parallel < 1 {
+				parseErr = fmt.Errorf("--parallel must be at least 1")
+			}
+		}
+		selectors := len(parsed.positional)
+		if parsed.has("due") {
+			selectors++
+		}
+		if parsed.has("all") || (!opts.Filter.IsEmpty() && !parsed.has("due")) {
+			selectors++
+		}
+		if parseErr == nil && (parsed.has("due") || parsed.has("all")) && selectors > 1 {
+			parseErr = fmt.Errorf("--due and --all can't be combined with each other or with key names")
+		}
+		if parseErr == nil && !opts.Filter.IsEmpty() && len(parsed.positional) > 0 {
+			parseErr = fmt.Errorf("--prefix, --tag and --owner select keys and can't be combined with key names")
+		}
+		if parseErr != nil || selectors == 0 {
+			printArgError(parseErr, "rotate command requires a key name, --due or --all")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s rotate <key-name> [--grace <duration>]\n", os.Args[0])
+			fmt.Fprintf(os.Stderr, "       %s rotate <key-name>... [--parallel <n>] [--json]\n", os.Args[0])
+			fmt.Fprintf(os.Stderr, "       %s rotate --due | --all [--prefix <namespace>] [--tag <key=value>]... [--owner <name>] [--parallel <n>] [--json]\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nOptions:")
+			fmt.Fprintln(os.Stderr, "  --grace <dur>    Keep the old key valid this long (see 'get --previous')")
+			fmt.Fprintln(os.Stderr, "  --due            Rotate every key that is past its rotation policy")
+			fmt.Fprintln(os.Stderr, "  --all            Rotate every provisioned key")
+			fmt.Fprintln(os.Stderr, "  --tag <key=value> Only rotate keys with this tag; repeatable, implies --all without --due")
+			fmt.Fprintln(os.Stderr, "  --owner <name>   Only rotate keys with this owner, implies --all without --due")
+			fmt.Fprintf(os.Stderr, "  --parallel <n>   Keys to create and revoke at once when rotating several (default %d)\n", commands.DefaultRotateParallelism)
+			fmt.Fprintln(os.Stderr, "  --json           Print the report for several keys as JSON")
 			fmt.Fprintln(os.Stderr, "\nExample:")
 			fmt.Fprintf(os.Stderr, "  %s rotate my-api-key\n", os.Args[0])
+			fmt.Fprintf(os.Stderr, "  %s rotate my-api-key --grace 1h\n", os.Args[0])
+			fmt.Fprintf(os.Stderr, "  %s rotate --all --parallel 8\n", os.Args[0])
+			os.Exit(1)
+		}
+		switch {
+		case parsed.has("due"):
+			err = commands.RotateDue(opts)
+		case parsed.has("all"), !opts.Filter.IsEmpty():
+			err = commands.RotateAll(opts)
+		case len(parsed.positional) == 1 && !parsed.has("json"):
+			err = commands.Rotate(parsed.positional[0], grace)
+		default:
+			err = commands.RotateMany(parsed.positional, opts)
+		}
+	case "history":
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "Error: history command requires a key name")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s history <key-name>\n", os.Args[0])
+			os.Exit(1)
+		}
+		err = commands.History(args[0])
+	case "rollback":
+		parsed, parseErr := parseArgs(args, []string{"force"}, []string{"to"})
+		var version int
+		if parseErr == nil && !parsed.has("to") {
+			parseErr = fmt.Errorf("--to is required")
+		}
+		if parseErr == nil {
+			version, parseErr = strconv.Atoi(parsed.value("to"))
+		}
+		if parseErr != nil || len(parsed.positional) != 1 {
+			printArgError(parseErr, "rollback command requires a key name")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s rollback <key-name> --to <version> [--force]\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nOptions:")
+			fmt.Fprintln(os.Stderr, "  --to <version>    Version to restore, as listed by 'lean_vault history'")
+			fmt.Fprintln(os.Stderr, "  --force           Roll back even if that version's key has been revoked")
+			fmt.Fprintln(os.Stderr, "\nExample:")
+			fmt.Fprintf(os.Stderr, "  %s rollback database-url --to 3\n", os.Args[0])
+			os.Exit(1)
+		}
+		err = commands.Rollback(parsed.positional[0], version, parsed.has("force"))
+	case "audit":
+		err = runAudit(args)
+	case "profile":
+		err = runProfile(args)
+	case "trash":
+		err = runTrash(args)
+	case "provisioning-key":
+		err = runProvisioningKey(args)
+	case "account":
+		err = runAccount(args)
+	case "plan", "apply":
+		bools := []string{}
+		if cmd == "apply" {
+			bools = append(bools, "yes")
+		}
+		parsed, parseErr := parseArgs(args, bools, []string{"file"})
+		if parseErr != nil || len(parsed.positional) != 0 {
+			printArgError(parseErr, cmd+" command takes no arguments")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s plan [--file <manifest>]\n", os.Args[0])
+			fmt.Fprintf(os.Stderr, "       %s apply [--file <manifest>] [--yes]\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nOptions:")
+			fmt.Fprintf(os.Stderr, "  --file <manifest>    YAML file with a keys list (default: the project's %s)\n", vault.ProjectFile)
+			fmt.Fprintln(os.Stderr, "  --yes                Apply without asking for confirmation")
+			os.Exit(1)
+		}
+		if cmd == "plan" {
+			err = commands.Plan(parsed.value("file"))
+		} else {
+			err = commands.Apply(parsed.value("file"), parsed.has("yes"))
+		}
+	case "policy":
+		err = runPolicy(args)
+	case "lease":
+		parsed, parseErr := parseArgs(args, nil, []string{"ttl", "limit", "account"})
+		var ttl time.Duration
+		var limit float64
+		var account string
+		if parseErr == nil {
+			account, parseErr = parseAccount(parsed)
+		}
+		if parseErr == nil && !parsed.has("ttl") {
+			parseErr = fmt.Errorf("--ttl is required")
+		}
+		if parseErr == nil {
+			ttl, parseErr = vault.ParseDuration(parsed.value("ttl"))
+		}
+		if parseErr == nil && parsed.has("limit") {
+			limit, parseErr = strconv.ParseFloat(parsed.value("limit"), 64)
+		}
+		if parseErr != nil || len(parsed.positional) != 1 {
+			printArgError(parseErr, "lease command requires a key name")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s lease --ttl <duration> [--limit <dollars>] [--account <name>] <key-name>\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nOptions:")
+			fmt.Fprintln(os.Stderr, "  --ttl <duration>     How long the key lives, e.g. 30m, 2h or 7d")
+			fmt.Fprintln(os.Stderr, "  --limit <dollars>    Spend limit for the key")
+			fmt.Fprintln(os.Stderr, "  --account <name>     Provision the key on another account")
+			fmt.Fprintln(os.Stderr, "\nExpired leases are revoked by a running agent or by 'lean_vault gc'.")
+			fmt.Fprintln(os.Stderr, "\nExample:")
+			fmt.Fprintf(os.Stderr, "  %s lease --ttl 2h --limit 5 ci-job-123\n", os.Args[0])
+			os.Exit(1)
+		}
+		err = commands.Lease(parsed.positional[0], ttl, limit, account)
+	case "gc":
+		parsed, parseErr := parseArgs(args, []string{"dry-run"}, nil)
+		if parseErr != nil || len(parsed.positional) != 0 {
+			printArgError(parseErr, "gc command takes no arguments")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s gc [--dry-run]\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nOptions:")
+			fmt.Fprintln(os.Stderr, "  --dry-run    List what would be revoked or purged without changing anything")
+			os.Exit(1)
+		}
+		err = commands.GC(parsed.has("dry-run"))
+	case "agent":
+		parsed, parseErr := parseArgs(args, nil, []string{"idle-timeout"})
+		idleTimeout := time.Duration(0)
+		if parseErr == nil && parsed.has("idle-timeout") {
+			idleTimeout, parseErr = time.ParseDuration(parsed.value("idle-timeout"))
+		}
+		if parseErr != nil || len(parsed.positional) != 0 {
+			printArgError(parseErr, "agent command takes no arguments")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s agent [--idle-timeout <duration>]\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nOptions:")
+			fmt.Fprintln(os.Stderr, "  --idle-timeout <duration>    Drop decrypted secrets after this long unused (default 15m)")
+			fmt.Fprintln(os.Stderr, "\nRuns in the foreground. While it runs, 'get' and 'list' are served from memory.")
+			fmt.Fprintln(os.Stderr, "Set LEAN_VAULT_NO_AGENT=1 to bypass it.")
+			fmt.Fprintln(os.Stderr, "\nExample:")
+			fmt.Fprintf(os.Stderr, "  %s agent --idle-timeout 30m &\n", os.Args[0])
+			os.Exit(1)
+		}
+		err = commands.Agent(idleTimeout)
+	case "incident":
+		parsed, parseErr := parseArgs(args, nil, []string{"reason"})
+		if parseErr != nil || len(parsed.positional) != 1 {
+			printArgError(parseErr, "incident command requires a key name")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s incident <key-name> [--reason <text>]\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nRotates the key, revokes the old one immediately, records the incident")
+			fmt.Fprintln(os.Stderr, "in the audit log and prints a summary to share.")
+			fmt.Fprintln(os.Stderr, "\nExample:")
+			fmt.Fprintf(os.Stderr, "  %s incident my-api-key --reason \"shown on stream\"\n", os.Args[0])
+			os.Exit(1)
+		}
+		err = commands.Incident(parsed.positional[0], parsed.value("reason"))
+	case "proxy":
+		parsed, parseErr := parseArgs(args, nil, []string{"listen", "base-url", "alias"})
+		aliases := make(map[string]string)
+		for _, alias := range parsed.values("alias") {
+			token, name, ok := strings.Cut(alias, "=")
+			if !ok && parseErr == nil {
+				parseErr = fmt.Errorf("--alias must look like <token>=<key-name>")
+			}
+			aliases[token] = name
+		}
+		if parseErr != nil || len(parsed.positional) != 0 {
+			printArgError(parseErr, "proxy command takes no arguments")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s proxy [--listen <addr>] [--base-url <url>] [--alias <token>=<key-name>]...\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nOptions:")
+			fmt.Fprintln(os.Stderr, "  --listen <addr>                 Address to listen on (default 127.0.0.1:8787)")
+			fmt.Fprintln(os.Stderr, "  --base-url <url>                API to forward to (default https://openrouter.ai/api/v1)")
+			fmt.Fprintln(os.Stderr, "  --alias <token>=<key-name>      Map a local token to a vault key (repeatable)")
+			fmt.Fprintln(os.Stderr, "\nSettings and aliases can also be kept in ~/.lean_vault/proxy.yml.")
+			fmt.Fprintln(os.Stderr, "\nExample:")
+			fmt.Fprintf(os.Stderr, "  %s proxy --alias chatbot=my-api-key\n", os.Args[0])
+			os.Exit(1)
+		}
+		err = commands.Proxy(commands.ProxyOptions{
+			Listen:  parsed.value("listen"),
+			BaseURL: parsed.value("base-url"),
+			Aliases: aliases,
+		})
+	case "render":
+		parsed, parseErr := parseArgs(args, nil, []string{"output"})
+		if parseErr != nil || len(parsed.positional) != 1 {
+			printArgError(parseErr, "render command requires a template file")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s render <template-file> [--output <file>]\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nOptions:")
+			fmt.Fprintln(os.Stderr, "  --output <file>    Write the result to a file with 0600 permissions instead of stdout")
+			fmt.Fprintln(os.Stderr, "\nTemplate functions:")
+			fmt.Fprintln(os.Stderr, `  {{ secret "name" }}              Value of a stored secret (fails if missing)`)
+			fmt.Fprintln(os.Stderr, `  {{ secretID "name" }}            Provider ID of a stored key`)
+			fmt.Fprintln(os.Stderr, `  {{ env "VAR" | default "x" }}    Environment variable with a fallback`)
+			fmt.Fprintln(os.Stderr, "\nExample:")
+			fmt.Fprintf(os.Stderr, "  %s render config.tmpl > config.yml\n", os.Args[0])
+			fmt.Fprintf(os.Stderr, "  %s render config.tmpl --output config.yml\n", os.Args[0])
+			os.Exit(1)
+		}
+		err = commands.Render(parsed.positional[0], parsed.value("output"))
+	case "redact":
+		if len(args) != 0 {
+			fmt.Fprintln(os.Stderr, "Error: redact command takes no arguments")
+			fmt.Fprintf(os.Stderr, "\nUsage: some-command 2>&1 | %s redact\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nReplaces every stored secret value (including base64 and URL-encoded forms)")
+			fmt.Fprintln(os.Stderr, "read from stdin with [REDACTED:<name>] before writing it to stdout.")
+			os.Exit(1)
+		}
+		err = commands.Redact()
+	case "scan":
+		parsed, parseErr := parseArgs(args, []string{"git-history", "staged", "install-hook"}, nil)
+		if parseErr != nil || len(parsed.positional) > 1 {
+			printArgError(parseErr, "scan command takes at most one path")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s scan [path] [--git-history | --staged | --install-hook]\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nOptions:")
+			fmt.Fprintln(os.Stderr, "  --git-history    Also scan every commit in the repository")
+			fmt.Fprintln(os.Stderr, "  --staged         Scan only changes staged for commit")
+			fmt.Fprintln(os.Stderr, "  --install-hook   Install a pre-commit hook that runs 'scan --staged'")
+			fmt.Fprintln(os.Stderr, "\nExample:")
+			fmt.Fprintf(os.Stderr, "  %s scan . --git-history\n", os.Args[0])
 			os.Exit(1)
 		}
-		err = commands.Rotate(args[0])
+		path := "."
+		if len(parsed.positional) == 1 {
+			path = parsed.positional[0]
+		}
+		if parsed.has("install-hook") {
+			err = commands.InstallScanHook(path)
+		} else {
+			err = commands.Scan(commands.ScanOptions{
+				Path:       path,
+				GitHistory: parsed.has("git-history"),
+				Staged:     parsed.has("staged"),
+			})
+		}
 	case "usage":
+		parsed, parseErr := parseArgs(args, []string{"local"}, filterFlags)
+		var filter vault.Filter
+		if parseErr == nil {
+			filter, parseErr = parseFilter(parsed)
+		}
+		if parseErr != nil || len(parsed.positional) != 0 {
+			printArgError(parseErr, "usage command takes no arguments")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s usage [--local] [--prefix <namespace>] [--tag <key=value>]... [--owner <name>]\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nOptions:")
+			fmt.Fprintln(os.Stderr, "  --local              Show usage recorded by 'lean_vault proxy' per alias, with local budgets")
+			printFilterOptions()
+			os.Exit(1)
+		}
+		if parsed.has("local") {
+			err = commands.UsageLocal(filter)
+		} else {
+			err = commands.Usage(filter)
+		}
+	case "doctor":
 		if len(args) != 0 {
-			fmt.Fprintln(os.Stderr, "Error: usage command takes no arguments")
-			fmt.Fprintf(os.Stderr, "\nUsage: %s usage\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "Error: doctor command takes no arguments")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s doctor\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nChecks file permissions, the master key, that the vault and every secret")
+			fmt.Fprintln(os.Stderr, "decrypt, key versions, pending revocations, shared provider keys, the agent")
+			fmt.Fprintln(os.Stderr, "socket and the audit log. Exits non-zero if a problem is found.")
 			os.Exit(1)
 		}
-		// TODO: Implement usage command
-		fmt.Println("Usage command not implemented yet")
+		err = commands.Doctor()
 	case "version":
 		fmt.Printf("lean_vault version %s\n", version)
 	default:
@@ -100,18 +544,482 @@ func main() {
 }
 
 func printUsage() {
-	fmt.Fprintf(os.Stderr, `Usage: %s <command> [arguments]
+	fmt.Fprintf(os.Stderr, `Usage: %s [--profile <name> | --vault-dir <dir>] <command> [arguments]
 
 Commands:
-  init                Initialize the vault
-  add <key-name>      Add a new OpenRouter API key
-  get <key-name>      Retrieve a stored key
-  list               List all stored keys
-  remove <key-name>   Remove and revoke a key
-  rotate <key-name>   Rotate a key (create new + revoke old)
-  usage              Display usage information for all keys
+  init                Initialize the vault (--provisioning-key-stdin, --provisioning-key-env, --no-provisioning-key, --key-file)
+  add <key-name>      Add a new OpenRouter API key (--account: on another account)
+  set <name>          Store a static secret (prompted or read from stdin)
+  get <key-name>      Retrieve a stored key (--previous: the value in its grace period)
+  list [namespace]   List stored keys (--recursive, --tag, --owner, --sort)
+  annotate <key-name> Set a key's description, owner and tags
+  export [namespace] Print secrets as shell exports or JSON (--tag, --owner)
+  mv <old> <new>      Rename a secret, keeping its key (--relabel: rename it on OpenRouter too)
+  cp <src> <dst>      Copy a secret under another name
+  remove <key-name>   Remove and revoke a key (static secrets are just deleted)
+  rotate <key-name>   Rotate a key (create new + revoke old, or prompt for a new static value)
+                      Several names, --all or --due rotate in bulk (--parallel, --json)
+  history <key-name>  List earlier versions of a secret
+  rollback <key-name> Restore an earlier version (--to <version>)
+  policy <subcommand> Set, clear or check per-key rotation policies
+  audit <subcommand>  Verify or show the tamper-evident audit log
+  profile <subcommand> List, create or switch between named vaults
+  plan                Show the changes needed to match the key manifest
+  apply               Create, update and remove keys to match the key manifest
+  lease <key-name>    Provision a short-lived key (--ttl, --limit)
+  trash <subcommand>  List, restore or purge removed secrets
+  provisioning-key <subcommand> Set, rotate or verify the OpenRouter provisioning key
+  account <subcommand> Add, list or remove OpenRouter accounts with their own provisioning keys
+  gc                  Revoke expired leases
+  agent               Serve decrypted secrets over a local socket (and revoke expired leases)
+  incident <key-name> Rotate a leaked key, revoke the old one now and log it
+  proxy               Run a local API proxy that injects vault keys
+  render <template>   Render a config template with secret placeholders
+  redact             Scrub stored secret values from stdin
+  scan [path]         Find stored secret values in files or git history
+  usage [--local]     Display usage for all keys (--local: per proxy alias)
+  doctor              Check the vault's integrity and suggest fixes
   version            Show version information
 
+Options:
+  --profile <name>    Use a named vault (default: LEAN_VAULT_PROFILE or 'profile use')
+  --vault-dir <dir>   Use the vault in a directory (default: LEAN_VAULT_DIR)
+
+Inside a project with a .lean_vault.yml manifest, its vault is used unless
+--vault-dir or --profile says otherwise.
+
 For detailed usage instructions, see: https://github.com/spacebarlabs/lean_vault
 `, os.Args[0])
 }
+
+// runPolicy handles the policy subcommands
+func runPolicy(args []string) error {
+	sub := ""
+	if len(args) > 0 {
+		sub = args[0]
+	}
+
+	switch sub {
+	case "set":
+		parsed, parseErr := parseArgs(args[1:], nil, []string{"max-age", "rotate-before-expiry", "on-expiry"})
+		if parseErr == nil && !parsed.has("max-age") {
+			parseErr = fmt.Errorf("--max-age is required")
+		}
+		if parseErr != nil || len(parsed.positional) != 1 {
+			printArgError(parseErr, "policy set requires a key name")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s policy set <key-name> --max-age <duration> [--rotate-before-expiry <duration>] [--on-expiry rotate|revoke|warn]\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nOptions:")
+			fmt.Fprintln(os.Stderr, "  --max-age <duration>                 How long a value may be used, e.g. 30d")
+			fmt.Fprintln(os.Stderr, "  --rotate-before-expiry <duration>    Report the key as due this long before max age")
+			fmt.Fprintln(os.Stderr, "  --on-expiry rotate|revoke|warn       What 'rotate --due' and 'gc' do with it (default rotate)")
+			fmt.Fprintln(os.Stderr, "\nExample:")
+			fmt.Fprintf(os.Stderr, "  %s policy set my-api-key --max-age 30d --rotate-before-expiry 3d\n", os.Args[0])
+			os.Exit(1)
+		}
+		return commands.PolicySet(parsed.positional[0], vault.Policy{
+			MaxAge:             parsed.value("max-age"),
+			RotateBeforeExpiry: parsed.value("rotate-before-expiry"),
+			OnExpiry:           parsed.value("on-expiry"),
+		})
+	case "clear":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, "Error: policy clear requires a key name")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s policy clear <key-name>\n", os.Args[0])
+			os.Exit(1)
+		}
+		return commands.PolicyClear(args[1])
+	case "check":
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "Error: policy check takes no arguments")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s policy check\n", os.Args[0])
+			os.Exit(1)
+		}
+		return commands.PolicyCheck()
+	default:
+		fmt.Fprintln(os.Stderr, "Error: policy command requires a subcommand")
+		fmt.Fprintf(os.Stderr, "\nUsage: %s policy <subcommand>\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "\nSubcommands:")
+		fmt.Fprintln(os.Stderr, "  set <key-name>    Set the rotation policy for a key")
+		fmt.Fprintln(os.Stderr, "  clear <key-name>  Remove the rotation policy from a key")
+		fmt.Fprintln(os.Stderr, "  check             List policy violations; exits non-zero if there are any")
+		os.Exit(1)
+	}
+	return nil
+}
+
+// runAudit handles the audit subcommands
+func runAudit(args []string) error {
+	sub := ""
+	if len(args) > 0 {
+		sub = args[0]
+	}
+
+	switch sub {
+	case "verify":
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "Error: audit verify takes no arguments")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s audit verify\n", os.Args[0])
+			os.Exit(1)
+		}
+		return commands.AuditVerify()
+	case "show":
+		parsed, parseErr := parseArgs(args[1:], nil, []string{"since"})
+		var since time.Time
+		if parseErr == nil && parsed.has("since") {
+			since, parseErr = parseSince(parsed.value("since"))
+		}
+		if parseErr != nil || len(parsed.positional) != 0 {
+			printArgError(parseErr, "audit show takes no arguments")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s audit show [--since <duration|date>]\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nOptions:")
+			fmt.Fprintln(os.Stderr, "  --since <duration|date>    Only show entries from the last duration (24h, 7d) or since a date (2006-01-02 or RFC 3339)")
+			os.Exit(1)
+		}
+		return commands.AuditShow(since)
+	default:
+		fmt.Fprintln(os.Stderr, "Error: audit command requires a subcommand")
+		fmt.Fprintf(os.Stderr, "\nUsage: %s audit <subcommand>\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "\nSubcommands:")
+		fmt.Fprintln(os.Stderr, "  verify    Check the log's hash chain and signatures; exits non-zero if tampered with")
+		fmt.Fprintln(os.Stderr, "  show      List recorded operations")
+		os.Exit(1)
+	}
+	return nil
+}
+
+// parseGlobalFlags removes --profile and --vault-dir from before the
+// command. They are passed on through LEAN_VAULT_PROFILE and LEAN_VAULT_DIR
+// so every vault opened by the command, and any process it starts, uses them.
+func parseGlobalFlags(args []string) ([]string, error) {
+	envVars := map[string]string{
+		"profile":   vault.ProfileEnvVar,
+		"vault-dir": vault.VaultDirEnvVar,
+	}
+	for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+		flag, value, hasValue := strings.Cut(strings.TrimPrefix(args[0], "--"), "=")
+		envVar, ok := envVars[flag]
+		if !ok {
+			break
+		}
+		if hasValue {
+			args = args[1:]
+		} else if len(args) > 1 {
+			value, args = args[1], args[2:]
+		} else {
+			return nil, fmt.Errorf("--%s requires a value", flag)
+		}
+		if value == "" {
+			return nil, fmt.Errorf("--%s requires a value", flag)
+		}
+		if flag == "profile" {
+			if err := vault.ValidateProfileName(value); err != nil {
+				return nil, err
+			}
+		}
+		os.Setenv(envVar, value)
+	}
+
+	// Report an invalid profile or project manifest before running any
+	// command, and say when a manifest in a parent directory picked the vault
+	v, err := vault.Resolve()
+	if err != nil {
+		return nil, err
+	}
+	if v.ProjectFile() != "" {
+		fmt.Fprintf(os.Stderr, "Using project vault %s (from %s)\n", v.VaultDir(), v.ProjectFile())
+	}
+	return args, nil
+}
+
+// runProfile handles the profile subcommands
+func runProfile(args []string) error {
+	sub := ""
+	if len(args) > 0 {
+		sub = args[0]
+	}
+
+	switch sub {
+	case "list":
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "Error: profile list takes no arguments")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s profile list\n", os.Args[0])
+			os.Exit(1)
+		}
+		return commands.ProfileList()
+	case "create":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, "Error: profile create requires a profile name")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s profile create <name>\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nCreates a separate vault and prompts for its provisioning key.")
+			fmt.Fprintln(os.Stderr, "\nExample:")
+			fmt.Fprintf(os.Stderr, "  %s profile create prod\n", os.Args[0])
+			os.Exit(1)
+		}
+		return commands.ProfileCreate(args[1])
+	case "use":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, "Error: profile use requires a profile name")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s profile use <name>\n", os.Args[0])
+			fmt.Fprintf(os.Stderr, "\nLEAN_VAULT_PROFILE and --profile override the profile chosen here.\n")
+			os.Exit(1)
+		}
+		return commands.ProfileUse(args[1])
+	default:
+		fmt.Fprintln(os.Stderr, "Error: profile command requires a subcommand")
+		fmt.Fprintf(os.Stderr, "\nUsage: %s profile <subcommand>\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "\nSubcommands:")
+		fmt.Fprintln(os.Stderr, "  list           List profiles; the active one is marked with *")
+		fmt.Fprintln(os.Stderr, "  create <name>  Create a profile with its own vault and provisioning key")
+		fmt.Fprintln(os.Stderr, "  use <name>     Make a profile the default")
+		os.Exit(1)
+	}
+	return nil
+}
+
+// runProvisioningKey handles the provisioning-key subcommands
+func runProvisioningKey(args []string) error {
+	sub := ""
+	if len(args) > 0 {
+		sub = args[0]
+	}
+
+	switch sub {
+	case "set", "rotate":
+		parsed, parseErr := parseArgs(args[1:], []string{"no-verify"}, []string{"account"})
+		var account string
+		if parseErr == nil {
+			account, parseErr = parseAccount(parsed)
+		}
+		if parseErr != nil || len(parsed.positional) != 0 {
+			printArgError(parseErr, fmt.Sprintf("provisioning-key %s takes no arguments", sub))
+			fmt.Fprintf(os.Stderr, "\nUsage: %s provisioning-key %s [--account <name>] [--no-verify]\n", os.Args[0], sub)
+			fmt.Fprintln(os.Stderr, "\nReads the new key from a hidden prompt or stdin. Stored API keys are kept.")
+			fmt.Fprintln(os.Stderr, "\nOptions:")
+			fmt.Fprintln(os.Stderr, "  --account <name>    Replace a named account's key instead of the default one")
+			fmt.Fprintln(os.Stderr, "  --no-verify         Store the key without checking it against OpenRouter")
+			os.Exit(1)
+		}
+		if sub == "set" {
+			return commands.ProvisioningKeySet(account, parsed.has("no-verify"))
+		}
+		return commands.ProvisioningKeyRotate(account, parsed.has("no-verify"))
+	case "verify":
+		parsed, parseErr := parseArgs(args[1:], nil, []string{"account"})
+		var account string
+		if parseErr == nil {
+			account, parseErr = parseAccount(parsed)
+		}
+		if parseErr != nil || len(parsed.positional) != 0 {
+			printArgError(parseErr, "provisioning-key verify takes no arguments")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s provisioning-key verify [--account <name>]\n", os.Args[0])
+			os.Exit(1)
+		}
+		return commands.ProvisioningKeyVerify(account)
+	default:
+		fmt.Fprintln(os.Stderr, "Error: provisioning-key command requires a subcommand")
+		fmt.Fprintf(os.Stderr, "\nUsage: %s provisioning-key <subcommand>\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "\nSubcommands:")
+		fmt.Fprintln(os.Stderr, "  set       Replace the stored provisioning key")
+		fmt.Fprintln(os.Stderr, "  rotate    Replace an exposed provisioning key with a new one from the same account")
+		fmt.Fprintln(os.Stderr, "  verify    Check that the stored key works and can manage every stored API key")
+		os.Exit(1)
+	}
+	return nil
+}
+
+// runAccount handles the account subcommands
+func runAccount(args []string) error {
+	sub := ""
+	if len(args) > 0 {
+		sub = args[0]
+	}
+
+	switch sub {
+	case "add":
+		parsed, parseErr := parseArgs(args[1:], []string{"no-verify"}, nil)
+		if parseErr != nil || len(parsed.positional) != 1 {
+			printArgError(parseErr, "account add requires an account name")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s account add [--no-verify] <name>\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nReads the account's provisioning key from a hidden prompt or stdin.")
+			fmt.Fprintln(os.Stderr, "\nOptions:")
+			fmt.Fprintln(os.Stderr, "  --no-verify    Store the key without checking it against OpenRouter")
+			fmt.Fprintln(os.Stderr, "\nExample:")
+			fmt.Fprintf(os.Stderr, "  %s account add acme\n", os.Args[0])
+			os.Exit(1)
+		}
+		return commands.AccountAdd(parsed.positional[0], parsed.has("no-verify"))
+	case "list":
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "Error: account list takes no arguments")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s account list\n", os.Args[0])
+			os.Exit(1)
+		}
+		return commands.AccountList()
+	case "remove":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, "Error: account remove requires an account name")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s account remove <name>\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nRemove or purge the account's keys first.")
+			os.Exit(1)
+		}
+		return commands.AccountRemove(args[1])
+	default:
+		fmt.Fprintln(os.Stderr, "Error: account command requires a subcommand")
+		fmt.Fprintf(os.Stderr, "\nUsage: %s account <subcommand>\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "\nSubcommands:")
+		fmt.Fprintln(os.Stderr, "  add <name>       Store the provisioning key of another OpenRouter account")
+		fmt.Fprintln(os.Stderr, "  list             List accounts and how many stored keys each provisioned")
+		fmt.Fprintln(os.Stderr, "  remove <name>    Forget an account that no stored key uses")
+		os.Exit(1)
+	}
+	return nil
+}
+
+// runTrash handles the trash subcommands
+func runTrash(args []string) error {
+	sub := ""
+	if len(args) > 0 {
+		sub = args[0]
+	}
+
+	switch sub {
+	case "list":
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "Error: trash list takes no arguments")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s trash list\n", os.Args[0])
+			os.Exit(1)
+		}
+		return commands.TrashList()
+	case "restore":
+		parsed, parseErr := parseArgs(args[1:], nil, []string{"as"})
+		if parseErr != nil || len(parsed.positional) != 1 {
+			printArgError(parseErr, "trash restore requires a key name")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s trash restore <key-name> [--as <new-name>]\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nRestores the most recently removed secret with that name.")
+			fmt.Fprintln(os.Stderr, "\nOptions:")
+			fmt.Fprintln(os.Stderr, "  --as <new-name>    Restore it under another name")
+			os.Exit(1)
+		}
+		return commands.TrashRestore(parsed.positional[0], parsed.value("as"))
+	case "purge":
+		parsed, parseErr := parseArgs(args[1:], []string{"all", "no-revoke"}, nil)
+		if parseErr == nil && parsed.has("all") == (len(parsed.positional) > 0) {
+			parseErr = fmt.Errorf("trash purge requires key names or --all, but not both")
+		}
+		if parseErr != nil {
+			printArgError(parseErr, "")
+			fmt.Fprintf(os.Stderr, "\nUsage: %s trash purge <key-name>... | --all [--no-revoke]\n", os.Args[0])
+			fmt.Fprintln(os.Stderr, "\nPermanently deletes removed secrets. Keys removed with 'remove --force'")
+			fmt.Fprintln(os.Stderr, "are revoked first.")
+			fmt.Fprintln(os.Stderr, "\nOptions:")
+			fmt.Fprintln(os.Stderr, "  --all          Purge everything in the trash")
+			fmt.Fprintln(os.Stderr, "  --no-revoke    Purge without revoking keys that are still active")
+			os.Exit(1)
+		}
+		return commands.TrashPurge(parsed.positional, parsed.has("all"), parsed.has("no-revoke"))
+	default:
+		fmt.Fprintln(os.Stderr, "Error: trash command requires a subcommand")
+		fmt.Fprintf(os.Stderr, "\nUsage: %s trash <subcommand>\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "\nSubcommands:")
+		fmt.Fprintln(os.Stderr, "  list                List removed secrets")
+		fmt.Fprintln(os.Stderr, "  restore <key-name>  Put a removed secret back (--as <new-name>)")
+		fmt.Fprintln(os.Stderr, "  purge <key-name>... Delete removed secrets for good (--all, --no-revoke)")
+		fmt.Fprintf(os.Stderr, "\n'lean_vault gc' purges secrets removed more than trash_retention_days ago\n")
+		fmt.Fprintf(os.Stderr, "(default %d; 0 keeps them) as set in settings.yml.\n", vault.DefaultTrashRetentionDays)
+		os.Exit(1)
+	}
+	return nil
+}
+
+// filterFlags are the flags parsed by parseFilter
+var filterFlags = []string{"prefix", "tag", "owner"}
+
+// parseFilter builds a filter from --prefix, --tag and --owner
+func parseFilter(parsed parsedArgs) (vault.Filter, error) {
+	tags, err := parseTags(parsed.values("tag"))
+	if err != nil {
+		return vault.Filter{}, err
+	}
+	prefix, err := parsePrefix(parsed.value("prefix"))
+	if err != nil {
+		return vault.Filter{}, err
+	}
+	return vault.Filter{Prefix: prefix, Tags: tags, Owner: parsed.value("owner")}, nil
+}
+
+// parseAccount returns the --account value, or the default account
+func parseAccount(parsed parsedArgs) (string, error) {
+	if !parsed.has("account") {
+		return vault.DefaultAccount, nil
+	}
+	account := parsed.value("account")
+	if err := vault.ValidateAccountName(account); err != nil {
+		return "", err
+	}
+	return account, nil
+}
+
+// parsePrefixArg takes the namespace from an optional positional argument,
+// for commands that accept "list prod/" as well as "list --prefix prod/"
+func parsePrefixArg(parsed parsedArgs, filter *vault.Filter) error {
+	switch {
+	case len(parsed.positional) == 0:
+		return nil
+	case len(parsed.positional) > 1:
+		return fmt.Errorf("expected at most one namespace, got %d arguments", len(parsed.positional))
+	case filter.Prefix != "":
+		return fmt.Errorf("give the namespace either as an argument or with --prefix, not both")
+	}
+	prefix, err := parsePrefix(parsed.positional[0])
+	filter.Prefix = prefix
+	return err
+}
+
+// parsePrefix normalizes a namespace and checks it is a valid name path
+func parsePrefix(value string) (string, error) {
+	prefix := vault.NormalizePrefix(value)
+	if prefix == "" {
+		return "", nil
+	}
+	if err := vault.ValidateName(strings.TrimSuffix(prefix, "/")); err != nil {
+		return "", fmt.Errorf("invalid namespace %q: %w", value, err)
+	}
+	return prefix, nil
+}
+
+// parseTags parses key=value tags
+func parseTags(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	tags := make(map[string]string, len(values))
+	for _, value := range values {
+		key, tagValue, err := vault.ParseTag(value)
+		if err != nil {
+			return nil, err
+		}
+		tags[key] = tagValue
+	}
+	return tags, nil
+}
+
+// printFilterOptions describes the flags parsed by parseFilter
+func printFilterOptions() {
+	fmt.Fprintln(os.Stderr, "  --prefix <namespace> Only keys under this namespace, e.g. staging/")
+	fmt.Fprintln(os.Stderr, "  --tag <key=value>    Only keys with this tag; repeatable, all must match")
+	fmt.Fprintln(os.Stderr, "  --owner <name>       Only keys with this owner")
+}
+
+// parseSince accepts a duration back from now, a date or an RFC 3339 time
+func parseSince(value string) (time.Time, error) {
+	if d, err := vault.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since value %q", value)
+}
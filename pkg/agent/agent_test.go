@@ -0,0 +1,205 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+func setupTestAgent(t *testing.T) (*vault.Vault, *Server) {
+	t.Setenv("HOME", t.TempDir())
+
+	v, err := vault.New()
+	if err != nil {
+		t.Fatalf("Failed to resolve vault: %v", err)
+	}
+	if err := v.Init("test-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+	if err := v.AddSecret("my-key", "test-value", "test-id"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+
+	server := NewServer(v, time.Minute)
+	if err := server.Listen(); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go server.Serve()
+	t.Cleanup(func() { server.Close() })
+
+	return v, server
+}
+
+func TestAgentRequests(t *testing.T) {
+	v, server := setupTestAgent(t)
+
+	client, err := Dial(server.SocketPath())
+	if err != nil {
+		t.Fatalf("Failed to dial agent: %v", err)
+	}
+	defer client.Close()
+
+	value, err := client.Get("my-key")
+	if err != nil {
+		t.Fatalf("Failed to get secret: %v", err)
+	}
+	if value != "test-value" {
+		t.Errorf("Got wrong secret value: got %v, want %v", value, "test-value")
+	}
+
+	if _, err := client.Get("missing"); err == nil {
+		t.Error("Getting a missing secret should fail")
+	}
+
+	listing, err := client.List()
+	if err != nil {
+		t.Fatalf("Failed to list secrets: %v", err)
+	}
+	if !listing.HasProvisioningKey || len(listing.Secrets) != 1 || listing.Secrets[0].Name != "my-key" {
+		t.Errorf("Got wrong listing: %+v", listing)
+	}
+
+	env, err := client.ExecEnv(nil)
+	if err != nil {
+		t.Fatalf("Failed to get env: %v", err)
+	}
+	if len(env) != 1 || env["MY_KEY"] != "test-value" {
+		t.Errorf("Got wrong env: %v", env)
+	}
+
+	// Changes to the vault are picked up without restarting the agent
+	if err := v.UpdateSecret("my-key", "rotated-value", "new-id"); err != nil {
+		t.Fatalf("Failed to update secret: %v", err)
+	}
+	value, err = client.Get("my-key")
+	if err != nil {
+		t.Fatalf("Failed to get secret after update: %v", err)
+	}
+	if value != "rotated-value" {
+		t.Errorf("Agent served stale value: got %v, want %v", value, "rotated-value")
+	}
+}
+
+func TestAgentRecordsRequests(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	v, err := vault.New()
+	if err != nil {
+		t.Fatalf("Failed to resolve vault: %v", err)
+	}
+	if err := v.Init("test-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+	if err := v.AddSecret("my-key", "test-value", "test-id"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+
+	var served []Served
+	server := NewServer(v, time.Minute)
+	server.SetRecorder(func(s Served) {
+		served = append(served, s)
+	})
+	if err := server.Listen(); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go server.Serve()
+	t.Cleanup(func() { server.Close() })
+
+	client, err := Dial(server.SocketPath())
+	if err != nil {
+		t.Fatalf("Failed to dial agent: %v", err)
+	}
+	defer client.Close()
+
+	client.Get("my-key")
+	client.Get("missing")
+	client.List()
+	client.ExecEnv([]string{"my-key"})
+
+	// Requests on one connection are answered in order, each before the
+	// response is sent
+	if len(served) != 4 {
+		t.Fatalf("Expected 4 recorded requests, got %+v", served)
+	}
+	if served[0].Method != MethodGet || served[0].Names[0] != "my-key" || served[0].Err != nil {
+		t.Errorf("Unexpected record for get: %+v", served[0])
+	}
+	if served[1].Err == nil {
+		t.Errorf("Failed get should be recorded with its error: %+v", served[1])
+	}
+	if served[2].Method != MethodList || served[3].Method != MethodExecEnv || served[3].Names[0] != "my-key" {
+		t.Errorf("Unexpected records: %+v", served[2:])
+	}
+}
+
+func TestAgentRefusesSecondInstance(t *testing.T) {
+	v, _ := setupTestAgent(t)
+
+	if err := NewServer(v, time.Minute).Listen(); err == nil {
+		t.Error("Starting a second agent on the same socket should fail")
+	}
+}
+
+func TestAgentIdleLock(t *testing.T) {
+	_, server := setupTestAgent(t)
+
+	client, err := Dial(server.SocketPath())
+	if err != nil {
+		t.Fatalf("Failed to dial agent: %v", err)
+	}
+	defer client.Close()
+	if _, err := client.Get("my-key"); err != nil {
+		t.Fatalf("Failed to get secret: %v", err)
+	}
+
+	// Not idle yet
+	server.lockIfIdle(time.Now())
+	if !server.cache.Loaded() {
+		t.Error("Agent should keep secrets before the idle timeout")
+	}
+
+	server.lockIfIdle(time.Now().Add(2 * time.Minute))
+
+	if server.cache.Loaded() {
+		t.Error("Agent should drop secrets after the idle timeout")
+	}
+
+	// The next request unlocks it again
+	if _, err := client.Get("my-key"); err != nil {
+		t.Fatalf("Failed to get secret after locking: %v", err)
+	}
+}
+
+func TestAgentJanitor(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	v, err := vault.New()
+	if err != nil {
+		t.Fatalf("Failed to resolve vault: %v", err)
+	}
+	if err := v.Init("test-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+
+	runs := make(chan time.Time, 10)
+	server := NewServer(v, time.Minute)
+	server.SetJanitor(10*time.Millisecond, func(now time.Time) {
+		runs <- now
+	})
+	if err := server.Listen(); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go server.Serve()
+	t.Cleanup(func() { server.Close() })
+
+	// The janitor runs on start and then periodically, even though no
+	// request has unlocked the agent
+	for i := 0; i < 2; i++ {
+		select {
+		case <-runs:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Janitor ran %d times, want at least 2", i)
+		}
+	}
+}
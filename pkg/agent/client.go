@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// dialTimeout keeps callers from hanging on an unresponsive agent
+const dialTimeout = time.Second
+
+// Client talks to a running agent
+type Client struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	nextID int
+}
+
+// Dial connects to the agent listening on socketPath
+func Dial(socketPath string) (*Client, error) {
+	conn, err := net.DialTimeout("unix", socketPath, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+// DialIfRunning connects to the agent unless it isn't running or has been
+// disabled with LEAN_VAULT_NO_AGENT, in which case it returns nil
+func DialIfRunning(socketPath string) *Client {
+	if noAgent := strings.ToLower(os.Getenv("LEAN_VAULT_NO_AGENT")); noAgent == "1" || noAgent == "true" {
+		return nil
+	}
+	c, err := Dial(socketPath)
+	if err != nil {
+		return nil
+	}
+	return c
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// call sends a request and decodes the result into out
+func (c *Client) call(method string, params, out interface{}) error {
+	c.nextID++
+	req := request{JSONRPC: "2.0", ID: c.nextID, Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("failed to marshal parameters: %w", err)
+		}
+		req.Params = data
+	}
+
+	c.conn.SetDeadline(time.Now().Add(connTimeout))
+	if err := json.NewEncoder(c.conn).Encode(req); err != nil {
+		return fmt.Errorf("failed to send request to agent: %w", err)
+	}
+
+	line, err := c.reader.ReadBytes('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read response from agent: %w", err)
+	}
+
+	var resp response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return fmt.Errorf("invalid response from agent: %w", err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, out)
+}
+
+// Get returns the value of a secret
+func (c *Client) Get(name string) (string, error) {
+	var result GetResult
+	if err := c.call(MethodGet, GetParams{Name: name}, &result); err != nil {
+		return "", err
+	}
+	return result.Value, nil
+}
+
+// List returns the stored secrets
+func (c *Client) List() (*ListResult, error) {
+	var result ListResult
+	if err := c.call(MethodList, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ExecEnv returns the named secrets, or all of them, keyed by environment variable name
+func (c *Client) ExecEnv(names []string) (map[string]string, error) {
+	var result ExecEnvResult
+	if err := c.call(MethodExecEnv, ExecEnvParams{Names: names}, &result); err != nil {
+		return nil, err
+	}
+	return result.Env, nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package agent
+
+import "net"
+
+// checkPeer relies on the socket's owner-only permissions where SO_PEERCRED
+// is not available
+func checkPeer(conn net.Conn) error {
+	return nil
+}
@@ -0,0 +1,116 @@
+// Package agent keeps decrypted secrets in a long-running process and serves
+// them over a Unix socket, so repeated lookups don't pay for decrypting the
+// whole vault each time.
+//
+// The protocol is JSON-RPC 2.0 with one request or response per line.
+package agent
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+const (
+	// MethodGet returns the value of one secret
+	MethodGet = "get"
+	// MethodList returns the names and types of stored secrets
+	MethodList = "list"
+	// MethodExecEnv returns secrets as environment variable assignments
+	MethodExecEnv = "exec-env"
+)
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeServerError    = -32000
+)
+
+// request is a JSON-RPC request
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int             `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is a JSON-RPC response
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int             `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is the error member of a JSON-RPC response
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// GetParams are the parameters of MethodGet
+type GetParams struct {
+	Name string `json:"name"`
+}
+
+// GetResult is the result of MethodGet
+type GetResult struct {
+	Value string `json:"value"`
+}
+
+// ListItem describes one stored secret
+type ListItem struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// ExpiresAt is set for leased keys
+	ExpiresAt   *time.Time        `json:"expires_at,omitempty"`
+	UpdatedAt   time.Time         `json:"updated_at,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Owner       string            `json:"owner,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	// Account is set for keys provisioned through a named account
+	Account string `json:"account,omitempty"`
+}
+
+// NewListItem describes an entry for a listing
+func NewListItem(name string, entry vault.SecretEntry) ListItem {
+	return ListItem{
+		Name:        name,
+		Type:        entry.SecretType(),
+		ExpiresAt:   entry.ExpiresAt,
+		UpdatedAt:   entry.UpdatedAt,
+		Description: entry.Description,
+		Owner:       entry.Owner,
+		Tags:        entry.Tags,
+		Account:     entry.Account,
+	}
+}
+
+// Metadata returns the item's labels for filtering
+func (i ListItem) Metadata() vault.Metadata {
+	return vault.Metadata{Description: i.Description, Owner: i.Owner, Tags: i.Tags}
+}
+
+// ListResult is the result of MethodList
+type ListResult struct {
+	Secrets            []ListItem `json:"secrets"`
+	HasProvisioningKey bool       `json:"has_provisioning_key"`
+}
+
+// ExecEnvParams are the parameters of MethodExecEnv. An empty list selects
+// every secret except the provisioning key.
+type ExecEnvParams struct {
+	Names []string `json:"names,omitempty"`
+}
+
+// ExecEnvResult is the result of MethodExecEnv, keyed by variable name
+type ExecEnvResult struct {
+	Env map[string]string `json:"env"`
+}
@@ -0,0 +1,329 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+const (
+	// DefaultIdleTimeout is how long decrypted secrets are kept without use
+	DefaultIdleTimeout = 15 * time.Minute
+	// connTimeout closes connections that stay silent this long
+	connTimeout = 30 * time.Second
+)
+
+// Server serves secrets from a vault over a Unix socket
+type Server struct {
+	cache       *vault.Cache
+	socketPath  string
+	idleTimeout time.Duration
+
+	// janitor, if set, runs every janitorInterval while serving
+	janitor         func(now time.Time)
+	janitorInterval time.Duration
+
+	// recorder, if set, is called for every request that reads secrets
+	recorder func(served Served)
+
+	mu       sync.Mutex
+	listener net.Listener
+}
+
+// NewServer creates an agent for the vault. Decrypted secrets are dropped
+// after idleTimeout without requests and reloaded on the next one.
+func NewServer(v *vault.Vault, idleTimeout time.Duration) *Server {
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultIdleTimeout
+	}
+	return &Server{
+		cache:       vault.NewCache(v),
+		socketPath:  v.AgentSocketPath(),
+		idleTimeout: idleTimeout,
+	}
+}
+
+// SocketPath returns the path the server listens on
+func (s *Server) SocketPath() string {
+	return s.socketPath
+}
+
+// SetJanitor registers a task, such as revoking expired leases, that runs
+// when the server starts serving and then every interval. It must be called
+// before Serve.
+func (s *Server) SetJanitor(interval time.Duration, task func(now time.Time)) {
+	s.janitorInterval = interval
+	s.janitor = task
+}
+
+// Served describes a request the server answered
+type Served struct {
+	Method string
+	// Names lists the secrets the request asked for; an exec-env request
+	// without names asks for all of them
+	Names []string
+	// Err is the error returned to the client, if the request failed
+	Err error
+}
+
+// SetRecorder registers a function, such as appending to the audit log, that
+// is called after each get, list and exec-env request. It must be called
+// before Serve.
+func (s *Server) SetRecorder(record func(served Served)) {
+	s.recorder = record
+}
+
+// Listen creates the socket with owner-only permissions. A stale socket left
+// by an agent that exited uncleanly is replaced; a live one is an error.
+func (s *Server) Listen() error {
+	if _, err := os.Stat(s.socketPath); err == nil {
+		if c, err := Dial(s.socketPath); err == nil {
+			c.Close()
+			return fmt.Errorf("agent already running on %s", s.socketPath)
+		}
+		if err := os.Remove(s.socketPath); err != nil {
+			return fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	// Restrict permissions before the socket exists so there is no window
+	// where another user could connect
+	oldMask := umask(0077)
+	listener, err := net.Listen("unix", s.socketPath)
+	umask(oldMask)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.socketPath, err)
+	}
+	if err := os.Chmod(s.socketPath, vault.DefaultFileMode); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+	return nil
+}
+
+// Serve accepts connections until Close is called
+func (s *Server) Serve() error {
+	s.mu.Lock()
+	listener := s.listener
+	s.mu.Unlock()
+	if listener == nil {
+		return fmt.Errorf("agent is not listening")
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go s.lockWhenIdle(stop)
+	if s.janitor != nil {
+		go s.runJanitor(stop)
+	}
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+		go s.handleConn(conn)
+	}
+}
+
+// Close stops the server, removes the socket and drops decrypted secrets
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cache.Clear()
+	if s.listener == nil {
+		return nil
+	}
+	err := s.listener.Close()
+	s.listener = nil
+	os.Remove(s.socketPath)
+	return err
+}
+
+// lockWhenIdle drops decrypted secrets once the idle timeout passes
+func (s *Server) lockWhenIdle(stop chan struct{}) {
+	interval := s.idleTimeout / 4
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.lockIfIdle(time.Now())
+		}
+	}
+}
+
+// runJanitor runs the janitor task immediately and then on every interval
+func (s *Server) runJanitor(stop chan struct{}) {
+	s.janitor(time.Now())
+
+	ticker := time.NewTicker(s.janitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			s.janitor(now)
+		}
+	}
+}
+
+// lockIfIdle drops decrypted secrets if nothing has used them since the idle timeout
+func (s *Server) lockIfIdle(now time.Time) {
+	s.cache.ClearIfIdle(now, s.idleTimeout)
+}
+
+// handleConn answers requests on one connection until it closes
+func (s *Server) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	if err := checkPeer(conn); err != nil {
+		fmt.Fprintf(os.Stderr, "Rejected agent connection: %v\n", err)
+		return
+	}
+
+	reader := bufio.NewReader(conn)
+	encoder := json.NewEncoder(conn)
+	for {
+		conn.SetDeadline(time.Now().Add(connTimeout))
+
+		line, err := reader.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			return
+		}
+
+		var req request
+		var resp response
+		if jsonErr := json.Unmarshal(line, &req); jsonErr != nil {
+			resp = response{Error: &rpcError{Code: codeParseError, Message: "invalid request"}}
+		} else {
+			resp = s.handle(req)
+		}
+		resp.JSONRPC = "2.0"
+		resp.ID = req.ID
+
+		if err := encoder.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+// handle dispatches a single request
+func (s *Server) handle(req request) response {
+	var result interface{}
+	var err *rpcError
+	var names []string
+
+	switch req.Method {
+	case MethodGet:
+		var params GetParams
+		if jsonErr := json.Unmarshal(req.Params, &params); jsonErr != nil || params.Name == "" {
+			return response{Error: &rpcError{Code: codeInvalidParams, Message: "get requires a name"}}
+		}
+		names = []string{params.Name}
+		result, err = s.get(params)
+	case MethodList:
+		result, err = s.list()
+	case MethodExecEnv:
+		var params ExecEnvParams
+		if len(req.Params) > 0 {
+			if jsonErr := json.Unmarshal(req.Params, &params); jsonErr != nil {
+				return response{Error: &rpcError{Code: codeInvalidParams, Message: "invalid exec-env parameters"}}
+			}
+		}
+		names = params.Names
+		result, err = s.execEnv(params)
+	default:
+		return response{Error: &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("unknown method %s", req.Method)}}
+	}
+
+	if s.recorder != nil {
+		served := Served{Method: req.Method, Names: names}
+		if err != nil {
+			served.Err = err
+		}
+		s.recorder(served)
+	}
+
+	if err != nil {
+		return response{Error: err}
+	}
+	data, jsonErr := json.Marshal(result)
+	if jsonErr != nil {
+		return response{Error: &rpcError{Code: codeServerError, Message: jsonErr.Error()}}
+	}
+	return response{Result: data}
+}
+
+func (s *Server) get(params GetParams) (*GetResult, *rpcError) {
+	value, err := s.cache.Get(params.Name)
+	if err != nil {
+		return nil, &rpcError{Code: codeServerError, Message: err.Error()}
+	}
+	return &GetResult{Value: value}, nil
+}
+
+func (s *Server) list() (*ListResult, *rpcError) {
+	secrets, entries, err := s.cache.Snapshot()
+	if err != nil {
+		return nil, &rpcError{Code: codeServerError, Message: err.Error()}
+	}
+
+	result := &ListResult{Secrets: []ListItem{}}
+	_, result.HasProvisioningKey = secrets[vault.MainProvisioningKeyName]
+	for name, entry := range entries {
+		result.Secrets = append(result.Secrets, NewListItem(name, entry))
+	}
+	sort.Slice(result.Secrets, func(i, j int) bool {
+		return result.Secrets[i].Name < result.Secrets[j].Name
+	})
+	return result, nil
+}
+
+func (s *Server) execEnv(params ExecEnvParams) (*ExecEnvResult, *rpcError) {
+	secrets, entries, err := s.cache.Snapshot()
+	if err != nil {
+		return nil, &rpcError{Code: codeServerError, Message: err.Error()}
+	}
+
+	names := params.Names
+	if len(names) == 0 {
+		for name := range entries {
+			names = append(names, name)
+		}
+	}
+
+	result := &ExecEnvResult{Env: make(map[string]string, len(names))}
+	for _, name := range names {
+		value, ok := secrets[name]
+		if !ok {
+			return nil, &rpcError{Code: codeServerError, Message: fmt.Sprintf("secret %s not found", name)}
+		}
+		result.Env[vault.EnvVarName(name)] = value
+	}
+	return result, nil
+}
@@ -0,0 +1,10 @@
+//go:build unix
+
+package agent
+
+import "syscall"
+
+// umask sets the file mode creation mask and returns the previous one
+func umask(mask int) int {
+	return syscall.Umask(mask)
+}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package agent
+
+// umask is a no-op on Windows, which has no file mode creation mask
+func umask(mask int) int {
+	return 0
+}
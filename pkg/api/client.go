@@ -7,10 +7,12 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strings"
 )
 
 const (
-	defaultBaseURL = "https://openrouter.ai/api/v1"
+	// DefaultBaseURL is the OpenRouter API endpoint
+	DefaultBaseURL = "https://openrouter.ai/api/v1"
 )
 
 // Client represents the OpenRouter API client
@@ -35,10 +37,35 @@ type KeyResponse struct {
 	} `json:"data"`
 }
 
+// KeyDetails describes an existing API key, including its usage
+type KeyDetails struct {
+	Name           string   `json:"name"`
+	Label          string   `json:"label,omitempty"`
+	Limit          *float64 `json:"limit"`
+	LimitRemaining *float64 `json:"limit_remaining"`
+	Usage          float64  `json:"usage"`
+	Disabled       bool     `json:"disabled"`
+	CreatedAt      string   `json:"created_at"`
+	UpdatedAt      string   `json:"updated_at"`
+	Hash           string   `json:"hash"`
+}
+
+// KeyInfo is the response for a single API key
+type KeyInfo struct {
+	Data KeyDetails `json:"data"`
+}
+
+// KeyUpdate changes an existing API key. Nil fields are left unchanged.
+type KeyUpdate struct {
+	Name     *string  `json:"name,omitempty"`
+	Limit    *float64 `json:"limit,omitempty"`
+	Disabled *bool    `json:"disabled,omitempty"`
+}
+
 // NewClient creates a new OpenRouter API client
 func NewClient(provisionKey string) *Client {
 	return &Client{
-		baseURL:      defaultBaseURL,
+		baseURL:      DefaultBaseURL,
 		provisionKey: provisionKey,
 		httpClient:   &http.Client{},
 	}
@@ -49,13 +76,29 @@ func (c *Client) SetDebug(debug bool) {
 	c.debug = debug
 }
 
+// SetBaseURL changes the API endpoint requests are sent to
+func (c *Client) SetBaseURL(baseURL string) {
+	c.baseURL = strings.TrimRight(baseURL, "/")
+}
+
 // CreateKey creates a new API key
 func (c *Client) CreateKey(name string) (*KeyResponse, error) {
-	url := fmt.Sprintf("%s/keys", c.baseURL)
-
-	payload := map[string]interface{}{
+	return c.createKey(name, map[string]interface{}{
 		"name": name,
-	}
+	})
+}
+
+// CreateKeyWithLimit creates a new API key with a spend limit in dollars
+func (c *Client) CreateKeyWithLimit(name string, limit float64) (*KeyResponse, error) {
+	return c.createKey(name, map[string]interface{}{
+		"name":  name,
+		"limit": limit,
+	})
+}
+
+// createKey sends a key creation request with the given payload
+func (c *Client) createKey(name string, payload map[string]interface{}) (*KeyResponse, error) {
+	url := fmt.Sprintf("%s/keys", c.baseURL)
 
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
@@ -142,3 +185,160 @@ func (c *Client) RevokeKey(keyID string) error {
 
 	return nil
 }
+
+// GetKey fetches details and usage for an API key
+func (c *Client) GetKey(keyID string) (*KeyInfo, error) {
+	url := fmt.Sprintf("%s/keys/%s", c.baseURL, keyID)
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.provisionKey)
+
+	if c.debug {
+		fmt.Fprintf(os.Stderr, "DEBUG: Fetching key with ID: %s\n", keyID)
+		fmt.Fprintf(os.Stderr, "DEBUG: URL: %s\n", url)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if c.debug {
+		fmt.Fprintf(os.Stderr, "DEBUG: Response status: %s\n", resp.Status)
+		fmt.Fprintf(os.Stderr, "DEBUG: Response body: %s\n", string(body))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error: %s", string(body))
+	}
+
+	var info KeyInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &info, nil
+}
+
+// UpdateKey changes the name, limit or disabled state of an API key
+func (c *Client) UpdateKey(keyID string, update KeyUpdate) (*KeyInfo, error) {
+	url := fmt.Sprintf("%s/keys/%s", c.baseURL, keyID)
+
+	jsonData, err := json.Marshal(update)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest("PATCH", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.provisionKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	if c.debug {
+		fmt.Fprintf(os.Stderr, "DEBUG: Updating key with ID: %s\n", keyID)
+		fmt.Fprintf(os.Stderr, "DEBUG: URL: %s\n", url)
+		fmt.Fprintf(os.Stderr, "DEBUG: Request body: %s\n", string(jsonData))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if c.debug {
+		fmt.Fprintf(os.Stderr, "DEBUG: Response status: %s\n", resp.Status)
+		fmt.Fprintf(os.Stderr, "DEBUG: Response body: %s\n", string(body))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error: %s", string(body))
+	}
+
+	var info KeyInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &info, nil
+}
+
+// ListKeys fetches every API key the provisioning key can manage, including
+// disabled ones. It also serves to check that the provisioning key works.
+func (c *Client) ListKeys() ([]KeyDetails, error) {
+	var keys []KeyDetails
+	seen := make(map[string]bool)
+	for {
+		url := fmt.Sprintf("%s/keys?include_disabled=true&offset=%d", c.baseURL, len(keys))
+
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+c.provisionKey)
+
+		if c.debug {
+			fmt.Fprintf(os.Stderr, "DEBUG: Listing keys from offset %d\n", len(keys))
+			fmt.Fprintf(os.Stderr, "DEBUG: URL: %s\n", url)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("request failed: %w", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+
+		if c.debug {
+			fmt.Fprintf(os.Stderr, "DEBUG: Response status: %s\n", resp.Status)
+			fmt.Fprintf(os.Stderr, "DEBUG: Response body: %s\n", string(body))
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("API error: %s", string(body))
+		}
+
+		var page struct {
+			Data []KeyDetails `json:"data"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+
+		// Stop at an empty page, or one the server repeated because it
+		// doesn't paginate
+		added := 0
+		for _, key := range page.Data {
+			if !seen[key.Hash] {
+				seen[key.Hash] = true
+				keys = append(keys, key)
+				added++
+			}
+		}
+		if added == 0 {
+			return keys, nil
+		}
+	}
+}
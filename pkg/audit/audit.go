@@ -0,0 +1,215 @@
+// Package audit records vault operations in an append-only log. Each entry
+// carries the hash of the entry before it, so that editing, removing or
+// reordering entries breaks the chain, and can be signed with an HMAC so
+// that the chain can't simply be recomputed by someone without the key.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"time"
+)
+
+// FileMode is the permission used for the audit log
+const FileMode = 0600
+
+// Entry is a single record in the audit log
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	Name   string    `json:"name,omitempty"`
+	// User is the local account that ran the operation
+	User string `json:"user,omitempty"`
+	// Details holds action-specific fields such as key IDs or a reason
+	Details map[string]string `json:"details,omitempty"`
+
+	// Seq numbers hashed entries from 1
+	Seq int `json:"seq,omitempty"`
+	// PrevHash is the hash of the entry before, empty for the first one
+	PrevHash string `json:"prev_hash,omitempty"`
+	// Hash covers every other field except MAC
+	Hash string `json:"hash,omitempty"`
+	// KeyID identifies the key used for MAC, if the entry is signed
+	KeyID string `json:"key_id,omitempty"`
+	MAC   string `json:"mac,omitempty"`
+}
+
+// computeHash returns the hash of the entry's content and chain fields
+func (e Entry) computeHash() (string, error) {
+	e.Hash = ""
+	e.MAC = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// computeMAC returns the HMAC of the entry's hash under key
+func computeMAC(key []byte, hash string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Log is an append-only log of JSON entries, one per line
+type Log struct {
+	path  string
+	keyID string
+	key   []byte
+}
+
+// New returns the log stored at path
+func New(path string) *Log {
+	return &Log{path: path}
+}
+
+// Path returns the location of the log file
+func (l *Log) Path() string {
+	return l.path
+}
+
+// SetKey makes Append sign entries with an HMAC under key. The ID is stored
+// with each entry so that Verify can find the key again after it changes.
+func (l *Log) SetKey(keyID string, key []byte) {
+	l.keyID = keyID
+	l.key = key
+}
+
+// Append adds an entry to the end of the log, creating it if needed, and
+// links it to the last entry
+func (l *Log) Append(entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.Time = entry.Time.UTC()
+	if entry.User == "" {
+		entry.User = currentUser()
+	}
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_RDWR, FileMode)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	// Hold the lock from reading the last entry until writing this one so
+	// that concurrent commands don't fork the chain
+	if err := lockFile(f); err != nil {
+		return fmt.Errorf("failed to lock audit log: %w", err)
+	}
+	defer unlockFile(f)
+
+	last, err := lastEntry(f)
+	if err != nil {
+		return err
+	}
+	entry.Seq = 1
+	entry.PrevHash = ""
+	if last != nil && last.Hash != "" {
+		entry.Seq = last.Seq + 1
+		entry.PrevHash = last.Hash
+	}
+	entry.KeyID = ""
+	entry.MAC = ""
+	if l.key != nil {
+		entry.KeyID = l.keyID
+	}
+
+	if entry.Hash, err = entry.computeHash(); err != nil {
+		return err
+	}
+	if l.key != nil {
+		entry.MAC = computeMAC(l.key, entry.Hash)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// Read returns every entry in the log. A missing log has no entries.
+func (l *Log) Read() ([]Entry, error) {
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	lines := bufio.NewScanner(f)
+	lines.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lineNo := 1; lines.Scan(); lineNo++ {
+		var entry Entry
+		if err := json.Unmarshal(lines.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("line %d of audit log is not a valid entry: %w", lineNo, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := lines.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// lastEntry returns the final entry of the log, or nil if it is empty
+func lastEntry(f *os.File) (*Entry, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat audit log: %w", err)
+	}
+	size := info.Size()
+	if size == 0 {
+		return nil, nil
+	}
+
+	// Read backwards in growing chunks until the last line is complete
+	for chunk := int64(4096); ; chunk *= 2 {
+		if chunk > size {
+			chunk = size
+		}
+		buf := make([]byte, chunk)
+		if _, err := f.ReadAt(buf, size-chunk); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+
+		trimmed := bytes.TrimRight(buf, "\n")
+		start := bytes.LastIndexByte(trimmed, '\n')
+		if start < 0 && chunk < size {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(trimmed[start+1:], &entry); err != nil {
+			return nil, fmt.Errorf("last line of audit log is not a valid entry: %w", err)
+		}
+		return &entry, nil
+	}
+}
+
+// currentUser names the account running the process
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
@@ -0,0 +1,208 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	log := New(path)
+
+	entries := []Entry{
+		{Action: "incident", Name: "key-1", Details: map[string]string{"reason": "shown on stream"}},
+		{Action: "incident", Name: "key-2"},
+	}
+	for _, entry := range entries {
+		if err := log.Append(entry); err != nil {
+			t.Fatalf("Failed to append entry: %v", err)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat audit log: %v", err)
+	}
+	if info.Mode().Perm() != FileMode {
+		t.Errorf("Audit log has wrong permissions: got %v, want %v", info.Mode().Perm(), os.FileMode(FileMode))
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	defer f.Close()
+
+	var got []Entry
+	lines := bufio.NewScanner(f)
+	for lines.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(lines.Bytes(), &entry); err != nil {
+			t.Fatalf("Failed to parse entry: %v", err)
+		}
+		got = append(got, entry)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(got))
+	}
+	if got[0].Name != "key-1" || got[0].Details["reason"] != "shown on stream" || got[0].Time.IsZero() {
+		t.Errorf("Unexpected first entry: %+v", got[0])
+	}
+}
+
+func TestHashChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	log := New(path)
+	key := []byte("test-audit-key")
+	log.SetKey("key-1", key)
+
+	for _, name := range []string{"key-1", "key-2", "key-3"} {
+		if err := log.Append(Entry{Action: "get", Name: name}); err != nil {
+			t.Fatalf("Failed to append entry: %v", err)
+		}
+	}
+
+	entries, err := log.Read()
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+	for i, entry := range entries {
+		if entry.Seq != i+1 || entry.Hash == "" || entry.MAC == "" || entry.User == "" {
+			t.Errorf("Entry %d is missing chain fields: %+v", i, entry)
+		}
+		if i > 0 && entry.PrevHash != entries[i-1].Hash {
+			t.Errorf("Entry %d does not link to the one before it", i)
+		}
+	}
+
+	keys := map[string][]byte{"key-1": key}
+	report, err := log.Verify(keys)
+	if err != nil {
+		t.Fatalf("Failed to verify audit log: %v", err)
+	}
+	if !report.OK() || report.Signed != 3 {
+		t.Errorf("Expected 3 signed entries and no problems, got %+v", report)
+	}
+
+	// Without the key the signatures can't be checked
+	report, err = log.Verify(nil)
+	if err != nil {
+		t.Fatalf("Failed to verify audit log: %v", err)
+	}
+	if report.OK() {
+		t.Error("Verifying signed entries without their key should report problems")
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	key := []byte("test-audit-key")
+	keys := map[string][]byte{"key-1": key}
+
+	tests := []struct {
+		name   string
+		tamper func(lines []string) []string
+	}{
+		{"modified", func(lines []string) []string {
+			lines[1] = strings.Replace(lines[1], `"name":"key-2"`, `"name":"other"`, 1)
+			return lines
+		}},
+		{"removed", func(lines []string) []string {
+			return append(lines[:1], lines[2:]...)
+		}},
+		{"reordered", func(lines []string) []string {
+			lines[1], lines[2] = lines[2], lines[1]
+			return lines
+		}},
+		{"truncated at the start", func(lines []string) []string {
+			return lines[1:]
+		}},
+		{"unchained", func(lines []string) []string {
+			for i, line := range lines {
+				var entry Entry
+				json.Unmarshal([]byte(line), &entry)
+				entry.Seq, entry.PrevHash, entry.Hash, entry.KeyID, entry.MAC = 0, "", "", "", ""
+				data, _ := json.Marshal(entry)
+				lines[i] = string(data)
+			}
+			return lines
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.log")
+			log := New(path)
+			log.SetKey("key-1", key)
+			for _, name := range []string{"key-1", "key-2", "key-3"} {
+				if err := log.Append(Entry{Action: "get", Name: name}); err != nil {
+					t.Fatalf("Failed to append entry: %v", err)
+				}
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read audit log: %v", err)
+			}
+			lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+			lines = tt.tamper(lines)
+			if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), FileMode); err != nil {
+				t.Fatalf("Failed to write audit log: %v", err)
+			}
+
+			report, err := log.Verify(keys)
+			if err != nil {
+				t.Fatalf("Failed to verify audit log: %v", err)
+			}
+			if report.OK() {
+				t.Errorf("Verify did not detect %s entries", tt.name)
+			}
+		})
+	}
+}
+
+func TestLegacyEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	legacy := `{"time":"2026-01-01T00:00:00Z","action":"incident","name":"key-1"}` + "\n"
+	if err := os.WriteFile(path, []byte(legacy), FileMode); err != nil {
+		t.Fatalf("Failed to write audit log: %v", err)
+	}
+
+	log := New(path)
+	report, err := log.Verify(nil)
+	if err != nil {
+		t.Fatalf("Failed to verify audit log: %v", err)
+	}
+	if !report.OK() || report.Legacy != 1 {
+		t.Errorf("Expected one legacy entry, got %+v", report)
+	}
+
+	// With keys configured, unchained entries can't be told apart from
+	// stripped ones
+	report, err = log.Verify(map[string][]byte{"key-1": []byte("test-audit-key")})
+	if err != nil {
+		t.Fatalf("Failed to verify audit log: %v", err)
+	}
+	if report.OK() {
+		t.Error("Unchained entries should fail verification when keys are given")
+	}
+
+	// Likewise once the log is chained
+	if err := log.Append(Entry{Action: "get", Name: "key-1"}); err != nil {
+		t.Fatalf("Failed to append entry: %v", err)
+	}
+	report, err = log.Verify(nil)
+	if err != nil {
+		t.Fatalf("Failed to verify audit log: %v", err)
+	}
+	if report.OK() || report.Legacy != 0 {
+		t.Errorf("Unchained entries should fail verification in a chained log, got %+v", report)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package audit
+
+import "os"
+
+// lockFile is a no-op on Windows, where the log is not locked
+func lockFile(f *os.File) error {
+	return nil
+}
+
+// unlockFile is a no-op on Windows
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"crypto/hmac"
+	"fmt"
+)
+
+// Problem describes an entry that failed verification
+type Problem struct {
+	// Line is the 1-based line number of the entry in the log
+	Line    int
+	Message string
+}
+
+// Report is the outcome of verifying a log
+type Report struct {
+	// Entries is the number of entries checked
+	Entries int
+	// Legacy counts entries written before the log was hash chained. They
+	// are only accepted in a log with no chained entries, verified without
+	// keys, since otherwise stripping the hashes would hide any tampering.
+	Legacy int
+	// Signed counts entries whose MAC was verified
+	Signed int
+	// Unsigned counts hash-chained entries without a MAC
+	Unsigned int
+	Problems []Problem
+}
+
+// OK reports whether no problems were found
+func (r *Report) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// Verify checks that every entry's hash matches its content, that each entry
+// links to the one before it, and that MACs match under the key named by
+// their key ID. Signed entries whose key isn't in keys are reported as
+// problems, since they can't be checked. Entries without a hash are problems
+// once the log has any chained entry or keys are given.
+func (l *Log) Verify(keys map[string][]byte) (*Report, error) {
+	entries, err := l.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Entries: len(entries)}
+	problem := func(line int, format string, args ...interface{}) {
+		report.Problems = append(report.Problems, Problem{Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	strict := len(keys) > 0
+	for _, entry := range entries {
+		if entry.Hash != "" {
+			strict = true
+			break
+		}
+	}
+
+	var prev *Entry
+	for i := range entries {
+		entry := entries[i]
+		line := i + 1
+
+		if entry.Hash == "" {
+			if strict {
+				problem(line, "entry without a hash (its hash was removed, or it predates chaining)")
+			} else {
+				report.Legacy++
+			}
+			continue
+		}
+
+		hash, err := entry.computeHash()
+		if err != nil {
+			return nil, err
+		}
+		if hash != entry.Hash {
+			problem(line, "content does not match its hash (entry was modified)")
+		}
+
+		switch {
+		case prev == nil && (entry.Seq != 1 || entry.PrevHash != ""):
+			problem(line, "chain starts at sequence %d instead of 1 (earlier entries were removed)", entry.Seq)
+		case prev != nil && entry.PrevHash != prev.Hash:
+			problem(line, "does not link to the entry before it (entries were removed, inserted or reordered)")
+		case prev != nil && entry.Seq != prev.Seq+1:
+			problem(line, "sequence %d follows %d", entry.Seq, prev.Seq)
+		}
+
+		switch {
+		case entry.MAC == "":
+			report.Unsigned++
+		case keys[entry.KeyID] == nil:
+			problem(line, "signed with unknown key %s", entry.KeyID)
+		case !hmac.Equal([]byte(computeMAC(keys[entry.KeyID], entry.Hash)), []byte(entry.MAC)):
+			problem(line, "signature does not match (entry or chain was rewritten)")
+		default:
+			report.Signed++
+		}
+
+		prev = &entries[i]
+	}
+
+	return report, nil
+}
@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// AccountAdd stores the provisioning key of another OpenRouter account, so
+// keys can be provisioned there with --account
+func AccountAdd(name string, noVerify bool) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	if err := vault.ValidateAccountName(name); err != nil {
+		return err
+	}
+	if name == vault.DefaultAccount {
+		return fmt.Errorf("the default account always exists; use 'lean_vault provisioning-key set' to replace its key")
+	}
+	if _, err := v.GetProvisioningKey(name); err == nil {
+		return fmt.Errorf("account %s already exists", name)
+	}
+
+	fmt.Fprintf(os.Stderr, "Create a provisioning key for the '%s' account at %s\n", name, provisioningKeysURL)
+	value, err := readSecretInput("OpenRouter provisioning key (input hidden): ")
+	if err != nil {
+		return err
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return fmt.Errorf("provisioning key cannot be empty")
+	}
+
+	if !noVerify {
+		client, err := newAPIClientWithKey(v, value)
+		if err != nil {
+			return err
+		}
+		if _, err := client.ListKeys(); err != nil {
+			fmt.Fprintln(os.Stderr, "Use --no-verify to store it without checking.")
+			return fmt.Errorf("OpenRouter rejected the provisioning key: %w", err)
+		}
+	}
+
+	if err := v.AddAccount(name, value); err != nil {
+		return fmt.Errorf("failed to add account: %w", err)
+	}
+	recordAudit(v, auditAccount, "", map[string]string{
+		"account":  name,
+		"action":   "add",
+		"verified": fmt.Sprint(!noVerify),
+	})
+
+	fmt.Fprintf(os.Stderr, "✓ Added account '%s'\n", name)
+	fmt.Fprintf(os.Stderr, "Provision keys on it with 'lean_vault add <name> --account %s'.\n", name)
+	return nil
+}
+
+// AccountList shows the accounts and how many stored keys each provisioned
+func AccountList() error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	accounts, err := v.ListAccounts()
+	if err != nil {
+		return fmt.Errorf("failed to list accounts: %w", err)
+	}
+	if len(accounts) == 0 {
+		fmt.Println("No accounts found. Store a provisioning key with 'lean_vault provisioning-key set'.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACCOUNT\tADDED\tKEYS")
+	for _, account := range accounts {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", account.Name, formatTime(account.AddedAt), account.Keys)
+	}
+	w.Flush()
+	return nil
+}
+
+// AccountRemove forgets a named account's provisioning key. Its keys must be
+// removed or purged first, so none are left that can't be revoked.
+func AccountRemove(name string) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	if err := v.RemoveAccount(name); err != nil {
+		return fmt.Errorf("failed to remove account: %w", err)
+	}
+	recordAudit(v, auditAccount, "", map[string]string{"account": name, "action": "remove"})
+
+	fmt.Fprintf(os.Stderr, "✓ Removed account '%s'\n", name)
+	fmt.Fprintln(os.Stderr, "⚠️  Its provisioning key still works until you delete it at:")
+	fmt.Fprintln(os.Stderr, "  ", provisioningKeysURL)
+	return nil
+}
+
+// checkAccount fails unless account has a provisioning key. The default
+// account is always accepted, since its key can be set later.
+func checkAccount(v *vault.Vault, account string) error {
+	if account == vault.DefaultAccount {
+		return nil
+	}
+	if _, err := v.GetProvisioningKey(account); err != nil {
+		return fmt.Errorf("account %s not found; add it with 'lean_vault account add %s'", account, account)
+	}
+	return nil
+}
@@ -3,29 +3,30 @@ package commands
 import (
 	"fmt"
 	"os"
-	"strings"
 
-	"github.com/spacebarlabs/lean_vault/pkg/api"
 	"github.com/spacebarlabs/lean_vault/pkg/vault"
 )
 
-// Add handles the addition of a new API key
-func Add(keyName string) error {
-	v := vault.New()
-
-	// Get the main provisioning key from the vault
-	provisioningKey, err := v.GetMainProvisioningKey()
+// Add handles the addition of a new API key, provisioned through account
+func Add(keyName, account string) error {
+	v, err := vault.New()
 	if err != nil {
-		return fmt.Errorf("failed to get provisioning key: %w", err)
+		return err
 	}
 
-	// Create OpenRouter API client
-	client := api.NewClient(provisioningKey)
+	// Check the name before provisioning a key that couldn't be stored
+	if err := vault.ValidateName(keyName); err != nil {
+		return err
+	}
 
-	// Enable debug mode if environment variable is set
-	if debug := strings.ToLower(os.Getenv("LEAN_VAULT_DEBUG")); debug == "1" || debug == "true" {
-		client.SetDebug(true)
-		fmt.Fprintln(os.Stderr, "Debug mode enabled")
+	if err := checkAccount(v, account); err != nil {
+		return err
+	}
+
+	// Create OpenRouter API client
+	client, err := newAccountClient(v, account)
+	if err != nil {
+		return err
 	}
 
 	fmt.Fprintf(os.Stderr, "Provisioning new API key '%s'...\n", keyName)
@@ -37,11 +38,12 @@ func Add(keyName string) error {
 	}
 
 	// Store the new key in the vault
-	err = v.AddSecret(keyName, resp.Key, resp.Data.Hash)
+	err = v.AddAccountSecret(keyName, resp.Key, resp.Data.Hash, account)
 	if err != nil {
 		return fmt.Errorf("failed to store API key: %w", err)
 	}
 
+	recordAudit(v, auditAdd, keyName, map[string]string{"key_id": resp.Data.Hash})
 	fmt.Fprintf(os.Stderr, "✓ API key '%s' created and stored successfully!\n", keyName)
 	return nil
 }
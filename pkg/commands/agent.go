@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spacebarlabs/lean_vault/pkg/agent"
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// expiryCheckInterval is how often the agent looks for expired keys
+const expiryCheckInterval = time.Minute
+
+// Agent runs the agent in the foreground until interrupted
+func Agent(idleTimeout time.Duration) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	// Fail fast if the vault can't be read rather than on the first request
+	if _, err := v.ListSecrets(); err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+
+	server := agent.NewServer(v, idleTimeout)
+	server.SetJanitor(expiryCheckInterval, func(now time.Time) {
+		revokeExpired(v, now)
+	})
+	server.SetRecorder(func(served agent.Served) {
+		recordAgentRequest(v, served)
+	})
+	if err := server.Listen(); err != nil {
+		return err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		server.Close()
+	}()
+
+	recordAudit(v, auditAgent, "", map[string]string{"event": "start"})
+	defer recordAudit(v, auditAgent, "", map[string]string{"event": "stop"})
+
+	fmt.Fprintf(os.Stderr, "✓ Agent listening on %s\n", server.SocketPath())
+	fmt.Fprintln(os.Stderr, "Commands like 'lean_vault get' will use it while it runs. Press Ctrl-C to stop.")
+
+	if err := server.Serve(); err != nil {
+		server.Close()
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "✓ Agent stopped")
+	return nil
+}
+
+// revokeExpired revokes what gc would, logging the outcome
+func revokeExpired(v *vault.Vault, now time.Time) {
+	targets, err := findGCTargets(v, now)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Warning: Failed to check for expired keys: %v\n", err)
+		return
+	}
+
+	printGCResults(collectGarbage(v, targets))
+}
+
+// agentAuditActions maps agent methods to the audit actions of the matching
+// commands
+var agentAuditActions = map[string]string{
+	agent.MethodGet:     auditGet,
+	agent.MethodList:    auditList,
+	agent.MethodExecEnv: auditExecEnv,
+}
+
+// recordAgentRequest records a request the agent answered in the audit log,
+// the same way the matching command would
+func recordAgentRequest(v *vault.Vault, served agent.Served) {
+	details := map[string]string{"via": "agent"}
+	if served.Err != nil {
+		details["error"] = served.Err.Error()
+	}
+
+	var name string
+	switch {
+	case served.Method == agent.MethodGet:
+		name = served.Names[0]
+	case served.Method == agent.MethodExecEnv && len(served.Names) == 0:
+		details["names"] = "all"
+	case served.Method == agent.MethodExecEnv:
+		details["names"] = strings.Join(served.Names, ",")
+	}
+	recordAudit(v, agentAuditActions[served.Method], name, details)
+}
@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// AnnotateOptions are the metadata changes to make; nil and empty fields are left alone
+type AnnotateOptions struct {
+	Description *string
+	Owner       *string
+	// Tags are added or replaced
+	Tags map[string]string
+	// Untag lists tag keys to remove
+	Untag []string
+}
+
+// Annotate changes the description, owner or tags of a secret
+func Annotate(keyName string, opts AnnotateOptions) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	entry, err := v.GetSecretEntry(keyName)
+	if err != nil {
+		return fmt.Errorf("failed to get secret: %w", err)
+	}
+
+	metadata := entry.Metadata()
+	if opts.Description != nil {
+		metadata.Description = *opts.Description
+	}
+	if opts.Owner != nil {
+		metadata.Owner = *opts.Owner
+	}
+	if metadata.Tags == nil {
+		metadata.Tags = make(map[string]string)
+	}
+	for key, value := range opts.Tags {
+		metadata.Tags[key] = value
+	}
+	for _, key := range opts.Untag {
+		delete(metadata.Tags, key)
+	}
+
+	if err := v.SetMetadata(keyName, metadata); err != nil {
+		return fmt.Errorf("failed to update metadata: %w", err)
+	}
+	recordAudit(v, auditAnnotate, keyName, map[string]string{
+		"description": metadata.Description,
+		"owner":       metadata.Owner,
+		"tags":        vault.FormatTags(metadata.Tags),
+	})
+
+	fmt.Fprintf(os.Stderr, "✓ Updated metadata for '%s'\n", keyName)
+	if entry.Managed {
+		fmt.Fprintln(os.Stderr, "⚠️  This key is managed by a manifest; 'lean_vault apply' will set its metadata back to the manifest's.")
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spacebarlabs/lean_vault/pkg/api"
+	"github.com/spacebarlabs/lean_vault/pkg/manifest"
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// Plan shows the changes apply would make to match the manifest at path,
+// or the project manifest when path is empty
+func Plan(path string) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	changes, _, err := planManifest(v, path)
+	if err != nil {
+		return err
+	}
+	printPlan(changes)
+	return nil
+}
+
+// Apply makes the vault and OpenRouter match the manifest, after showing the
+// plan and asking for confirmation unless yes is set
+func Apply(path string, yes bool) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	changes, current, err := planManifest(v, path)
+	if err != nil {
+		return err
+	}
+	printPlan(changes)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	if !yes {
+		fmt.Fprint(os.Stderr, "\nApply these changes? Only 'yes' will be accepted: ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if strings.TrimSpace(answer) != "yes" {
+			return fmt.Errorf("apply cancelled")
+		}
+	}
+	fmt.Fprintln(os.Stderr)
+
+	clients := newAccountClients(v)
+	failed := 0
+	for _, change := range changes {
+		if err := applyChange(v, clients, change, current[change.Name]); err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "⚠️  Failed to %s %s: %v\n", change.Action, change.Name, err)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "✓ %s %s\n", pastTense(change.Action), change.Name)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d changes failed; run 'lean_vault plan' to see what is left", failed, len(changes))
+	}
+	fmt.Fprintf(os.Stderr, "\n✓ Applied %d changes\n", len(changes))
+	return nil
+}
+
+// planManifest loads the manifest and compares it with the vault and the
+// provider's view of the keys it names
+func planManifest(v *vault.Vault, path string) ([]manifest.Change, map[string]manifest.Current, error) {
+	if path == "" {
+		path = v.ProjectFile()
+	}
+	if path == "" {
+		return nil, nil, fmt.Errorf("no manifest found; pass --file or add keys to a %s", vault.ProjectFile)
+	}
+	m, err := manifest.Load(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries, err := v.ListSecretEntries()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list secrets: %w", err)
+	}
+
+	// Only provisioned keys named in the manifest need their limit and
+	// state fetched from the provider
+	desired := make(map[string]bool, len(m.Keys))
+	for _, key := range m.Keys {
+		desired[key.Name] = true
+	}
+	current := make(map[string]manifest.Current, len(entries))
+	var needed []string
+	for name, entry := range entries {
+		current[name] = manifest.Current{Entry: entry}
+		if entry.IsProvisioned() && desired[name] {
+			needed = append(needed, name)
+		}
+	}
+
+	if len(needed) > 0 {
+		clients := newAccountClients(v)
+		var mu sync.Mutex
+		var lookupErr error
+		forEachConcurrently(needed, DefaultRotateParallelism, func(name string) {
+			client, err := clients.forEntry(entries[name])
+			var info *api.KeyInfo
+			if err == nil {
+				info, err = client.GetKey(entries[name].ID)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				lookupErr = fmt.Errorf("failed to get key %s: %w", name, err)
+				return
+			}
+			cur := current[name]
+			cur.Limit = info.Data.Limit
+			cur.Disabled = info.Data.Disabled
+			current[name] = cur
+		})
+		if lookupErr != nil {
+			return nil, nil, lookupErr
+		}
+	}
+
+	changes, err := manifest.Plan(m, current)
+	if err != nil {
+		return nil, nil, err
+	}
+	return changes, current, nil
+}
+
+// applyChange makes one planned change. New keys are created on the default
+// account; existing ones are changed through the account that provisioned them.
+func applyChange(v *vault.Vault, clients *accountClients, change manifest.Change, cur manifest.Current) error {
+	client, err := clients.forEntry(cur.Entry)
+	if err != nil {
+		return err
+	}
+
+	switch change.Action {
+	case manifest.ActionCreate:
+		return applyCreate(v, client, change.Key)
+	case manifest.ActionUpdate:
+		return applyUpdate(v, client, change, cur.Entry)
+	case manifest.ActionRemove:
+		if err := revokeAndRemove(v, client, change.Name, cur.Entry); err != nil {
+			return err
+		}
+		recordAudit(v, auditApply, change.Name, map[string]string{"change": change.Action, "key_id": cur.Entry.ID})
+		return nil
+	}
+	return fmt.Errorf("unknown change %s", change.Action)
+}
+
+// applyCreate provisions a key from the manifest and stores it as managed
+func applyCreate(v *vault.Vault, client *api.Client, key *manifest.Key) error {
+	var resp *api.KeyResponse
+	var err error
+	if key.Limit != nil {
+		resp, err = client.CreateKeyWithLimit(key.Name, *key.Limit)
+	} else {
+		resp, err = client.CreateKey(key.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	if err := v.AddSecret(key.Name, resp.Key, resp.Data.Hash); err != nil {
+		if revokeErr := client.RevokeKey(resp.Data.Hash); revokeErr != nil {
+			return fmt.Errorf("failed to store API key: %w; the unstored key %s could not be revoked: %v", err, resp.Data.Hash, revokeErr)
+		}
+		return fmt.Errorf("failed to store API key: %w", err)
+	}
+	recordAudit(v, auditApply, key.Name, map[string]string{"change": manifest.ActionCreate, "key_id": resp.Data.Hash})
+
+	if key.Disabled {
+		disabled := true
+		if _, err := client.UpdateKey(resp.Data.Hash, api.KeyUpdate{Disabled: &disabled}); err != nil {
+			return fmt.Errorf("created but failed to disable: %w", err)
+		}
+	}
+
+	metadata := vault.Metadata{Description: key.Description, Owner: key.Owner, Tags: key.Tags, Policy: key.Policy, Managed: true}
+	if key.Limit != nil {
+		metadata.Limit = *key.Limit
+	}
+	if err := v.SetMetadata(key.Name, metadata); err != nil {
+		return fmt.Errorf("created but failed to store metadata: %w", err)
+	}
+	return nil
+}
+
+// applyUpdate changes an existing key's limit, state and metadata
+func applyUpdate(v *vault.Vault, client *api.Client, change manifest.Change, entry vault.SecretEntry) error {
+	key := change.Key
+
+	var update api.KeyUpdate
+	if change.Has("limit") {
+		update.Limit = key.Limit
+	}
+	if change.Has("disabled") {
+		update.Disabled = &key.Disabled
+	}
+	if update.Limit != nil || update.Disabled != nil {
+		if _, err := client.UpdateKey(entry.ID, update); err != nil {
+			return fmt.Errorf("failed to update API key: %w", err)
+		}
+	}
+
+	metadata := entry.Metadata()
+	metadata.Description = key.Description
+	metadata.Owner = key.Owner
+	metadata.Tags = key.Tags
+	metadata.Policy = key.Policy
+	metadata.Managed = true
+	if key.Limit != nil {
+		metadata.Limit = *key.Limit
+	}
+	if err := v.SetMetadata(key.Name, metadata); err != nil {
+		return fmt.Errorf("failed to store metadata: %w", err)
+	}
+
+	details := map[string]string{"change": manifest.ActionUpdate, "key_id": entry.ID}
+	for _, diff := range change.Diffs {
+		details[diff.Field] = diff.To
+	}
+	recordAudit(v, auditApply, key.Name, details)
+	return nil
+}
+
+// printPlan prints the changes in a plan
+func printPlan(changes []manifest.Change) {
+	if len(changes) == 0 {
+		fmt.Println("No changes. The vault matches the manifest.")
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, change := range changes {
+		counts[change.Action]++
+		switch change.Action {
+		case manifest.ActionCreate:
+			fmt.Printf("+ create %s\n", change.Name)
+			fmt.Printf("    limit: %s\n", manifest.FormatLimit(change.Key.Limit))
+			if change.Key.Disabled {
+				fmt.Println("    disabled: true")
+			}
+			if change.Key.Description != "" {
+				fmt.Printf("    description: %q\n", change.Key.Description)
+			}
+			if change.Key.Owner != "" {
+				fmt.Printf("    owner: %s\n", change.Key.Owner)
+			}
+			fmt.Printf("    tags: %s\n", vault.FormatTags(change.Key.Tags))
+			fmt.Printf("    policy: %s\n", manifest.FormatPolicy(change.Key.Policy))
+		case manifest.ActionUpdate:
+			fmt.Printf("~ update %s\n", change.Name)
+			for _, diff := range change.Diffs {
+				fmt.Printf("    %s: %s → %s\n", diff.Field, diff.From, diff.To)
+			}
+		case manifest.ActionRemove:
+			fmt.Printf("- remove %s (revokes the key)\n", change.Name)
+		}
+	}
+	fmt.Printf("\nPlan: %d to create, %d to update, %d to remove.\n",
+		counts[manifest.ActionCreate], counts[manifest.ActionUpdate], counts[manifest.ActionRemove])
+}
+
+// pastTense describes a completed change action
+func pastTense(action string) string {
+	switch action {
+	case manifest.ActionCreate:
+		return "Created"
+	case manifest.ActionUpdate:
+		return "Updated"
+	default:
+		return "Removed"
+	}
+}
@@ -0,0 +1,167 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spacebarlabs/lean_vault/pkg/audit"
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// Audit actions recorded by commands
+const (
+	auditInit            = "init"
+	auditAdd             = "add"
+	auditLease           = "lease"
+	auditSet             = "set"
+	auditGet             = "get"
+	auditList            = "list"
+	auditExecEnv         = "exec-env"
+	auditRemove          = "remove"
+	auditRotate          = "rotate"
+	auditRollback        = "rollback"
+	auditRevoke          = "revoke"
+	auditPolicy          = "policy"
+	auditRender          = "render"
+	auditRedact          = "redact"
+	auditScan            = "scan"
+	auditAgent           = "agent"
+	auditProxy           = "proxy"
+	auditUsage           = "usage"
+	auditHistory         = "history"
+	auditIncident        = "incident"
+	auditApply           = "apply"
+	auditAnnotate        = "annotate"
+	auditExport          = "export"
+	auditMove            = "move"
+	auditCopy            = "copy"
+	auditRestore         = "restore"
+	auditPurge           = "purge"
+	auditProvisioningKey = "provisioning-key"
+	auditAccount         = "account"
+)
+
+// openAuditLog returns the vault's audit log, signing entries with the audit
+// key when the master key is readable
+func openAuditLog(v *vault.Vault) *audit.Log {
+	log := audit.New(v.AuditLogPath())
+	if id, key, err := v.AuditKey(); err == nil {
+		log.SetKey(id, key)
+	}
+	return log
+}
+
+// recordAudit appends an entry to the audit log. Failing to record doesn't
+// fail the command, but is reported.
+func recordAudit(v *vault.Vault, action, name string, details map[string]string) {
+	err := openAuditLog(v).Append(audit.Entry{
+		Action:  action,
+		Name:    name,
+		Details: details,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Warning: Failed to record %s in audit log: %v\n", action, err)
+	}
+}
+
+// AuditVerify checks the audit log's hash chain and signatures
+func AuditVerify() error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	keys, err := v.AuditKeys()
+	if err != nil {
+		return fmt.Errorf("failed to load audit keys: %w", err)
+	}
+
+	report, err := audit.New(v.AuditLogPath()).Verify(keys)
+	if err != nil {
+		return fmt.Errorf("failed to verify audit log: %w", err)
+	}
+
+	if report.Entries == 0 {
+		fmt.Fprintln(os.Stderr, "The audit log is empty.")
+		return nil
+	}
+
+	for _, problem := range report.Problems {
+		fmt.Fprintf(os.Stderr, "✗ Line %d: %s\n", problem.Line, problem.Message)
+	}
+	if report.Legacy > 0 {
+		fmt.Fprintf(os.Stderr, "⚠️  %d entries predate hash chaining and can't be verified\n", report.Legacy)
+	}
+	if report.Unsigned > 0 {
+		fmt.Fprintf(os.Stderr, "⚠️  %d entries are chained but not signed\n", report.Unsigned)
+	}
+
+	if !report.OK() {
+		return fmt.Errorf("audit log failed verification with %d problem(s)", len(report.Problems))
+	}
+
+	fmt.Fprintf(os.Stderr, "✓ Audit log verified: %d entries, %d signed\n", report.Entries, report.Signed)
+	return nil
+}
+
+// AuditShow prints audit log entries recorded at or after since
+func AuditShow(since time.Time) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	entries, err := audit.New(v.AuditLogPath()).Read()
+	if err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tUSER\tACTION\tNAME\tDETAILS")
+	shown := 0
+	for _, entry := range entries {
+		if entry.Time.Before(since) {
+			continue
+		}
+		shown++
+
+		name := entry.Name
+		if name == "" {
+			name = "-"
+		}
+		user := entry.User
+		if user == "" {
+			user = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", entry.Time.Local().Format(time.RFC3339), user, entry.Action, name, formatDetails(entry.Details))
+	}
+	w.Flush()
+
+	if shown == 0 {
+		fmt.Println("No audit entries found.")
+	}
+	return nil
+}
+
+// formatDetails renders entry details as sorted key=value pairs
+func formatDetails(details map[string]string) string {
+	if len(details) == 0 {
+		return "-"
+	}
+
+	keys := make([]string, 0, len(details))
+	for key := range details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = fmt.Sprintf("%s=%s", key, details[key])
+	}
+	return strings.Join(pairs, " ")
+}
@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spacebarlabs/lean_vault/pkg/api"
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// newAPIClient creates an OpenRouter client authenticated with the vault's
+// main provisioning key, with debug output enabled by LEAN_VAULT_DEBUG
+func newAPIClient(v *vault.Vault) (*api.Client, error) {
+	return newAccountClient(v, vault.DefaultAccount)
+}
+
+// newAccountClient creates an OpenRouter client authenticated with an
+// account's provisioning key
+func newAccountClient(v *vault.Vault, account string) (*api.Client, error) {
+	provisioningKey, err := v.GetProvisioningKey(account)
+	if err != nil {
+		if account == vault.DefaultAccount {
+			return nil, fmt.Errorf("failed to get provisioning key: %w; store one with 'lean_vault provisioning-key set'", err)
+		}
+		return nil, fmt.Errorf("failed to get provisioning key: %w", err)
+	}
+	return newAPIClientWithKey(v, provisioningKey)
+}
+
+// newAPIClientWithKey creates an OpenRouter client for the vault's API
+// endpoint authenticated with the given provisioning key
+func newAPIClientWithKey(v *vault.Vault, provisioningKey string) (*api.Client, error) {
+	settings, err := v.LoadSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create OpenRouter API client
+	client := api.NewClient(provisioningKey)
+	if settings.APIBaseURL != "" {
+		client.SetBaseURL(settings.APIBaseURL)
+	}
+
+	// Enable debug mode if environment variable is set
+	if debug := strings.ToLower(os.Getenv("LEAN_VAULT_DEBUG")); debug == "1" || debug == "true" {
+		client.SetDebug(true)
+		fmt.Fprintln(os.Stderr, "Debug mode enabled")
+	}
+
+	return client, nil
+}
+
+// accountClients creates one client per account on first use, for commands
+// that act on keys from several accounts
+type accountClients struct {
+	v       *vault.Vault
+	mu      sync.Mutex
+	clients map[string]*api.Client
+}
+
+// newAccountClients returns an empty client cache for the vault
+func newAccountClients(v *vault.Vault) *accountClients {
+	return &accountClients{v: v, clients: make(map[string]*api.Client)}
+}
+
+// forEntry returns the client for the account that provisioned entry
+func (c *accountClients) forEntry(entry vault.SecretEntry) (*api.Client, error) {
+	return c.forAccount(entry.AccountName())
+}
+
+// forAccount returns the client for account
+func (c *accountClients) forAccount(account string) (*api.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if client, ok := c.clients[account]; ok {
+		return client, nil
+	}
+	client, err := newAccountClient(c.v, account)
+	if err != nil {
+		return nil, err
+	}
+	c.clients[account] = client
+	return client, nil
+}
@@ -0,0 +1,310 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spacebarlabs/lean_vault/pkg/audit"
+	"github.com/spacebarlabs/lean_vault/pkg/redact"
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// fakeOpenRouter records the key operations made against it
+type fakeOpenRouter struct {
+	mu      sync.Mutex
+	created []string
+	revoked []string
+	// fetched records "<key id> <provisioning key>" for each key looked up
+	fetched []string
+}
+
+func (f *fakeOpenRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/keys":
+		var body struct {
+			Name string `json:"name"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		hash := fmt.Sprintf("hash-%d", len(f.created)+1)
+		f.created = append(f.created, hash)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"key":"sk-or-%s","data":{"name":%q,"hash":%q}}`, hash, body.Name, hash)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/keys/"):
+		id := strings.TrimPrefix(r.URL.Path, "/keys/")
+		f.fetched = append(f.fetched, id+" "+strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		fmt.Fprintf(w, `{"data":{"hash":%q,"usage":1.5}}`, id)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/keys/"):
+		f.revoked = append(f.revoked, strings.TrimPrefix(r.URL.Path, "/keys/"))
+		fmt.Fprint(w, `{}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// setupTestVault creates a vault whose API calls go to a fake OpenRouter
+func setupTestVault(t *testing.T, settings string) (*vault.Vault, *fakeOpenRouter) {
+	t.Helper()
+
+	fake := &fakeOpenRouter{}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	v := vault.NewDir(filepath.Join(dir, vault.DefaultVaultDir))
+	t.Setenv(vault.VaultDirEnvVar, v.VaultDir())
+	if err := v.Init("test-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+	settings = "api_base_url: " + server.URL + "\n" + settings
+	if err := os.WriteFile(v.SettingsPath(), []byte(settings), 0600); err != nil {
+		t.Fatalf("Failed to write settings: %v", err)
+	}
+	return v, fake
+}
+
+func TestGCKeepsUpstreamKeysRemovedWithForce(t *testing.T) {
+	v, fake := setupTestVault(t, "trash_retention_days: 1\n")
+
+	for _, name := range []string{"forced", "unrevoked"} {
+		if err := v.AddSecret(name, "sk-"+name, "id-"+name); err != nil {
+			t.Fatalf("Failed to add secret: %v", err)
+		}
+	}
+	if err := v.TrashSecret("forced", false, true); err != nil {
+		t.Fatalf("Failed to trash secret: %v", err)
+	}
+	if err := v.TrashSecret("unrevoked", false, false); err != nil {
+		t.Fatalf("Failed to trash secret: %v", err)
+	}
+
+	targets, err := findGCTargets(v, time.Now().Add(48*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to find gc targets: %v", err)
+	}
+	if len(targets.trash) != 2 {
+		t.Fatalf("Got %d trash targets, want 2", len(targets.trash))
+	}
+	for _, result := range collectGarbage(v, targets) {
+		if result.err != nil {
+			t.Errorf("Failed to collect %s: %v", result.description, result.err)
+		}
+	}
+
+	if len(fake.revoked) != 1 || fake.revoked[0] != "id-unrevoked" {
+		t.Errorf("Revoked %v, want only id-unrevoked", fake.revoked)
+	}
+	if trash, _ := v.ListTrash(); len(trash) != 0 {
+		t.Errorf("Trash should be purged, got %+v", trash)
+	}
+}
+
+func TestCopyProvisionsNewKey(t *testing.T) {
+	v, fake := setupTestVault(t, "")
+
+	if err := v.AddSecret("chatbot", "sk-original", "id-original"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+	if err := v.SetMetadata("chatbot", vault.Metadata{Owner: "alice", Limit: 5}); err != nil {
+		t.Fatalf("Failed to set metadata: %v", err)
+	}
+
+	if err := Copy("chatbot", "chatbot-staging"); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	copied, err := v.GetSecretEntry("chatbot-staging")
+	if err != nil {
+		t.Fatalf("Failed to get copy: %v", err)
+	}
+	if len(fake.created) != 1 || copied.ID != fake.created[0] || copied.Owner != "alice" {
+		t.Errorf("Copy should be backed by a new key and keep the labels, got %+v (created %v)", copied, fake.created)
+	}
+	if value, _ := v.GetSecret("chatbot"); value != "sk-original" {
+		t.Errorf("Original value changed to %q", value)
+	}
+
+	// The copy is independent: removing it leaves the original's key alone
+	if err := Remove("chatbot-staging", false); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if len(fake.revoked) != 1 || fake.revoked[0] != copied.ID {
+		t.Errorf("Revoked %v, want only the copy's key %s", fake.revoked, copied.ID)
+	}
+}
+
+func TestRenderReplacesOutputWithPrivateFile(t *testing.T) {
+	v, _ := setupTestVault(t, "")
+
+	if err := v.SetStaticSecret("db", "postgres://secret"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+	dir := t.TempDir()
+	templatePath := filepath.Join(dir, "config.tmpl")
+	if err := os.WriteFile(templatePath, []byte(`url: {{ secret "db" }}`), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	outputPath := filepath.Join(dir, "config.yml")
+	if err := os.WriteFile(outputPath, []byte("old"), 0644); err != nil {
+		t.Fatalf("Failed to write output: %v", err)
+	}
+
+	if err := Render(templatePath, outputPath); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	info, err := os.Stat(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to stat output: %v", err)
+	}
+	if info.Mode().Perm() != vault.DefaultFileMode {
+		t.Errorf("Output mode is %v, want %v", info.Mode().Perm(), os.FileMode(vault.DefaultFileMode))
+	}
+	if data, _ := os.ReadFile(outputPath); string(data) != "url: postgres://secret" {
+		t.Errorf("Output is %q", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("Temporary files were left behind: %v", entries)
+	}
+}
+
+func TestRotateManyRotatesRepeatedNamesOnce(t *testing.T) {
+	v, fake := setupTestVault(t, "")
+
+	if err := v.AddSecret("chatbot", "sk-old", "id-old"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+
+	if err := RotateMany([]string{"chatbot", "chatbot"}, BulkRotateOptions{JSON: true}); err != nil {
+		t.Fatalf("RotateMany failed: %v", err)
+	}
+	entry, err := v.GetSecretEntry("chatbot")
+	if err != nil {
+		t.Fatalf("Failed to get secret: %v", err)
+	}
+	if len(fake.created) != 1 || entry.ID != fake.created[0] {
+		t.Errorf("Created %v for one key, stored %s", fake.created, entry.ID)
+	}
+	if len(fake.revoked) != 1 || fake.revoked[0] != "id-old" {
+		t.Errorf("Revoked %v, want only id-old", fake.revoked)
+	}
+}
+
+func TestUsageUsesEachKeysAccount(t *testing.T) {
+	v, fake := setupTestVault(t, "")
+
+	if err := v.AddAccount("acme", "acme-provisioning-key"); err != nil {
+		t.Fatalf("Failed to add account: %v", err)
+	}
+	if err := v.AddSecret("personal", "sk-personal", "id-personal"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+	if err := v.AddAccountSecret("client", "sk-client", "id-client", "acme"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+
+	if err := Usage(vault.Filter{}); err != nil {
+		t.Fatalf("Usage failed: %v", err)
+	}
+	want := []string{"id-client acme-provisioning-key", "id-personal test-provisioning-key"}
+	if strings.Join(fake.fetched, ",") != strings.Join(want, ",") {
+		t.Errorf("Fetched %v, want %v", fake.fetched, want)
+	}
+}
+
+func TestGCTrashesKeysPastRevokePolicy(t *testing.T) {
+	v, fake := setupTestVault(t, "")
+
+	if err := v.AddSecret("chatbot", "sk-chatbot", "id-chatbot"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+	if err := v.SetStaticSecret("db", "postgres://secret"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+	revoke := &vault.Policy{MaxAge: "1d", OnExpiry: vault.OnExpiryRevoke}
+	if err := v.SetPolicy("db", revoke); err == nil {
+		t.Error("A static secret should not accept on_expiry: revoke")
+	}
+	if err := v.SetPolicy("chatbot", revoke); err != nil {
+		t.Fatalf("Failed to set policy: %v", err)
+	}
+
+	targets, err := findGCTargets(v, time.Now().Add(48*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to find gc targets: %v", err)
+	}
+	for _, result := range collectGarbage(v, targets) {
+		if result.err != nil {
+			t.Errorf("Failed to collect %s: %v", result.description, result.err)
+		}
+	}
+
+	if len(fake.revoked) != 1 || fake.revoked[0] != "id-chatbot" {
+		t.Errorf("Revoked %v, want only id-chatbot", fake.revoked)
+	}
+	trash, _ := v.ListTrash()
+	if len(trash) != 1 || trash[0].Name != "chatbot" || !trash[0].Revoked {
+		t.Errorf("The expired key should be in the trash as revoked, got %+v", trash)
+	}
+	if value, err := v.GetSecret("db"); err != nil || value != "postgres://secret" {
+		t.Errorf("Static secret should be untouched, got %q (%v)", value, err)
+	}
+}
+
+func TestRedactCoversPreviousValuesInGracePeriod(t *testing.T) {
+	v, _ := setupTestVault(t, "")
+
+	if err := v.AddSecret("chatbot", "sk-or-old-value", "id-old"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+	if err := v.RotateSecretWithGrace("chatbot", "sk-or-new-value", "id-new", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to rotate secret: %v", err)
+	}
+
+	secrets, err := redactedSecrets(v)
+	if err != nil {
+		t.Fatalf("Failed to load secrets: %v", err)
+	}
+	got := redact.New(secrets).String("old sk-or-old-value new sk-or-new-value")
+	if want := "old [REDACTED:chatbot (previous)] new [REDACTED:chatbot]"; got != want {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+}
+
+func TestIncidentDoesNotClaimRevocationWithoutKeyID(t *testing.T) {
+	v, fake := setupTestVault(t, "")
+
+	if err := v.AddSecret("chatbot", "sk-leaked", ""); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+
+	if err := Incident("chatbot", ""); err == nil {
+		t.Error("Incident should fail when the old key could not be revoked")
+	}
+	if len(fake.created) != 1 || len(fake.revoked) != 0 {
+		t.Errorf("Created %v and revoked %v, want one new key and nothing revoked", fake.created, fake.revoked)
+	}
+
+	entries, err := audit.New(v.AuditLogPath()).Read()
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+	var status string
+	for _, entry := range entries {
+		if entry.Action == auditIncident {
+			status = entry.Details["status"]
+		}
+	}
+	if status != statusOldKeyUnknown {
+		t.Errorf("Incident recorded status %q, want %q", status, statusOldKeyUnknown)
+	}
+}
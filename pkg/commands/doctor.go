@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spacebarlabs/lean_vault/pkg/agent"
+	"github.com/spacebarlabs/lean_vault/pkg/audit"
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// Doctor checks the vault's integrity and prints a fix for each problem
+func Doctor() error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	results := v.Diagnose()
+	results = append(results, diagnoseAgentSocket(v)...)
+	results = append(results, diagnoseAuditLog(v)...)
+
+	warnings, problems := 0, 0
+	for _, result := range results {
+		switch result.Severity {
+		case vault.SeverityOK:
+			fmt.Fprintf(os.Stderr, "✓ %s\n", result.Message)
+			continue
+		case vault.SeverityWarning:
+			warnings++
+			fmt.Fprintf(os.Stderr, "⚠️  %s\n", result.Message)
+		default:
+			problems++
+			fmt.Fprintf(os.Stderr, "✗ %s\n", result.Message)
+		}
+		if result.Fix != "" {
+			fmt.Fprintf(os.Stderr, "  Fix: %s\n", result.Fix)
+		}
+	}
+
+	if problems > 0 {
+		return fmt.Errorf("found %d problem(s) and %d warning(s)", problems, warnings)
+	}
+	if warnings > 0 {
+		fmt.Fprintf(os.Stderr, "\n✓ No problems found (%d warning(s))\n", warnings)
+		return nil
+	}
+	fmt.Fprintln(os.Stderr, "\n✓ No problems found")
+	return nil
+}
+
+// diagnoseAgentSocket reports a socket left behind by an agent that didn't
+// shut down cleanly
+func diagnoseAgentSocket(v *vault.Vault) []vault.Diagnosis {
+	path := v.AgentSocketPath()
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	client, err := agent.Dial(path)
+	if err != nil {
+		return []vault.Diagnosis{{
+			Severity: vault.SeverityWarning,
+			Message:  fmt.Sprintf("Agent socket %s is left over from an agent that is no longer running", path),
+			Fix:      "Run: rm " + path,
+		}}
+	}
+	client.Close()
+	return []vault.Diagnosis{{Severity: vault.SeverityOK, Message: "Agent is running"}}
+}
+
+// diagnoseAuditLog verifies the audit log's hash chain and signatures
+func diagnoseAuditLog(v *vault.Vault) []vault.Diagnosis {
+	keys, err := v.AuditKeys()
+	if err != nil {
+		// The vault checks already explain why the keys can't be loaded
+		return nil
+	}
+	report, err := audit.New(v.AuditLogPath()).Verify(keys)
+	if err != nil {
+		return []vault.Diagnosis{{
+			Severity: vault.SeverityProblem,
+			Message:  fmt.Sprintf("Cannot read the audit log: %v", err),
+			Fix:      "Check that you own " + v.AuditLogPath(),
+		}}
+	}
+	if !report.OK() {
+		return []vault.Diagnosis{{
+			Severity: vault.SeverityProblem,
+			Message:  fmt.Sprintf("Audit log failed verification with %d problem(s)", len(report.Problems)),
+			Fix:      "Run 'lean_vault audit verify' to see where the log was modified, and keep a copy before investigating",
+		}}
+	}
+	return []vault.Diagnosis{{
+		Severity: vault.SeverityOK,
+		Message:  fmt.Sprintf("Audit log verified (%d entries)", report.Entries),
+	}}
+}
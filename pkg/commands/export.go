@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// Export formats
+const (
+	ExportFormatEnv  = "env"
+	ExportFormatJSON = "json"
+)
+
+// Export prints the secrets matching the filter, as shell export statements
+// using their environment variable names or as a JSON object keyed by name
+func Export(filter vault.Filter, format string) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	entries, err := v.ListSecretEntries()
+	if err != nil {
+		return fmt.Errorf("failed to list secrets: %w", err)
+	}
+	entries = vault.FilterEntries(entries, filter)
+
+	values, err := v.GetAllSecrets()
+	if err != nil {
+		return fmt.Errorf("failed to read secrets: %w", err)
+	}
+
+	names := sortedNames(entries)
+	recordAudit(v, auditExport, "", map[string]string{
+		"count":  fmt.Sprint(len(names)),
+		"format": format,
+		"names":  strings.Join(names, ","),
+	})
+
+	switch format {
+	case ExportFormatJSON:
+		selected := make(map[string]string, len(names))
+		for _, name := range names {
+			selected[name] = values[name]
+		}
+		data, err := json.MarshalIndent(selected, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode secrets: %w", err)
+		}
+		fmt.Println(string(data))
+	default:
+		// Different names can map to the same variable, e.g. "a-b" and "a_b"
+		seen := make(map[string]string)
+		for _, name := range names {
+			envName := vault.EnvVarName(name)
+			if other, ok := seen[envName]; ok {
+				return fmt.Errorf("secrets %s and %s both export as %s", other, name, envName)
+			}
+			seen[envName] = name
+		}
+		for _, name := range names {
+			fmt.Printf("export %s=%s\n", vault.EnvVarName(name), shellQuote(values[name]))
+		}
+	}
+
+	if len(names) == 0 {
+		fmt.Fprintln(os.Stderr, "No keys match.")
+	}
+	return nil
+}
+
+// shellQuote quotes a value for POSIX shells
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,248 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spacebarlabs/lean_vault/pkg/api"
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// gcTargets are the keys gc revokes at a point in time
+type gcTargets struct {
+	// expired are entries to revoke and remove from the vault
+	expired map[string]vault.SecretEntry
+	// retired are entries whose previous version's grace period has ended
+	retired map[string]vault.SecretEntry
+	// trash are removed secrets kept longer than the trash retention
+	trash []vault.TrashedSecret
+}
+
+// empty reports whether there is nothing to revoke or purge
+func (t gcTargets) empty() bool {
+	return len(t.expired) == 0 && len(t.retired) == 0 && len(t.trash) == 0
+}
+
+// gcResult is the outcome of revoking or purging one target
+type gcResult struct {
+	description string
+	// purge is set for trashed secrets, which are purged rather than revoked
+	purge bool
+	err   error
+}
+
+// GC revokes expired leases, keys past a policy with on_expiry: revoke, and
+// previous keys whose grace period has ended, and purges secrets that have
+// been in the trash longer than its retention
+func GC(dryRun bool) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	targets, err := findGCTargets(v, time.Now())
+	if err != nil {
+		return err
+	}
+	if targets.empty() {
+		fmt.Fprintln(os.Stderr, "Nothing to revoke or purge.")
+		return nil
+	}
+
+	if dryRun {
+		for _, name := range sortedNames(targets.expired) {
+			fmt.Fprintf(os.Stderr, "Would revoke '%s' (%s)\n", name, expiryReason(targets.expired[name]))
+		}
+		for _, name := range sortedNames(targets.retired) {
+			fmt.Fprintf(os.Stderr, "Would revoke the previous key of '%s' (grace period ended %s)\n", name,
+				targets.retired[name].Previous.RevokeAt.Local().Format(time.RFC3339))
+		}
+		for _, trashed := range targets.trash {
+			fmt.Fprintf(os.Stderr, "Would purge '%s' from the trash (removed %s)\n", trashed.Name,
+				trashed.DeletedAt.Local().Format(time.RFC3339))
+		}
+		return nil
+	}
+
+	results := collectGarbage(v, targets)
+	printGCResults(results)
+
+	failures := 0
+	for _, result := range results {
+		if result.err != nil {
+			failures++
+		}
+	}
+	if failures > 0 {
+		fmt.Fprintln(os.Stderr, "Keys that could not be revoked are kept so gc can retry them.")
+		return fmt.Errorf("failed to revoke %d key(s)", failures)
+	}
+	return nil
+}
+
+// findGCTargets returns what gc should revoke or purge: expired leases, keys
+// past their policy's max_age whose on_expiry action is revoke, previous
+// versions whose grace period has ended and secrets past the trash retention
+func findGCTargets(v *vault.Vault, now time.Time) (gcTargets, error) {
+	entries, err := v.ListSecretEntries()
+	if err != nil {
+		return gcTargets{}, fmt.Errorf("failed to list secrets: %w", err)
+	}
+	settings, err := v.LoadSettings()
+	if err != nil {
+		return gcTargets{}, err
+	}
+	trash, err := v.ExpiredTrash(settings.TrashRetention(), now)
+	if err != nil {
+		return gcTargets{}, fmt.Errorf("failed to list trash: %w", err)
+	}
+	pending, err := v.PendingRevocations(now)
+	if err != nil {
+		return gcTargets{}, fmt.Errorf("failed to list pending revocations: %w", err)
+	}
+
+	targets := gcTargets{
+		expired: make(map[string]vault.SecretEntry),
+		retired: make(map[string]vault.SecretEntry),
+		trash:   trash,
+	}
+	for name, entry := range entries {
+		if entry.Expired(now) {
+			targets.expired[name] = entry
+			continue
+		}
+		// Only provider keys can be revoked; a static secret is never
+		// dropped for being old
+		if entry.IsProvisioned() && entry.Policy != nil && entry.Policy.Action() == vault.OnExpiryRevoke {
+			if state, err := entry.CheckPolicy(now); err == nil && state.Status == vault.PolicyExpired {
+				targets.expired[name] = entry
+			}
+		}
+	}
+	// Revoking an expired key also revokes its previous version
+	for name := range pending {
+		entry, exists := entries[name]
+		if _, expired := targets.expired[name]; exists && entry.Previous != nil && !expired {
+			targets.retired[name] = entry
+		}
+	}
+	return targets, nil
+}
+
+// expiryReason describes why findGCTargets selected an entry
+func expiryReason(entry vault.SecretEntry) string {
+	if entry.IsLease() {
+		return "lease expired " + entry.ExpiresAt.Local().Format(time.RFC3339)
+	}
+	return "older than its max_age of " + entry.Policy.MaxAge
+}
+
+// collectGarbage revokes the targets, each with its account's provisioning
+// key. Expired entries are moved to the trash and retired versions are
+// dropped; anything whose revocation fails is kept so that a later run can
+// retry it.
+func collectGarbage(v *vault.Vault, targets gcTargets) []gcResult {
+	var results []gcResult
+	clients := newAccountClients(v)
+
+	for _, name := range sortedNames(targets.retired) {
+		entry := targets.retired[name]
+		client, err := clients.forEntry(entry)
+		if err == nil {
+			err = revokePrevious(v, client, name, *entry.Previous)
+		}
+		if err == nil {
+			recordAudit(v, auditRevoke, name, map[string]string{"reason": "grace period ended", "key_id": entry.Previous.ID})
+		}
+		results = append(results, gcResult{description: fmt.Sprintf("previous key of '%s'", name), err: err})
+	}
+
+	for _, name := range sortedNames(targets.expired) {
+		entry := targets.expired[name]
+		client, err := clients.forEntry(entry)
+		if err == nil {
+			err = revokeAndRemove(v, client, name, entry)
+		}
+		if err == nil {
+			recordAudit(v, auditRevoke, name, map[string]string{"reason": expiryReason(entry), "key_id": entry.ID})
+		}
+		results = append(results, gcResult{description: fmt.Sprintf("expired key '%s'", name), err: err})
+	}
+
+	// Secrets with nothing to revoke are purged without a client
+	for _, trashed := range targets.trash {
+		var client *api.Client
+		var err error
+		if trashed.NeedsRevocation() {
+			client, err = clients.forEntry(trashed.Entry)
+		}
+		if err == nil {
+			err = purgeTrashed(v, client, trashed)
+		}
+		results = append(results, gcResult{description: fmt.Sprintf("'%s' from the trash", trashed.Name), purge: true, err: err})
+	}
+	return results
+}
+
+// revokeAndRemove revokes an entry's key, and any previous key it still
+// holds, then moves the entry to the trash
+func revokeAndRemove(v *vault.Vault, client *api.Client, name string, entry vault.SecretEntry) error {
+	// Otherwise removing the entry would forget a key that is still active
+	if entry.Previous != nil {
+		if err := revokePrevious(v, client, name, *entry.Previous); err != nil {
+			return fmt.Errorf("failed to revoke previous key: %w", err)
+		}
+	}
+
+	if entry.IsProvisioned() {
+		if err := client.RevokeKey(entry.ID); err != nil {
+			return err
+		}
+	}
+	if err := v.TrashSecret(name, true, false); err != nil {
+		return fmt.Errorf("revoked but failed to move to the trash: %w", err)
+	}
+	return nil
+}
+
+// revokePrevious revokes a secret's previous version, if it is backed by a
+// provider key, and drops it from the vault
+func revokePrevious(v *vault.Vault, client *api.Client, name string, previous vault.SecretVersion) error {
+	if previous.ID != "" {
+		if err := client.RevokeKey(previous.ID); err != nil {
+			return err
+		}
+	}
+	if err := v.ClearPrevious(name, previous.ID); err != nil {
+		return fmt.Errorf("revoked but failed to update vault: %w", err)
+	}
+	return nil
+}
+
+// printGCResults reports the outcome of collectGarbage
+func printGCResults(results []gcResult) {
+	for _, result := range results {
+		switch {
+		case result.err != nil && result.purge:
+			fmt.Fprintf(os.Stderr, "⚠️  Warning: Failed to purge %s: %v\n", result.description, result.err)
+		case result.err != nil:
+			fmt.Fprintf(os.Stderr, "⚠️  Warning: Failed to revoke %s: %v\n", result.description, result.err)
+		case result.purge:
+			fmt.Fprintf(os.Stderr, "✓ Purged %s\n", result.description)
+		default:
+			fmt.Fprintf(os.Stderr, "✓ Revoked %s\n", result.description)
+		}
+	}
+}
+
+// sortedNames returns the keys of a map in sorted order
+func sortedNames[T any](m map[string]T) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
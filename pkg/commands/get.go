@@ -5,21 +5,51 @@ import (
 	"os"
 	"strings"
 
+	"github.com/spacebarlabs/lean_vault/pkg/agent"
 	"github.com/spacebarlabs/lean_vault/pkg/vault"
 )
 
-// Get retrieves a secret from the vault
-func Get(keyName string) error {
-	v := vault.New()
+// Get retrieves a secret from the vault. With previous set it retrieves the
+// value replaced by a rotation that is still in its grace period.
+func Get(keyName string, previous bool) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
 
-	// Get the secret value
-	value, err := v.GetSecret(keyName)
+	// Get the secret value, from the agent if one is running
+	var value string
+	if previous {
+		value, err = v.GetPreviousSecret(keyName)
+	} else {
+		value, err = getSecret(v, keyName)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to get secret: %w", err)
 	}
 
+	var details map[string]string
+	if previous {
+		details = map[string]string{"version": "previous"}
+	}
+	recordAudit(v, auditGet, keyName, details)
+
 	// Clean the value and print only the value to stdout (no newline)
 	value = strings.TrimSpace(value)
 	fmt.Fprint(os.Stdout, value)
 	return nil
 }
+
+// getSecret asks a running agent for a secret, falling back to reading the
+// vault directly if there is no agent or it can't answer
+func getSecret(v *vault.Vault, keyName string) (string, error) {
+	if client := agent.DialIfRunning(v.AgentSocketPath()); client != nil {
+		value, err := client.Get(keyName)
+		client.Close()
+		if err == nil {
+			return value, nil
+		}
+	}
+
+	return v.GetSecret(keyName)
+}
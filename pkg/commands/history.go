@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// History lists the current and earlier versions of a secret, newest first
+func History(keyName string) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	entry, err := v.GetSecretEntry(keyName)
+	if err != nil {
+		return fmt.Errorf("failed to get secret: %w", err)
+	}
+
+	recordAudit(v, auditHistory, keyName, nil)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tSTORED\tREPLACED\tREASON\tKEY ID\tSTATUS")
+	fmt.Fprintf(w, "%d\t%s\t-\t-\t%s\tcurrent\n", entry.CurrentVersion(), formatTime(entry.UpdatedAt), formatKeyID(entry.ID))
+	for i := len(entry.History) - 1; i >= 0; i-- {
+		past := entry.History[i]
+		status := "-"
+		if entry.Previous != nil && entry.Previous.Version == past.Version {
+			status = "grace period until " + formatTime(*entry.Previous.RevokeAt)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", past.Version, formatTime(past.CreatedAt), formatTime(past.RetiredAt),
+			past.Reason, formatKeyID(past.ID), status)
+	}
+	w.Flush()
+
+	if len(entry.History) == 0 {
+		fmt.Println("\nNo earlier versions are recorded.")
+	}
+	return nil
+}
+
+// Rollback makes an earlier version of a secret current again. Provider keys
+// are checked first: rolling back to a key that has been revoked upstream
+// is refused unless force is set.
+func Rollback(keyName string, version int, force bool) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	entry, err := v.GetSecretEntry(keyName)
+	if err != nil {
+		return fmt.Errorf("failed to get secret: %w", err)
+	}
+
+	var target *vault.SecretVersion
+	for i := range entry.History {
+		if entry.History[i].Version == version {
+			target = &entry.History[i]
+			break
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("version %d of '%s' is not in its history; see 'lean_vault history %s'", version, keyName, keyName)
+	}
+
+	if target.ID != "" && entry.SecretType() != vault.SecretTypeStatic {
+		client, err := newAccountClient(v, entry.AccountName())
+		if err != nil {
+			return err
+		}
+
+		info, err := client.GetKey(target.ID)
+		switch {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "⚠️  Warning: Version %d's key could not be found on OpenRouter and has probably been revoked: %v\n", version, err)
+		case info.Data.Disabled:
+			fmt.Fprintf(os.Stderr, "⚠️  Warning: Version %d's key is disabled on OpenRouter.\n", version)
+		}
+		if (err != nil || info.Data.Disabled) && !force {
+			fmt.Fprintln(os.Stderr, "Rolling back to it would leave the secret unusable. Use --force to roll back anyway.")
+			return fmt.Errorf("rollback target has been revoked")
+		}
+	}
+
+	if _, err := v.RollbackSecret(keyName, version); err != nil {
+		return fmt.Errorf("failed to roll back: %w", err)
+	}
+
+	details := map[string]string{"to_version": fmt.Sprint(version)}
+	if target.ID != "" {
+		details["key_id"] = target.ID
+	}
+	recordAudit(v, auditRollback, keyName, details)
+
+	fmt.Fprintf(os.Stderr, "✓ Secret '%s' rolled back to version %d\n", keyName, version)
+	if entry.IsProvisioned() && entry.ID != target.ID {
+		fmt.Fprintf(os.Stderr, "The replaced key (%s) has not been revoked and is kept in the history.\n", entry.ID)
+	}
+	return nil
+}
+
+// formatKeyID shows a provider key ID, or "-" for values without one
+func formatKeyID(id string) string {
+	if id == "" {
+		return "-"
+	}
+	return id
+}
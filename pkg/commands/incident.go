@@ -0,0 +1,159 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spacebarlabs/lean_vault/pkg/audit"
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// DefaultIncidentReason is recorded when no reason is given
+const DefaultIncidentReason = "suspected leak"
+
+// statusOldKeyUnknown is the incident status when the leaked key was stored
+// without a provider ID, so rotation couldn't revoke it
+const statusOldKeyUnknown = "rotated; old key had no ID and was not revoked"
+
+// Incident responds to a leaked key: it rotates the key, revokes the old one
+// immediately, records the incident in the audit log and prints a summary
+func Incident(keyName, reason string) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+	startedAt := time.Now().UTC()
+
+	if reason == "" {
+		reason = DefaultIncidentReason
+	}
+
+	entry, err := v.GetSecretEntry(keyName)
+	if err != nil {
+		return fmt.Errorf("failed to get current key ID: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "🚨 Responding to incident for '%s'...\n", keyName)
+
+	details := map[string]string{
+		"reason": reason,
+		"type":   entry.SecretType(),
+	}
+	if entry.ID != "" {
+		details["old_key_id"] = entry.ID
+	}
+
+	var result *rotation
+	var rotateErr error
+	if entry.SecretType() == vault.SecretTypeStatic {
+		rotateErr = rotateStatic(v, keyName, vault.ReasonIncident)
+	} else {
+		client, err := newAccountClient(v, entry.AccountName())
+		if err != nil {
+			return err
+		}
+		result, rotateErr = rotateProvisioned(v, client, keyName, entry.ID, vault.ReasonIncident)
+	}
+
+	switch {
+	case rotateErr != nil:
+		details["status"] = "rotation failed"
+		details["error"] = rotateErr.Error()
+	case result == nil:
+		details["status"] = "value replaced"
+	case result.RevokeErr != nil:
+		details["status"] = "old key still active"
+		details["new_key_id"] = result.NewKeyID
+		details["error"] = result.RevokeErr.Error()
+	case !result.Revoked():
+		details["status"] = statusOldKeyUnknown
+		details["new_key_id"] = result.NewKeyID
+	default:
+		details["status"] = "rotated and revoked"
+		details["new_key_id"] = result.NewKeyID
+	}
+
+	// A value kept for a grace period may be the one that leaked
+	if previous := entry.Previous; previous != nil && rotateErr == nil {
+		if previous.ID != "" {
+			details["previous_key_id"] = previous.ID
+		}
+		if err := revokePreviousNow(v, keyName, entry.AccountName(), *previous); err != nil {
+			details["previous_status"] = "still active"
+			details["previous_error"] = err.Error()
+		} else {
+			details["previous_status"] = "revoked"
+		}
+	}
+
+	// Record the incident even if the response failed, so there is a trail
+	auditErr := openAuditLog(v).Append(audit.Entry{
+		Time:    startedAt,
+		Action:  auditIncident,
+		Name:    keyName,
+		Details: details,
+	})
+	if auditErr != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Warning: Failed to record incident in audit log: %v\n", auditErr)
+	}
+
+	printIncidentSummary(keyName, startedAt, details)
+
+	if rotateErr != nil {
+		return rotateErr
+	}
+	if result != nil && result.RevokeErr != nil {
+		return fmt.Errorf("old key could not be revoked and may still be active")
+	}
+	if details["status"] == statusOldKeyUnknown {
+		return fmt.Errorf("old key had no ID, so it was not revoked and may still be active")
+	}
+	if details["previous_status"] == "still active" {
+		return fmt.Errorf("previous key could not be revoked and may still be active")
+	}
+	return nil
+}
+
+// revokePreviousNow revokes a previous version without waiting for its grace period
+func revokePreviousNow(v *vault.Vault, keyName, account string, previous vault.SecretVersion) error {
+	client, err := newAccountClient(v, account)
+	if err != nil {
+		return err
+	}
+	return revokePrevious(v, client, keyName, previous)
+}
+
+// printIncidentSummary writes a summary suitable for pasting into an incident channel
+func printIncidentSummary(keyName string, startedAt time.Time, details map[string]string) {
+	fmt.Println()
+	fmt.Printf("🚨 Secret incident: %s\n", keyName)
+	fmt.Printf("Time:        %s\n", startedAt.Format(time.RFC3339))
+	fmt.Printf("Reason:      %s\n", details["reason"])
+	fmt.Printf("Type:        %s\n", details["type"])
+	if details["old_key_id"] != "" {
+		fmt.Printf("Old key ID:  %s\n", details["old_key_id"])
+	}
+	if details["new_key_id"] != "" {
+		fmt.Printf("New key ID:  %s\n", details["new_key_id"])
+	}
+	fmt.Printf("Status:      %s\n", details["status"])
+	if details["error"] != "" {
+		fmt.Printf("Error:       %s\n", details["error"])
+	}
+	if details["previous_status"] != "" {
+		fmt.Printf("Previous:    %s\n", details["previous_status"])
+		if details["previous_error"] != "" {
+			fmt.Printf("             %s\n", details["previous_error"])
+		}
+	}
+
+	switch details["status"] {
+	case "old key still active":
+		fmt.Println("Action:      Revoke the old key in the OpenRouter dashboard now")
+	case statusOldKeyUnknown:
+		fmt.Println("Action:      Find the old key in the OpenRouter dashboard and revoke it now")
+	case "value replaced":
+		fmt.Println("Action:      Invalidate the old value with its issuer")
+	}
+}
@@ -2,7 +2,9 @@ package commands
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"syscall"
 
@@ -10,25 +12,134 @@ import (
 	"golang.org/x/term"
 )
 
+// InitOptions configures init. With none of the provisioning key options
+// set, the key is prompted for on the terminal.
+type InitOptions struct {
+	// ProvisioningKeyStdin reads the provisioning key from stdin
+	ProvisioningKeyStdin bool
+	// ProvisioningKeyEnv names an environment variable holding the provisioning key
+	ProvisioningKeyEnv string
+	// NoProvisioningKey creates a vault for static secrets only
+	NoProvisioningKey bool
+	// KeyFile is a pre-generated master key to use instead of a new one
+	KeyFile string
+}
+
 // Init handles the initialization of the vault
-func Init() error {
-	// Create a new vault instance
-	v := vault.New()
+func Init(opts InitOptions) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+	return initVault(v, opts)
+}
 
-	// Check if vault already exists before showing any prompts
-	if _, err := os.Stat(v.VaultDir()); err == nil {
+// initVault gets the provisioning key, from a prompt unless opts say
+// otherwise, and creates the vault
+func initVault(v *vault.Vault, opts InitOptions) error {
+	// Check if vault already exists before showing any prompts. The
+	// directory alone may exist because it holds other profiles.
+	if _, err := v.Stat(); err == nil {
 		fmt.Fprintln(os.Stderr, "\n⚠️  Vault already exists!")
-		fmt.Fprintln(os.Stderr, "Location:", v.VaultDir())
-		fmt.Fprintln(os.Stderr, "\nTo start fresh:")
-		fmt.Fprintf(os.Stderr, "1. Remove the existing vault: rm -rf %s\n", v.VaultDir())
-		fmt.Fprintln(os.Stderr, "2. Run 'lean_vault init' again")
-		fmt.Fprintln(os.Stderr, "\n⚠️  Warning: Removing the vault will delete all stored API keys!")
+		printExistingVault(v)
 		return fmt.Errorf("vault already initialized")
 	}
 
+	var masterKey []byte
+	if opts.KeyFile != "" {
+		key, err := os.ReadFile(opts.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read master key file: %w", err)
+		}
+		masterKey = key
+	}
+
+	keyStr, source, err := initProvisioningKey(v, opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "\nInitializing vault...")
+
+	// Initialize the vault
+	if err := v.InitWith(vault.InitOptions{ProvisioningKey: keyStr, MasterKey: masterKey}); err != nil {
+		// This should rarely happen since we checked earlier, but handle it just in case
+		if strings.Contains(err.Error(), "vault file already exists") {
+			fmt.Fprintln(os.Stderr, "\n⚠️  Another process may have initialized the vault!")
+			printExistingVault(v)
+			return fmt.Errorf("vault already initialized")
+		}
+		return fmt.Errorf("failed to initialize vault: %w", err)
+	}
+
+	details := map[string]string{"provisioning_key": source}
+	if opts.KeyFile != "" {
+		details["master_key"] = "key file"
+	}
+	recordAudit(v, auditInit, "", details)
+
+	if v.ProjectFile() != "" {
+		return finishProjectInit(v)
+	}
+
+	fmt.Fprintln(os.Stderr, "\n✓ Vault initialized successfully!")
+	fmt.Fprintln(os.Stderr, "✓ Your vault is located at:", v.VaultDir())
+	if keyStr == "" {
+		fmt.Fprintln(os.Stderr, "\nYou can now use 'lean_vault set <name>' to store static secrets.")
+		fmt.Fprintln(os.Stderr, "To provision API keys later, run 'lean_vault provisioning-key set'.")
+		return nil
+	}
+	fmt.Fprintln(os.Stderr, "\nYou can now use 'lean_vault add <key-name>' to create new API keys.")
+	return nil
+}
+
+// initProvisioningKey returns the provisioning key for a new vault and
+// where it came from. It is empty when opts ask for no provisioning key.
+func initProvisioningKey(v *vault.Vault, opts InitOptions) (string, string, error) {
+	var key, source string
+	switch {
+	case opts.NoProvisioningKey:
+		return "", "none", nil
+	case opts.ProvisioningKeyStdin:
+		value, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read provisioning key from stdin: %w", err)
+		}
+		key, source = string(value), "stdin"
+	case opts.ProvisioningKeyEnv != "":
+		key, source = os.Getenv(opts.ProvisioningKeyEnv), "env"
+		if key == "" {
+			return "", "", fmt.Errorf("environment variable %s is not set or empty", opts.ProvisioningKeyEnv)
+		}
+	default:
+		value, err := promptProvisioningKey(v)
+		if err != nil {
+			return "", "", err
+		}
+		key, source = value, "prompt"
+	}
+
+	// Trim any whitespace from the key
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", "", fmt.Errorf("provisioning key cannot be empty")
+	}
+	return key, source, nil
+}
+
+// promptProvisioningKey explains init and reads the provisioning key from
+// the terminal
+func promptProvisioningKey(v *vault.Vault) (string, error) {
+	if !term.IsTerminal(int(syscall.Stdin)) {
+		return "", fmt.Errorf("no terminal to prompt for the provisioning key; use --provisioning-key-stdin, --provisioning-key-env <VAR> or --no-provisioning-key")
+	}
+
 	// Print instructions
 	fmt.Fprintln(os.Stderr, "Initialize your Lean Vault")
 	fmt.Fprintln(os.Stderr, "------------------------")
+	if v.ProfileName() != vault.DefaultProfile {
+		fmt.Fprintln(os.Stderr, "Profile:", v.ProfileName())
+	}
 	fmt.Fprintln(os.Stderr, "This will create a secure vault in:", v.VaultDir())
 	fmt.Fprintln(os.Stderr, "Please enter your OpenRouter provisioning key.")
 	fmt.Fprintln(os.Stderr, "This is the master key used to provision new API keys.")
@@ -39,35 +150,38 @@ func Init() error {
 	fmt.Print("OpenRouter Provisioning Key (input hidden): ")
 	key, err := term.ReadPassword(int(syscall.Stdin))
 	if err != nil {
-		return fmt.Errorf("failed to read key: %w", err)
+		return "", fmt.Errorf("failed to read key: %w", err)
 	}
 	fmt.Println() // Add newline after password input
+	return string(key), nil
+}
 
-	// Trim any whitespace from the key
-	keyStr := strings.TrimSpace(string(key))
-	if keyStr == "" {
-		return fmt.Errorf("provisioning key cannot be empty")
-	}
+// printExistingVault explains how to change an existing vault without
+// starting over
+func printExistingVault(v *vault.Vault) {
+	fmt.Fprintln(os.Stderr, "Location:", v.VaultDir())
+	fmt.Fprintln(os.Stderr, "\nTo replace the provisioning key and keep your API keys:")
+	fmt.Fprintln(os.Stderr, "  lean_vault provisioning-key set      # a wrong or missing key")
+	fmt.Fprintln(os.Stderr, "  lean_vault provisioning-key rotate   # an exposed key")
+	fmt.Fprintln(os.Stderr, "\nTo keep a separate set of keys, create a profile: lean_vault profile create <name>")
+}
 
-	fmt.Fprintln(os.Stderr, "\nInitializing vault...")
+// projectGitignore keeps everything in a project vault directory except the
+// encrypted vault out of the repository
+const projectGitignore = "# Only the encrypted vault is committed\n*\n!.gitignore\n!" + vault.DefaultVaultFile + "\n"
 
-	// Initialize the vault
-	if err := v.Init(keyStr); err != nil {
-		// This should rarely happen since we checked earlier, but handle it just in case
-		if strings.Contains(err.Error(), "already exists") {
-			fmt.Fprintln(os.Stderr, "\n⚠️  Another process may have initialized the vault!")
-			fmt.Fprintln(os.Stderr, "Location:", v.VaultDir())
-			fmt.Fprintln(os.Stderr, "\nTo start fresh:")
-			fmt.Fprintf(os.Stderr, "1. Remove the existing vault: rm -rf %s\n", v.VaultDir())
-			fmt.Fprintln(os.Stderr, "2. Run 'lean_vault init' again")
-			fmt.Fprintln(os.Stderr, "\n⚠️  Warning: Removing the vault will delete all stored API keys!")
-			return fmt.Errorf("vault already initialized")
+// finishProjectInit ignores the project vault's local files and explains
+// what to commit
+func finishProjectInit(v *vault.Vault) error {
+	gitignore := filepath.Join(v.VaultDir(), ".gitignore")
+	if _, err := os.Stat(gitignore); os.IsNotExist(err) {
+		if err := os.WriteFile(gitignore, []byte(projectGitignore), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", gitignore, err)
 		}
-		return fmt.Errorf("failed to initialize vault: %w", err)
 	}
 
-	fmt.Fprintln(os.Stderr, "\n✓ Vault initialized successfully!")
-	fmt.Fprintln(os.Stderr, "✓ Your vault is located at:", v.VaultDir())
-	fmt.Fprintln(os.Stderr, "\nYou can now use 'lean_vault add <key-name>' to create new API keys.")
+	fmt.Fprintln(os.Stderr, "\n✓ Project vault initialized for", v.ProjectFile())
+	fmt.Fprintln(os.Stderr, "✓ Commit:", v.VaultFilePath())
+	fmt.Fprintln(os.Stderr, "⚠️  Never commit the master key; share it out of band:", v.KeyFilePath())
 	return nil
 }
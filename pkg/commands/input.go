@@ -0,0 +1,33 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// readSecretInput reads a secret value from a hidden prompt when stdin is a
+// terminal, or from the whole of stdin when it is piped
+func readSecretInput(prompt string) (string, error) {
+	if term.IsTerminal(int(syscall.Stdin)) {
+		fmt.Fprint(os.Stderr, prompt)
+		value, err := term.ReadPassword(int(syscall.Stdin))
+		fmt.Fprintln(os.Stderr) // Add newline after password input
+		if err != nil {
+			return "", fmt.Errorf("failed to read value: %w", err)
+		}
+		return string(value), nil
+	}
+
+	value, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to read value from stdin: %w", err)
+	}
+
+	// Drop the trailing newline added by echo and most editors
+	return strings.TrimRight(string(value), "\r\n"), nil
+}
@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spacebarlabs/lean_vault/pkg/api"
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// Lease provisions a short-lived API key that is revoked once ttl has passed.
+// A limit above zero caps what the key can spend, in dollars. The key is
+// provisioned through account.
+func Lease(keyName string, ttl time.Duration, limit float64, account string) error {
+	if ttl <= 0 {
+		return fmt.Errorf("lease duration must be positive")
+	}
+	if limit < 0 {
+		return fmt.Errorf("spend limit cannot be negative")
+	}
+
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	// Check the name before provisioning a key that couldn't be stored
+	if _, err := v.GetSecretEntry(keyName); err == nil {
+		return fmt.Errorf("secret %s already exists", keyName)
+	}
+	if err := vault.ValidateName(keyName); err != nil {
+		return err
+	}
+
+	if err := checkAccount(v, account); err != nil {
+		return err
+	}
+
+	// Create OpenRouter API client
+	client, err := newAccountClient(v, account)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Provisioning leased API key '%s'...\n", keyName)
+
+	// Create new key via OpenRouter API, capped if a limit was given
+	expiresAt := time.Now().Add(ttl)
+	var resp *api.KeyResponse
+	if limit > 0 {
+		resp, err = client.CreateKeyWithLimit(keyName, limit)
+	} else {
+		resp, err = client.CreateKey(keyName)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	// Store the new key in the vault with its expiry
+	if err := v.AddLease(keyName, resp.Key, resp.Data.Hash, expiresAt, limit, account); err != nil {
+		// Don't leave an untracked key behind
+		if revokeErr := client.RevokeKey(resp.Data.Hash); revokeErr != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Warning: Failed to revoke the unstored key: %v\n", revokeErr)
+		}
+		return fmt.Errorf("failed to store API key: %w", err)
+	}
+
+	details := map[string]string{
+		"key_id":     resp.Data.Hash,
+		"expires_at": expiresAt.UTC().Format(time.RFC3339),
+	}
+	if limit > 0 {
+		details["limit"] = fmt.Sprintf("%.2f", limit)
+	}
+	recordAudit(v, auditLease, keyName, details)
+
+	fmt.Fprintf(os.Stderr, "✓ API key '%s' leased until %s\n", keyName, expiresAt.Local().Format(time.RFC3339))
+	if limit > 0 {
+		fmt.Fprintf(os.Stderr, "  Spend limit: $%.2f\n", limit)
+	}
+	fmt.Fprintln(os.Stderr, "It will be revoked by a running agent or 'lean_vault gc' once it expires.")
+	return nil
+}
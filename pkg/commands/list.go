@@ -2,22 +2,54 @@ package commands
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/spacebarlabs/lean_vault/pkg/agent"
 	"github.com/spacebarlabs/lean_vault/pkg/vault"
 )
 
-// List displays all stored keys
-func List() error {
-	v := vault.New()
-	secrets, err := v.ListSecrets()
+// List sort orders
+const (
+	SortName  = "name"
+	SortAge   = "age"
+	SortOwner = "owner"
+)
+
+// ListOptions selects and orders the keys List shows
+type ListOptions struct {
+	Filter vault.Filter
+	// Sort is one of the Sort constants; empty sorts by name
+	Sort string
+	// Recursive lists every key under Filter.Prefix instead of collapsing
+	// deeper namespaces into one line each
+	Recursive bool
+}
+
+// List displays the stored keys matching the filter
+func List(opts ListOptions) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+	listing, err := listSecrets(v)
 	if err != nil {
 		return fmt.Errorf("failed to list secrets: %w", err)
 	}
+	secrets := filterListItems(listing.Secrets, opts.Filter)
+	sortListItems(secrets, opts.Sort)
+	var namespaces []namespaceCount
+	if opts.Filter.Prefix != "" && !opts.Recursive {
+		secrets, namespaces = collapseNamespaces(secrets, opts.Filter.Prefix)
+	}
+	hasProvisioningKey := listing.HasProvisioningKey
+	recordAudit(v, auditList, "", nil)
 
-	// Check if provisioning key exists
-	_, err = v.GetMainProvisioningKey()
-	hasProvisioningKey := err == nil
-
+	if !opts.Filter.IsEmpty() && len(secrets) == 0 && len(namespaces) == 0 {
+		fmt.Println("No keys match.")
+		return nil
+	}
 	if len(secrets) == 0 && !hasProvisioningKey {
 		fmt.Println("No API keys found.")
 		fmt.Println("Use 'lean_vault add <key-name>' to add a new key.")
@@ -32,11 +64,142 @@ func List() error {
 		fmt.Println("  ✗ OpenRouter Provisioning Key (not configured)")
 	}
 
-	if len(secrets) > 0 {
+	if opts.Filter.Prefix != "" {
+		fmt.Printf("\nStored API keys in %s:\n", opts.Filter.Prefix)
+		for _, ns := range namespaces {
+			fmt.Printf("  - %s (%d keys)\n", ns.name, ns.count)
+		}
+	} else if len(secrets) > 0 {
 		fmt.Println("\nStored API keys:")
-		for _, name := range secrets {
-			fmt.Printf("  - %s\n", name)
+	}
+	if len(secrets) > 0 {
+		now := time.Now()
+		for _, secret := range secrets {
+			switch {
+			case secret.Type == vault.SecretTypeStatic:
+				fmt.Printf("  - %s (static)%s\n", secret.Name, formatLabels(secret))
+			case secret.ExpiresAt != nil && !now.Before(*secret.ExpiresAt):
+				fmt.Printf("  - %s (lease expired, pending revocation)%s\n", secret.Name, formatLabels(secret))
+			case secret.ExpiresAt != nil:
+				fmt.Printf("  - %s (lease, expires %s)%s\n", secret.Name, secret.ExpiresAt.Local().Format(time.RFC3339), formatLabels(secret))
+			default:
+				fmt.Printf("  - %s%s\n", secret.Name, formatLabels(secret))
+			}
+			if secret.Description != "" {
+				fmt.Printf("      %s\n", secret.Description)
+			}
 		}
 	}
 	return nil
 }
+
+// listSecrets asks a running agent for the stored secrets, falling back to
+// reading the vault directly
+func listSecrets(v *vault.Vault) (*agent.ListResult, error) {
+	if client := agent.DialIfRunning(v.AgentSocketPath()); client != nil {
+		listing, err := client.List()
+		client.Close()
+		if err == nil {
+			return listing, nil
+		}
+	}
+
+	entries, err := v.ListSecretEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if provisioning key exists
+	_, err = v.GetMainProvisioningKey()
+	listing := &agent.ListResult{HasProvisioningKey: err == nil}
+
+	for name, entry := range entries {
+		listing.Secrets = append(listing.Secrets, agent.NewListItem(name, entry))
+	}
+	sort.Slice(listing.Secrets, func(i, j int) bool {
+		return listing.Secrets[i].Name < listing.Secrets[j].Name
+	})
+	return listing, nil
+}
+
+// namespaceCount is a namespace collapsed into one line of a listing
+type namespaceCount struct {
+	name  string
+	count int
+}
+
+// collapseNamespaces keeps the items directly inside prefix and counts the
+// items in each namespace below it, like a directory listing
+func collapseNamespaces(items []agent.ListItem, prefix string) ([]agent.ListItem, []namespaceCount) {
+	var direct []agent.ListItem
+	counts := make(map[string]int)
+	for _, item := range items {
+		rest := strings.TrimPrefix(item.Name, prefix)
+		if i := strings.Index(rest, "/"); i >= 0 {
+			counts[prefix+rest[:i+1]]++
+			continue
+		}
+		direct = append(direct, item)
+	}
+
+	namespaces := make([]namespaceCount, 0, len(counts))
+	for _, name := range sortedNames(counts) {
+		namespaces = append(namespaces, namespaceCount{name: name, count: counts[name]})
+	}
+	return direct, namespaces
+}
+
+// filterListItems returns the items whose metadata matches the filter
+func filterListItems(items []agent.ListItem, filter vault.Filter) []agent.ListItem {
+	if filter.IsEmpty() {
+		return items
+	}
+	var matched []agent.ListItem
+	for _, item := range items {
+		if filter.Matches(item.Name, item.Metadata()) {
+			matched = append(matched, item)
+		}
+	}
+	return matched
+}
+
+// sortListItems orders items by name, by age with the longest unchanged
+// first, or by owner. Ties and entries of unknown age keep name order.
+func sortListItems(items []agent.ListItem, order string) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		switch order {
+		case SortAge:
+			if a.UpdatedAt.IsZero() != b.UpdatedAt.IsZero() {
+				return b.UpdatedAt.IsZero()
+			}
+			if !a.UpdatedAt.Equal(b.UpdatedAt) {
+				return a.UpdatedAt.Before(b.UpdatedAt)
+			}
+		case SortOwner:
+			if a.Owner != b.Owner {
+				// Keys without an owner go last
+				return b.Owner == "" || (a.Owner != "" && a.Owner < b.Owner)
+			}
+		}
+		return a.Name < b.Name
+	})
+}
+
+// formatLabels describes an item's owner and tags for a listing
+func formatLabels(item agent.ListItem) string {
+	var labels []string
+	if item.Account != "" {
+		labels = append(labels, "account: "+item.Account)
+	}
+	if item.Owner != "" {
+		labels = append(labels, "owner: "+item.Owner)
+	}
+	if len(item.Tags) > 0 {
+		labels = append(labels, "tags: "+vault.FormatTags(item.Tags))
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(labels, "; ") + "]"
+}
@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spacebarlabs/lean_vault/pkg/api"
+	"github.com/spacebarlabs/lean_vault/pkg/proxy"
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// Move renames a secret without touching the key behind it. With relabel,
+// a provisioned key is also renamed on OpenRouter.
+func Move(oldName, newName string, relabel bool) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	entry, err := v.GetSecretEntry(oldName)
+	if err != nil {
+		return fmt.Errorf("failed to get secret: %w", err)
+	}
+
+	if err := v.RenameSecret(oldName, newName); err != nil {
+		return fmt.Errorf("failed to rename secret: %w", err)
+	}
+	details := map[string]string{"to": newName}
+	if entry.ID != "" {
+		details["key_id"] = entry.ID
+	}
+	recordAudit(v, auditMove, oldName, details)
+	fmt.Fprintf(os.Stderr, "✓ Renamed '%s' to '%s'\n", oldName, newName)
+
+	if relabel && entry.IsProvisioned() {
+		relabelKey(v, entry, newName)
+	}
+	if entry.Managed {
+		fmt.Fprintf(os.Stderr, "⚠️  '%s' was managed by a manifest; rename it there too, or 'lean_vault apply' will revoke it and create '%s' again.\n", newName, oldName)
+	}
+	warnProxyAliases(v, oldName)
+	return nil
+}
+
+// Copy stores a copy of a secret under a new name. A provisioned key gets a
+// new key of its own on the same account, so the two never share a key that
+// revoking either one would revoke.
+func Copy(srcName, dstName string) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	entry, err := v.GetSecretEntry(srcName)
+	if err != nil {
+		return fmt.Errorf("failed to get secret: %w", err)
+	}
+
+	details := map[string]string{"to": dstName}
+	if entry.IsProvisioned() {
+		keyID, err := copyProvisionedKey(v, srcName, dstName, entry)
+		if err != nil {
+			return err
+		}
+		details["key_id"] = keyID
+	} else if err := v.CopySecret(srcName, dstName); err != nil {
+		return fmt.Errorf("failed to copy secret: %w", err)
+	}
+	recordAudit(v, auditCopy, srcName, details)
+
+	fmt.Fprintf(os.Stderr, "✓ Copied '%s' to '%s'\n", srcName, dstName)
+	if entry.IsProvisioned() {
+		fmt.Fprintf(os.Stderr, "'%s' has its own new %s key; '%s' is unchanged.\n", dstName, entry.SecretType(), srcName)
+	}
+	return nil
+}
+
+// copyProvisionedKey provisions a new key for a copy of a provisioned entry
+// and stores it, returning the new key's ID
+func copyProvisionedKey(v *vault.Vault, srcName, dstName string, entry vault.SecretEntry) (string, error) {
+	if entry.IsLease() {
+		return "", fmt.Errorf("leases can't be copied; use 'lean_vault lease' for a new one")
+	}
+	// Check the destination before provisioning a key that couldn't be stored
+	if err := vault.ValidateName(dstName); err != nil {
+		return "", err
+	}
+	if _, err := v.GetSecretEntry(dstName); err == nil {
+		return "", fmt.Errorf("secret %s already exists", dstName)
+	}
+
+	client, err := newAccountClient(v, entry.AccountName())
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(os.Stderr, "Provisioning a new API key for '%s'...\n", dstName)
+	var resp *api.KeyResponse
+	if entry.Limit > 0 {
+		resp, err = client.CreateKeyWithLimit(dstName, entry.Limit)
+	} else {
+		resp, err = client.CreateKey(dstName)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	if err := v.CopyProvisionedSecret(srcName, dstName, resp.Key, resp.Data.Hash); err != nil {
+		// Don't leave an untracked key behind
+		if revokeErr := client.RevokeKey(resp.Data.Hash); revokeErr != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Warning: Failed to revoke the unstored key: %v\n", revokeErr)
+		}
+		return "", fmt.Errorf("failed to copy secret: %w", err)
+	}
+	return resp.Data.Hash, nil
+}
+
+// relabelKey renames a key on OpenRouter to match the vault. Failure only
+// warns, since the vault has already been updated.
+func relabelKey(v *vault.Vault, entry vault.SecretEntry, name string) {
+	client, err := newAccountClient(v, entry.AccountName())
+	if err == nil {
+		_, err = client.UpdateKey(entry.ID, api.KeyUpdate{Name: &name})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to rename the key on OpenRouter: %v\n", err)
+		fmt.Fprintln(os.Stderr, "The key still works; only its label on OpenRouter is out of date.")
+		return
+	}
+	fmt.Fprintf(os.Stderr, "✓ Renamed the key on OpenRouter to '%s'\n", name)
+}
+
+// warnProxyAliases points out proxy aliases that still use an old name
+func warnProxyAliases(v *vault.Vault, oldName string) {
+	cfg, err := proxy.LoadConfig(v.ProxyConfigPath())
+	if err != nil {
+		return
+	}
+	var aliases []string
+	for alias, name := range cfg.Aliases {
+		if name == oldName {
+			aliases = append(aliases, alias)
+		}
+	}
+	if len(aliases) == 0 {
+		return
+	}
+	sort.Strings(aliases)
+	fmt.Fprintf(os.Stderr, "⚠️  Proxy aliases %v still point to '%s'; update them in %s\n", aliases, oldName, v.ProxyConfigPath())
+}
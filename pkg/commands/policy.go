@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// PolicySet attaches a rotation policy to a key, replacing any existing one
+func PolicySet(keyName string, policy vault.Policy) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	if err := v.SetPolicy(keyName, &policy); err != nil {
+		return fmt.Errorf("failed to set policy: %w", err)
+	}
+
+	recordAudit(v, auditPolicy, keyName, map[string]string{
+		"operation":            "set",
+		"max_age":              policy.MaxAge,
+		"rotate_before_expiry": policy.RotateBeforeExpiry,
+		"on_expiry":            policy.Action(),
+	})
+	fmt.Fprintf(os.Stderr, "✓ Policy for '%s' set: max age %s, on expiry %s\n", keyName, policy.MaxAge, policy.Action())
+	return nil
+}
+
+// PolicyClear removes the rotation policy from a key
+func PolicyClear(keyName string) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	if err := v.SetPolicy(keyName, nil); err != nil {
+		return fmt.Errorf("failed to clear policy: %w", err)
+	}
+
+	recordAudit(v, auditPolicy, keyName, map[string]string{"operation": "clear"})
+	fmt.Fprintf(os.Stderr, "✓ Policy for '%s' removed\n", keyName)
+	return nil
+}
+
+// PolicyCheck lists every key with a policy and returns an error if any of
+// them is due for rotation, past its max age, or of unknown age
+func PolicyCheck() error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	entries, err := v.ListSecretEntries()
+	if err != nil {
+		return fmt.Errorf("failed to list secrets: %w", err)
+	}
+
+	now := time.Now()
+	violations := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY NAME\tMAX AGE\tLAST ROTATED\tEXPIRES\tON EXPIRY\tSTATUS")
+	for _, name := range sortedNames(entries) {
+		entry := entries[name]
+		if entry.Policy == nil {
+			continue
+		}
+
+		state, err := entry.CheckPolicy(now)
+		if err != nil {
+			violations++
+			fmt.Fprintf(w, "%s\t%s\t-\t-\t%s\tInvalid policy: %v\n", name, entry.Policy.MaxAge, entry.Policy.OnExpiry, err)
+			continue
+		}
+		if state.Violated() {
+			violations++
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", name, entry.Policy.MaxAge,
+			formatTime(entry.UpdatedAt), formatTime(state.ExpiresAt), entry.Policy.Action(), policyStatusText(state.Status))
+	}
+	w.Flush()
+
+	recordAudit(v, auditPolicy, "", map[string]string{"operation": "check", "violations": fmt.Sprint(violations)})
+
+	if violations > 0 {
+		return fmt.Errorf("%d key(s) violate their rotation policy", violations)
+	}
+	return nil
+}
+
+// policyStatusText describes a policy status for display
+func policyStatusText(status string) string {
+	switch status {
+	case vault.PolicyDue:
+		return "Due for rotation"
+	case vault.PolicyExpired:
+		return "Expired"
+	case vault.PolicyUnknownAge:
+		return "Unknown age (rotate to start tracking)"
+	default:
+		return "OK"
+	}
+}
+
+// formatTime formats a timestamp for tables, showing "-" when it is unknown
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
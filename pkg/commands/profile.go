@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// ProfileList lists the vault profiles, marking the active one
+func ProfileList() error {
+	current, err := vault.Resolve()
+	if err != nil {
+		return err
+	}
+	active := current.ProfileName()
+	profiles, err := vault.ListProfiles()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\tPROFILE\tSTATUS\tLOCATION")
+	for _, profile := range profiles {
+		marker := ""
+		if profile.Name == active {
+			marker = "*"
+		}
+		status := "initialized"
+		if !profile.Initialized {
+			status = "not initialized"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", marker, profile.Name, status, profile.Dir)
+	}
+	w.Flush()
+
+	printVaultOverride(current)
+	if os.Getenv(vault.ProfileEnvVar) != "" {
+		fmt.Fprintf(os.Stderr, "\nThe active profile is set by %s.\n", vault.ProfileEnvVar)
+	}
+	return nil
+}
+
+// ProfileCreate creates a profile and initializes its vault with its own
+// provisioning key
+func ProfileCreate(name string) error {
+	if err := vault.ValidateProfileName(name); err != nil {
+		return err
+	}
+	if name == vault.DefaultProfile {
+		return fmt.Errorf("the default profile always exists; use 'lean_vault init' to initialize it")
+	}
+
+	v := vault.NewProfile(name)
+	if _, err := os.Stat(v.VaultDir()); err == nil {
+		return fmt.Errorf("profile %s already exists at %s", name, v.VaultDir())
+	}
+	if err := os.MkdirAll(v.VaultDir(), vault.DefaultDirMode); err != nil {
+		return fmt.Errorf("failed to create profile directory: %w", err)
+	}
+
+	if err := initVault(v, InitOptions{}); err != nil {
+		// Don't leave an empty profile behind
+		os.Remove(v.VaultDir())
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "\nSwitch to it with 'lean_vault profile use %s', or use it once with --profile %s.\n", name, name)
+	return nil
+}
+
+// ProfileUse makes a profile the active one
+func ProfileUse(name string) error {
+	if err := vault.UseProfile(name); err != nil {
+		return fmt.Errorf("failed to switch profile: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "✓ Using profile %s\n", name)
+	if env := os.Getenv(vault.ProfileEnvVar); env != "" && env != name {
+		fmt.Fprintf(os.Stderr, "⚠️  %s=%s overrides this in the current shell\n", vault.ProfileEnvVar, env)
+	}
+	if current, err := vault.Resolve(); err == nil {
+		printVaultOverride(current)
+	}
+	return nil
+}
+
+// printVaultOverride explains when the vault in use isn't a profile
+func printVaultOverride(v *vault.Vault) {
+	switch {
+	case v.ProjectFile() != "":
+		fmt.Fprintf(os.Stderr, "\n⚠️  The project vault from %s is used in this directory instead of a profile.\n", v.ProjectFile())
+	case v.ProfileName() == "":
+		fmt.Fprintf(os.Stderr, "\n⚠️  %s=%s is used instead of a profile.\n", vault.VaultDirEnvVar, os.Getenv(vault.VaultDirEnvVar))
+	}
+}
@@ -0,0 +1,165 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spacebarlabs/lean_vault/pkg/api"
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// provisioningKeysURL is where OpenRouter provisioning keys are managed
+const provisioningKeysURL = "https://openrouter.ai/settings/provisioning-keys"
+
+// ProvisioningKeySet replaces an account's provisioning key, for example when
+// it was entered wrongly or the vault was created without one
+func ProvisioningKeySet(account string, noVerify bool) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	if err := checkAccount(v, account); err != nil {
+		return err
+	}
+	if err := replaceProvisioningKey(v, account, vault.ReasonSet, noVerify); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "✓ Provisioning key stored. Your API keys were not changed.")
+	return nil
+}
+
+// ProvisioningKeyRotate replaces an account's exposed provisioning key with a
+// new one from the same OpenRouter account. OpenRouter can't revoke
+// provisioning keys through its API, so the old one has to be deleted in the
+// dashboard.
+func ProvisioningKeyRotate(account string, noVerify bool) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	if _, err := v.GetProvisioningKey(account); err != nil {
+		return fmt.Errorf("no provisioning key is stored; use 'lean_vault provisioning-key set': %w", err)
+	}
+
+	fmt.Fprintln(os.Stderr, "Create a new provisioning key at", provisioningKeysURL)
+	fmt.Fprintln(os.Stderr, "and enter it below. Your API keys stay valid.")
+	fmt.Fprintln(os.Stderr)
+
+	if err := replaceProvisioningKey(v, account, vault.ReasonRotate, noVerify); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "✓ Provisioning key rotated. Your API keys were not changed.")
+	fmt.Fprintln(os.Stderr, "\n⚠️  The old provisioning key keeps working until you delete it at:")
+	fmt.Fprintln(os.Stderr, "  ", provisioningKeysURL)
+	fmt.Fprintln(os.Stderr, "Run 'lean_vault scan --git-history' to find where it leaked.")
+	return nil
+}
+
+// ProvisioningKeyVerify checks that an account's provisioning key works and
+// can manage every key the vault provisioned through that account
+func ProvisioningKeyVerify(account string) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	client, err := newAccountClient(v, account)
+	if err != nil {
+		return err
+	}
+	keys, err := client.ListKeys()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ OpenRouter rejected the provisioning key: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Replace it with 'lean_vault provisioning-key set', or 'rotate' if it was exposed.")
+		return fmt.Errorf("provisioning key verification failed")
+	}
+	fmt.Fprintf(os.Stderr, "✓ Provisioning key works (%d API keys on the account)\n", len(keys))
+
+	hidden, err := unmanageableKeys(v, account, keys)
+	if err != nil {
+		return err
+	}
+	if len(hidden) > 0 {
+		fmt.Fprintf(os.Stderr, "✗ Stored keys not found on the account: %s\n", strings.Join(hidden, ", "))
+		fmt.Fprintln(os.Stderr, "They were revoked elsewhere or belong to another account.")
+		return fmt.Errorf("%d stored key(s) can't be managed", len(hidden))
+	}
+	fmt.Fprintln(os.Stderr, "✓ Every stored API key is on the account")
+	return nil
+}
+
+// replaceProvisioningKey prompts for an account's new provisioning key, checks
+// it against OpenRouter unless noVerify is set, and stores it. A rotation is
+// refused if the new key can't manage every key of the account; set only warns.
+func replaceProvisioningKey(v *vault.Vault, account, reason string, noVerify bool) error {
+	value, err := readSecretInput("New OpenRouter provisioning key (input hidden): ")
+	if err != nil {
+		return err
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return fmt.Errorf("provisioning key cannot be empty")
+	}
+	if current, err := v.GetProvisioningKey(account); err == nil && current == value {
+		return fmt.Errorf("that provisioning key is already stored")
+	}
+
+	if !noVerify {
+		client, err := newAPIClientWithKey(v, value)
+		if err != nil {
+			return err
+		}
+		keys, err := client.ListKeys()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Use --no-verify to store it without checking.")
+			return fmt.Errorf("OpenRouter rejected the new provisioning key: %w", err)
+		}
+		hidden, err := unmanageableKeys(v, account, keys)
+		if err != nil {
+			return err
+		}
+		if len(hidden) > 0 {
+			if reason == vault.ReasonRotate {
+				return fmt.Errorf("the new provisioning key can't manage %s; is it from another account?", strings.Join(hidden, ", "))
+			}
+			fmt.Fprintf(os.Stderr, "⚠️  The new provisioning key can't manage: %s\n", strings.Join(hidden, ", "))
+		}
+	}
+
+	if err := v.SetProvisioningKey(account, value, reason); err != nil {
+		return fmt.Errorf("failed to store provisioning key: %w", err)
+	}
+	recordAudit(v, auditProvisioningKey, "", map[string]string{
+		"account":  account,
+		"action":   reason,
+		"verified": fmt.Sprint(!noVerify),
+	})
+	return nil
+}
+
+// unmanageableKeys returns the names of keys provisioned through account that
+// are missing from keys, the listing for its provisioning key
+func unmanageableKeys(v *vault.Vault, account string, keys []api.KeyDetails) ([]string, error) {
+	entries, err := v.ListSecretEntries()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets: %w", err)
+	}
+
+	listed := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		listed[key.Hash] = true
+	}
+	var hidden []string
+	for name, entry := range entries {
+		if entry.IsProvisioned() && entry.AccountName() == account && !listed[entry.ID] {
+			hidden = append(hidden, name)
+		}
+	}
+	sort.Strings(hidden)
+	return hidden, nil
+}
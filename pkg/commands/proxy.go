@@ -0,0 +1,140 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spacebarlabs/lean_vault/pkg/api"
+	"github.com/spacebarlabs/lean_vault/pkg/proxy"
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// ProxyOptions override the settings in the proxy configuration file
+type ProxyOptions struct {
+	Listen  string
+	BaseURL string
+	// Aliases are added to those in the configuration file
+	Aliases map[string]string
+}
+
+// Proxy runs a local reverse proxy that injects vault keys into requests
+func Proxy(opts ProxyOptions) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := proxy.LoadConfig(v.ProxyConfigPath())
+	if err != nil {
+		return err
+	}
+	if opts.Listen != "" {
+		cfg.Listen = opts.Listen
+	}
+	if cfg.Listen == "" {
+		cfg.Listen = proxy.DefaultListenAddr
+	}
+	if opts.BaseURL != "" {
+		cfg.BaseURL = opts.BaseURL
+	}
+	if cfg.BaseURL == "" {
+		settings, err := v.LoadSettings()
+		if err != nil {
+			return err
+		}
+		cfg.BaseURL = settings.APIBaseURL
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = api.DefaultBaseURL
+	}
+	if cfg.Aliases == nil {
+		cfg.Aliases = make(map[string]string)
+	}
+	for alias, name := range opts.Aliases {
+		cfg.Aliases[alias] = name
+	}
+
+	if len(cfg.Aliases) == 0 {
+		fmt.Fprintln(os.Stderr, "No aliases configured.")
+		fmt.Fprintf(os.Stderr, "Add them to %s or pass --alias <token>=<key-name>.\n", v.ProxyConfigPath())
+		return fmt.Errorf("no aliases configured")
+	}
+
+	// Fail fast on aliases that point at nothing
+	cache := vault.NewCache(v)
+	for alias, name := range cfg.Aliases {
+		if _, err := cache.Get(name); err != nil {
+			return fmt.Errorf("alias %s: %w", alias, err)
+		}
+	}
+
+	handler, err := proxy.New(cfg.BaseURL, cfg.Aliases, cache)
+	if err != nil {
+		return err
+	}
+
+	for alias, budget := range cfg.Budgets {
+		switch budget.Period {
+		case proxy.PeriodTotal, proxy.PeriodDaily, proxy.PeriodMonthly:
+		default:
+			return fmt.Errorf("budget for alias %s has unknown period %q", alias, budget.Period)
+		}
+	}
+	ledger, err := proxy.OpenLedger(v.UsageLedgerPath())
+	if err != nil {
+		return err
+	}
+	handler.SetAccounting(ledger, cfg.Budgets)
+
+	if host, _, err := net.SplitHostPort(cfg.Listen); err == nil {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			fmt.Fprintf(os.Stderr, "⚠️  Warning: %s is not a loopback address; anyone who can reach it can use your keys.\n", cfg.Listen)
+		}
+	}
+
+	server := &http.Server{
+		Addr:              cfg.Listen,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+
+	aliases := make([]string, 0, len(cfg.Aliases))
+	for alias := range cfg.Aliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+
+	recordAudit(v, auditProxy, "", map[string]string{"event": "start", "listen": cfg.Listen, "aliases": strings.Join(aliases, ",")})
+	defer recordAudit(v, auditProxy, "", map[string]string{"event": "stop"})
+
+	fmt.Fprintf(os.Stderr, "✓ Proxy listening on http://%s, forwarding to %s\n", cfg.Listen, cfg.BaseURL)
+	for _, alias := range aliases {
+		fmt.Fprintf(os.Stderr, "  %s → %s\n", alias, cfg.Aliases[alias])
+	}
+	fmt.Fprintln(os.Stderr, "Send requests with 'Authorization: Bearer <alias>'. Press Ctrl-C to stop.")
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("proxy failed: %w", err)
+	}
+
+	fmt.Fprintln(os.Stderr, "✓ Proxy stopped")
+	return nil
+}
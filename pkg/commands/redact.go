@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spacebarlabs/lean_vault/pkg/redact"
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// Redact copies stdin to stdout, replacing every stored secret value with a marker
+func Redact() error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	secrets, err := redactedSecrets(v)
+	if err != nil {
+		return err
+	}
+
+	recordAudit(v, auditRedact, "", nil)
+
+	w := redact.New(secrets).NewWriter(os.Stdout)
+
+	// Copy in small reads so output keeps flowing for live pipes
+	buf := make([]byte, 4096)
+	for {
+		n, readErr := os.Stdin.Read(buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				return fmt.Errorf("failed to write output: %w", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			// Flush what we have so nothing already read is lost
+			w.Close()
+			return fmt.Errorf("failed to read input: %w", readErr)
+		}
+	}
+
+	return w.Close()
+}
+
+// redactedSecrets returns the values redact replaces, by marker name: every
+// stored value, and previous values still in their grace period under
+// "<name> (previous)", since those keys still work
+func redactedSecrets(v *vault.Vault) (map[string]string, error) {
+	secrets, err := v.GetAllSecrets()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load secrets: %w", err)
+	}
+	previous, err := v.GetPreviousSecrets()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load previous values: %w", err)
+	}
+	for name, value := range previous {
+		secrets[name+" (previous)"] = value
+	}
+	return secrets, nil
+}
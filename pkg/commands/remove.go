@@ -3,40 +3,50 @@ package commands
 import (
 	"fmt"
 	"os"
-	"strings"
 
-	"github.com/spacebarlabs/lean_vault/pkg/api"
 	"github.com/spacebarlabs/lean_vault/pkg/vault"
 )
 
 // Remove handles the removal of an API key
 func Remove(keyName string, force bool) error {
-	v := vault.New()
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
 
-	// Get the key ID first to check if it exists
-	keyID, err := v.GetSecretID(keyName)
+	// Get the entry first to check if it exists and what backs it
+	entry, err := v.GetSecretEntry(keyName)
 	if err != nil {
 		return fmt.Errorf("failed to get key ID: %w", err)
 	}
+	keyID := entry.ID
 
-	if !force {
-		// Get the main provisioning key from the vault
-		provisioningKey, err := v.GetMainProvisioningKey()
-		if err != nil {
-			return fmt.Errorf("failed to get provisioning key: %w", err)
-		}
+	// Static secrets have nothing to revoke upstream
+	revoke := !force && entry.IsProvisioned()
 
+	if revoke {
 		// Create OpenRouter API client
-		client := api.NewClient(provisioningKey)
-
-		// Enable debug mode if environment variable is set
-		if debug := strings.ToLower(os.Getenv("LEAN_VAULT_DEBUG")); debug == "1" || debug == "true" {
-			client.SetDebug(true)
-			fmt.Fprintln(os.Stderr, "Debug mode enabled")
+		client, err := newAccountClient(v, entry.AccountName())
+		if err != nil {
+			return err
 		}
 
 		fmt.Fprintf(os.Stderr, "Attempting to revoke API key '%s'...\n", keyName)
 
+		// A previous key still in its grace period would otherwise be forgotten
+		if previous := entry.Previous; previous != nil && previous.ID != "" {
+			if err := client.RevokeKey(previous.ID); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: Failed to revoke previous key on OpenRouter: %v\n", err)
+				fmt.Fprintln(os.Stderr, "If the key is already inactive or you want to remove it anyway, use --force:")
+				fmt.Fprintf(os.Stderr, "  lean_vault remove %s --force\n", keyName)
+				return fmt.Errorf("key revocation failed")
+			}
+			if err := v.ClearPrevious(keyName, previous.ID); err != nil {
+				return fmt.Errorf("failed to update vault: %w", err)
+			}
+			fmt.Fprintf(os.Stderr, "✓ Previous key of '%s' revoked successfully\n", keyName)
+		}
+
 		// Attempt to revoke the key via OpenRouter API
 		err = client.RevokeKey(keyID)
 		if err != nil {
@@ -48,16 +58,27 @@ func Remove(keyName string, force bool) error {
 		fmt.Fprintf(os.Stderr, "✓ API key '%s' revoked successfully\n", keyName)
 	}
 
-	// Remove the key from the vault
-	err = v.RemoveSecret(keyName)
+	// Move the key to the trash, which keeps its ID in case it still needs
+	// revoking. With --force the key is left alone for good.
+	err = v.TrashSecret(keyName, revoke, force && entry.IsProvisioned())
 	if err != nil {
 		return fmt.Errorf("failed to remove key from vault: %w", err)
 	}
 
-	if force {
+	details := map[string]string{"revoked": fmt.Sprint(revoke)}
+	if keyID != "" {
+		details["key_id"] = keyID
+	}
+	recordAudit(v, auditRemove, keyName, details)
+
+	if !entry.IsProvisioned() {
+		fmt.Fprintf(os.Stderr, "✓ Secret '%s' removed from vault\n", keyName)
+	} else if force {
 		fmt.Fprintf(os.Stderr, "✓ API key '%s' removed from vault (revocation skipped)\n", keyName)
+		fmt.Fprintln(os.Stderr, "The key stays active on OpenRouter; purging it from the trash won't revoke it.")
 	} else {
 		fmt.Fprintf(os.Stderr, "✓ API key '%s' removed from vault\n", keyName)
 	}
+	fmt.Fprintf(os.Stderr, "It is in the trash; 'lean_vault trash restore %s' brings it back.\n", keyName)
 	return nil
 }
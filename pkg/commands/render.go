@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// Render executes a text/template file with access to vault secrets. The result
+// is written to stdout, or to outputPath with owner-only permissions.
+func Render(templatePath, outputPath string) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	source, err := os.ReadFile(templatePath)
+	if err != nil {
+		return fmt.Errorf("failed to read template: %w", err)
+	}
+
+	values := make(map[string]string)
+	tmpl, err := template.New(filepath.Base(templatePath)).
+		Option("missingkey=error").
+		Funcs(templateFuncs(v, values)).
+		Parse(string(source))
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	// Render fully before writing so a missing secret never leaves a partial file
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, nil); err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+
+	details := map[string]string{"template": templatePath, "secrets": strings.Join(sortedNames(values), ",")}
+	if outputPath != "" {
+		details["output"] = outputPath
+	}
+	recordAudit(v, auditRender, "", details)
+
+	if outputPath == "" {
+		_, err := os.Stdout.Write(out.Bytes())
+		return err
+	}
+
+	if err := writePrivateFile(outputPath, out.Bytes()); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "✓ Rendered %s to %s\n", templatePath, outputPath)
+	return nil
+}
+
+// writePrivateFile replaces path with data readable only by the owner. The
+// data goes to a temporary file in the same directory that is renamed over
+// path, so it is never written into an existing file with wider permissions.
+func writePrivateFile(path string, data []byte) error {
+	// CreateTemp makes the file with mode 0600
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// templateFuncs returns the helpers available to templates. Secret lookups are
+// cached in values so a value used several times is only decrypted once.
+func templateFuncs(v *vault.Vault, values map[string]string) template.FuncMap {
+	return template.FuncMap{
+		"secret": func(name string) (string, error) {
+			if value, ok := values[name]; ok {
+				return value, nil
+			}
+			value, err := v.GetSecret(name)
+			if err != nil {
+				return "", err
+			}
+			values[name] = value
+			return value, nil
+		},
+		"secretID": func(name string) (string, error) {
+			id, err := v.GetSecretID(name)
+			if err != nil {
+				return "", err
+			}
+			if id == "" {
+				return "", fmt.Errorf("secret %s has no provider ID", name)
+			}
+			return id, nil
+		},
+		"env": os.Getenv,
+		// default is written for pipelines: {{ env "PORT" | default "8080" }}
+		"default": func(fallback, value string) string {
+			if value == "" {
+				return fallback
+			}
+			return value
+		},
+	}
+}
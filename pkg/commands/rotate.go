@@ -3,63 +3,230 @@ package commands
 import (
 	"fmt"
 	"os"
-	"strings"
+	"time"
 
 	"github.com/spacebarlabs/lean_vault/pkg/api"
 	"github.com/spacebarlabs/lean_vault/pkg/vault"
 )
 
-// Rotate handles the rotation of an API key
-func Rotate(keyName string) error {
-	v := vault.New()
+// rotation describes the outcome of replacing a provisioned key
+type rotation struct {
+	OldKeyID string
+	NewKeyID string
+	// RevokeErr is set when the old key could not be revoked and may still be active
+	RevokeErr error
+}
+
+// Revoked reports whether the old key is known to be revoked
+func (r *rotation) Revoked() bool {
+	return r.OldKeyID != "" && r.RevokeErr == nil
+}
+
+// Rotate handles the rotation of an API key. With a grace period the old key
+// stays valid as the previous version and is revoked once the grace period
+// ends, by 'lean_vault gc' or a running agent.
+func Rotate(keyName string, grace time.Duration) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
 
 	// 1. Get the current key's ID and verify it exists
-	oldKeyID, err := v.GetSecretID(keyName)
+	entry, err := v.GetSecretEntry(keyName)
 	if err != nil {
 		return fmt.Errorf("failed to get current key ID: %w", err)
 	}
 
-	// 2. Get the main provisioning key
-	provisioningKey, err := v.GetMainProvisioningKey()
-	if err != nil {
-		return fmt.Errorf("failed to get provisioning key: %w", err)
+	if grace > 0 {
+		return rotateWithGrace(v, keyName, entry, grace)
 	}
 
-	// 3. Create OpenRouter API client
-	client := api.NewClient(provisioningKey)
+	// Static secrets have no provider, so rotating means supplying a new value
+	if entry.SecretType() == vault.SecretTypeStatic {
+		if err := rotateStatic(v, keyName, vault.ReasonRotate); err != nil {
+			return err
+		}
+		recordAudit(v, auditRotate, keyName, nil)
+		return nil
+	}
 
-	// Enable debug mode if set
-	if debug := strings.ToLower(os.Getenv("LEAN_VAULT_DEBUG")); debug == "1" || debug == "true" {
-		client.SetDebug(true)
-		fmt.Fprintln(os.Stderr, "Debug mode enabled")
+	// 2. Create OpenRouter API client
+	client, err := newAccountClient(v, entry.AccountName())
+	if err != nil {
+		return err
 	}
 
 	fmt.Fprintf(os.Stderr, "Rotating API key '%s'...\n", keyName)
 
-	// 4. Create new key
+	// 3. Create, store and revoke
+	result, err := rotateProvisioned(v, client, keyName, entry.ID, vault.ReasonRotate)
+	if err != nil {
+		return err
+	}
+	recordAudit(v, auditRotate, keyName, map[string]string{
+		"old_key_id": result.OldKeyID,
+		"new_key_id": result.NewKeyID,
+		"revoked":    fmt.Sprint(result.Revoked()),
+	})
+
+	if result.OldKeyID == "" {
+		fmt.Fprintf(os.Stderr, "⚠️  Warning: No OpenRouter ID was stored for the old key, so it was not revoked.\n")
+	} else if result.RevokeErr != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Warning: Failed to revoke old key: %v\n", result.RevokeErr)
+		fmt.Fprintf(os.Stderr, "The new key has been stored successfully, but the old key may still be active.\n")
+		fmt.Fprintf(os.Stderr, "You may want to try revoking it manually or contact OpenRouter support.\n")
+		return fmt.Errorf("key rotation partially succeeded but revocation failed")
+	}
+
+	fmt.Fprintf(os.Stderr, "✓ API key '%s' rotated successfully!\n", keyName)
+	return nil
+}
+
+// rotateWithGrace stores a new value and keeps the old one as the previous
+// version until the grace period ends
+func rotateWithGrace(v *vault.Vault, keyName string, entry vault.SecretEntry, grace time.Duration) error {
+	if entry.Previous != nil {
+		return fmt.Errorf("the previous value of '%s' is still in its grace period; rotate again once it has been revoked", keyName)
+	}
+
+	var value, id string
+	if entry.SecretType() == vault.SecretTypeStatic {
+		var err error
+		if value, err = readNewValue(keyName); err != nil {
+			return err
+		}
+	} else {
+		client, err := newAccountClient(v, entry.AccountName())
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "Rotating API key '%s' with a %s grace period...\n", keyName, grace)
+		fmt.Fprintf(os.Stderr, "Creating new key...\n")
+		resp, err := client.CreateKey(keyName)
+		if err != nil {
+			return fmt.Errorf("failed to create new API key: %w", err)
+		}
+		value, id = resp.Key, resp.Data.Hash
+	}
+
+	revokeAt := time.Now().Add(grace)
+	if err := v.RotateSecretWithGrace(keyName, value, id, revokeAt); err != nil {
+		return fmt.Errorf("failed to store new value: %w", err)
+	}
+
+	details := map[string]string{"grace": grace.String(), "revoke_at": revokeAt.UTC().Format(time.RFC3339)}
+	if entry.ID != "" {
+		details["old_key_id"] = entry.ID
+	}
+	if id != "" {
+		details["new_key_id"] = id
+	}
+	recordAudit(v, auditRotate, keyName, details)
+
+	fmt.Fprintf(os.Stderr, "✓ Secret '%s' rotated successfully!\n", keyName)
+	fmt.Fprintf(os.Stderr, "The old value stays valid until %s ('lean_vault get %s --previous').\n", revokeAt.Local().Format(time.RFC3339), keyName)
+	if entry.IsProvisioned() {
+		fmt.Fprintln(os.Stderr, "It will then be revoked by a running agent or 'lean_vault gc'.")
+	}
+	return nil
+}
+
+// RotateDue rotates every key that is due, expired or of unknown age under a
+// policy whose on_expiry action is rotate. Static secrets need a new value
+// from the user, so they are reported as skipped.
+func RotateDue(opts BulkRotateOptions) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	entries, err := v.ListSecretEntries()
+	if err != nil {
+		return fmt.Errorf("failed to list secrets: %w", err)
+	}
+
+	entries = vault.FilterEntries(entries, opts.Filter)
+
+	now := time.Now()
+	var due []string
+	for _, name := range sortedNames(entries) {
+		entry := entries[name]
+		if entry.Policy == nil || entry.Policy.Action() != vault.OnExpiryRotate {
+			continue
+		}
+		state, err := entry.CheckPolicy(now)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Warning: Skipping '%s': invalid policy: %v\n", name, err)
+			continue
+		}
+		if state.Violated() {
+			due = append(due, name)
+		}
+	}
+
+	if len(due) == 0 {
+		fmt.Fprintln(os.Stderr, "No keys are due for rotation.")
+		return nil
+	}
+
+	return rotateMany(v, entries, due, opts)
+}
+
+// rotateProvisioned creates a replacement key, stores it in the vault and
+// revokes the old one. An error is returned only if the new key could not be
+// created or stored; a failed revocation is reported in the result.
+func rotateProvisioned(v *vault.Vault, client *api.Client, keyName, oldKeyID, reason string) (*rotation, error) {
+	result := &rotation{OldKeyID: oldKeyID}
+
+	// Create new key
 	fmt.Fprintf(os.Stderr, "Creating new key...\n")
 	resp, err := client.CreateKey(keyName)
 	if err != nil {
-		return fmt.Errorf("failed to create new API key: %w", err)
+		return nil, fmt.Errorf("failed to create new API key: %w", err)
 	}
+	result.NewKeyID = resp.Data.Hash
 
-	// 5. Update vault with new key
+	// Update vault with new key
 	fmt.Fprintf(os.Stderr, "Updating vault with new key...\n")
-	err = v.UpdateSecret(keyName, resp.Key, resp.Data.Hash)
-	if err != nil {
-		return fmt.Errorf("failed to store new API key: %w", err)
+	update := vault.SecretUpdate{Value: resp.Key, ID: resp.Data.Hash, Reason: reason}
+	if err := v.UpdateSecrets(map[string]vault.SecretUpdate{keyName: update}); err != nil {
+		return nil, fmt.Errorf("failed to store new API key: %w", err)
+	}
+
+	// Revoke old key
+	if oldKeyID != "" {
+		fmt.Fprintf(os.Stderr, "Revoking old key...\n")
+		result.RevokeErr = client.RevokeKey(oldKeyID)
 	}
 
-	// 6. Revoke old key
-	fmt.Fprintf(os.Stderr, "Revoking old key...\n")
-	err = client.RevokeKey(oldKeyID)
+	return result, nil
+}
+
+// rotateStatic replaces the value of a static secret with one read from the user
+func rotateStatic(v *vault.Vault, keyName, reason string) error {
+	value, err := readNewValue(keyName)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "⚠️  Warning: Failed to revoke old key: %v\n", err)
-		fmt.Fprintf(os.Stderr, "The new key has been stored successfully, but the old key may still be active.\n")
-		fmt.Fprintf(os.Stderr, "You may want to try revoking it manually or contact OpenRouter support.\n")
-		return fmt.Errorf("key rotation partially succeeded but revocation failed")
+		return err
 	}
 
-	fmt.Fprintf(os.Stderr, "✓ API key '%s' rotated successfully!\n", keyName)
+	update := vault.SecretUpdate{Value: value, Reason: reason}
+	if err := v.UpdateSecrets(map[string]vault.SecretUpdate{keyName: update}); err != nil {
+		return fmt.Errorf("failed to store new value: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "✓ Secret '%s' rotated successfully!\n", keyName)
 	return nil
 }
+
+// readNewValue asks the user for the new value of a static secret
+func readNewValue(keyName string) (string, error) {
+	value, err := readSecretInput(fmt.Sprintf("New value for '%s' (input hidden): ", keyName))
+	if err != nil {
+		return "", err
+	}
+	if value == "" {
+		return "", fmt.Errorf("secret value cannot be empty")
+	}
+	return value, nil
+}
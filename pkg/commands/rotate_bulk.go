@@ -0,0 +1,270 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/spacebarlabs/lean_vault/pkg/api"
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// DefaultRotateParallelism is how many keys bulk rotation works on at once
+const DefaultRotateParallelism = 4
+
+// Outcomes of rotating one key in a bulk rotation
+const (
+	rotationSucceeded = "success"
+	rotationPartial   = "partial"
+	rotationFailed    = "failed"
+	rotationSkipped   = "skipped"
+)
+
+// BulkRotateOptions configures a bulk rotation
+type BulkRotateOptions struct {
+	// Parallel is the number of concurrent API calls; zero uses the default
+	Parallel int
+	// JSON prints the report as JSON instead of a table
+	JSON bool
+	// Filter limits --all and --due to keys with matching metadata
+	Filter vault.Filter
+}
+
+// rotationReport is the outcome of rotating one key in a bulk rotation
+type rotationReport struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	OldKeyID string `json:"old_key_id,omitempty"`
+	NewKeyID string `json:"new_key_id,omitempty"`
+	Error    string `json:"error,omitempty"`
+
+	newValue string
+	// client manages keys of the account that provisioned the old key
+	client *api.Client
+}
+
+// RotateAll rotates every provisioned key in the vault that matches the filter
+func RotateAll(opts BulkRotateOptions) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	entries, err := v.ListSecretEntries()
+	if err != nil {
+		return fmt.Errorf("failed to list secrets: %w", err)
+	}
+	entries = vault.FilterEntries(entries, opts.Filter)
+	if len(entries) == 0 {
+		fmt.Fprintln(os.Stderr, "No keys match.")
+		return nil
+	}
+
+	return rotateMany(v, entries, sortedNames(entries), opts)
+}
+
+// RotateMany rotates the named keys
+func RotateMany(names []string, opts BulkRotateOptions) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	entries, err := v.ListSecretEntries()
+	if err != nil {
+		return fmt.Errorf("failed to list secrets: %w", err)
+	}
+	for _, name := range names {
+		if _, ok := entries[name]; !ok {
+			return fmt.Errorf("secret %s not found", name)
+		}
+	}
+
+	return rotateMany(v, entries, names, opts)
+}
+
+// rotateMany creates replacements for the named keys concurrently, stores
+// them with a single vault write, then revokes the old keys concurrently.
+// Static secrets and leases are skipped. If the vault write fails, the new
+// keys are revoked again and the old ones are left untouched.
+func rotateMany(v *vault.Vault, entries map[string]vault.SecretEntry, names []string, opts BulkRotateOptions) error {
+	parallel := opts.Parallel
+	if parallel <= 0 {
+		parallel = DefaultRotateParallelism
+	}
+
+	// A name given twice would create two keys, one of which is never stored
+	seen := make(map[string]bool, len(names))
+	var reports, pending []*rotationReport
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		entry := entries[name]
+		report := &rotationReport{Name: name, OldKeyID: entry.ID}
+		reports = append(reports, report)
+
+		switch {
+		case entry.SecretType() == vault.SecretTypeStatic:
+			report.Status = rotationSkipped
+			report.Error = "static secrets need a new value; use 'lean_vault rotate " + name + "'"
+		case entry.IsLease():
+			report.Status = rotationSkipped
+			report.Error = "leases expire instead of rotating"
+		default:
+			pending = append(pending, report)
+		}
+	}
+
+	if len(pending) > 0 {
+		// Resolve each key's account up front; a missing account fails only its keys
+		clients := newAccountClients(v)
+		var resolved []*rotationReport
+		for _, report := range pending {
+			client, err := clients.forEntry(entries[report.Name])
+			if err != nil {
+				report.Status = rotationFailed
+				report.Error = err.Error()
+				continue
+			}
+			report.client = client
+			resolved = append(resolved, report)
+		}
+		pending = resolved
+
+		if !opts.JSON {
+			fmt.Fprintf(os.Stderr, "Rotating %d key(s) with up to %d at a time...\n", len(pending), parallel)
+		}
+
+		// Create the replacements
+		forEachConcurrently(pending, parallel, func(report *rotationReport) {
+			resp, err := report.client.CreateKey(report.Name)
+			if err != nil {
+				report.Status = rotationFailed
+				report.Error = fmt.Sprintf("failed to create new API key: %v", err)
+				return
+			}
+			report.NewKeyID = resp.Data.Hash
+			report.newValue = resp.Key
+		})
+
+		// Store every new key at once
+		updates := make(map[string]vault.SecretUpdate)
+		var created []*rotationReport
+		for _, report := range pending {
+			if report.Status == "" {
+				updates[report.Name] = vault.SecretUpdate{Value: report.newValue, ID: report.NewKeyID, Reason: vault.ReasonRotate}
+				created = append(created, report)
+			}
+		}
+		if len(updates) > 0 {
+			if err := v.UpdateSecrets(updates); err != nil {
+				discardNewKeys(created, parallel, err)
+				created = nil
+			}
+		}
+
+		// Revoke the old keys
+		forEachConcurrently(created, parallel, func(report *rotationReport) {
+			report.Status = rotationSucceeded
+			if report.OldKeyID == "" {
+				report.Status = rotationPartial
+				report.Error = "no OpenRouter ID was stored for the old key, so it was not revoked"
+				return
+			}
+			if err := report.client.RevokeKey(report.OldKeyID); err != nil {
+				report.Status = rotationPartial
+				report.Error = fmt.Sprintf("failed to revoke old key: %v", err)
+			}
+		})
+	}
+
+	for _, report := range reports {
+		if report.Status == rotationSucceeded || report.Status == rotationPartial {
+			recordAudit(v, auditRotate, report.Name, map[string]string{
+				"old_key_id": report.OldKeyID,
+				"new_key_id": report.NewKeyID,
+				"revoked":    fmt.Sprint(report.Status == rotationSucceeded),
+				"bulk":       "true",
+			})
+		}
+	}
+
+	if opts.JSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(reports); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	} else {
+		printRotationReports(reports)
+	}
+
+	failures := 0
+	for _, report := range reports {
+		if report.Status == rotationFailed || report.Status == rotationPartial {
+			failures++
+		}
+	}
+	if failures > 0 {
+		return fmt.Errorf("failed to fully rotate %d key(s)", failures)
+	}
+	return nil
+}
+
+// discardNewKeys revokes keys that were created but could not be stored, so
+// they don't linger unused. The old keys stay in place.
+func discardNewKeys(reports []*rotationReport, parallel int, storeErr error) {
+	forEachConcurrently(reports, parallel, func(report *rotationReport) {
+		report.Status = rotationFailed
+		report.Error = fmt.Sprintf("failed to store new API key: %v", storeErr)
+		if err := report.client.RevokeKey(report.NewKeyID); err != nil {
+			report.Error += fmt.Sprintf("; the unstored new key %s could not be revoked: %v", report.NewKeyID, err)
+		}
+	})
+}
+
+// forEachConcurrently calls fn for each item with at most parallel calls in flight
+func forEachConcurrently[T any](items []T, parallel int, fn func(T)) {
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for _, item := range items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(item T) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(item)
+		}(item)
+	}
+	wg.Wait()
+}
+
+// printRotationReports prints a bulk rotation report as a table
+func printRotationReports(reports []*rotationReport) {
+	sorted := append([]*rotationReport(nil), reports...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	counts := make(map[string]int)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY NAME\tSTATUS\tDETAILS")
+	for _, report := range sorted {
+		counts[report.Status]++
+		details := report.Error
+		if details == "" {
+			details = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", report.Name, report.Status, details)
+	}
+	w.Flush()
+
+	fmt.Printf("\n%d succeeded, %d partial, %d failed, %d skipped\n",
+		counts[rotationSucceeded], counts[rotationPartial], counts[rotationFailed], counts[rotationSkipped])
+}
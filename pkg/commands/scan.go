@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spacebarlabs/lean_vault/pkg/scan"
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// ScanOptions controls what the scan command looks at
+type ScanOptions struct {
+	// Path is the file or directory to scan, and the repository for git modes
+	Path string
+	// GitHistory also scans every commit reachable from any ref
+	GitHistory bool
+	// Staged scans only the changes staged for commit, as the pre-commit hook does
+	Staged bool
+}
+
+// Scan reports any value currently or previously stored in the vault that
+// appears in the working tree or git history
+func Scan(opts ScanOptions) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	fingerprints, err := v.SecretFingerprints()
+	if err != nil {
+		return fmt.Errorf("failed to load secret fingerprints: %w", err)
+	}
+	scanner := scan.New(fingerprints)
+
+	var findings []scan.Finding
+	if opts.Staged {
+		findings, err = scanner.ScanStaged(opts.Path)
+		if err != nil {
+			return fmt.Errorf("failed to scan staged changes: %w", err)
+		}
+	} else {
+		var warnings []error
+		findings, warnings, err = scanner.ScanPath(opts.Path)
+		if err != nil {
+			return fmt.Errorf("failed to scan %s: %w", opts.Path, err)
+		}
+		for _, warning := range warnings {
+			fmt.Fprintf(os.Stderr, "⚠️  Warning: Skipped %v\n", warning)
+		}
+		if opts.GitHistory {
+			historyFindings, err := scanner.ScanGitHistory(opts.Path)
+			if err != nil {
+				return fmt.Errorf("failed to scan git history: %w", err)
+			}
+			findings = append(findings, historyFindings...)
+		}
+	}
+
+	details := map[string]string{"path": opts.Path, "findings": fmt.Sprint(len(findings))}
+	recordAudit(v, auditScan, "", details)
+
+	if len(findings) == 0 {
+		fmt.Fprintln(os.Stderr, "✓ No stored secrets found")
+		return nil
+	}
+
+	for _, f := range findings {
+		if f.Commit != "" {
+			fmt.Printf("%s:%s:%d: %s\n", shortCommit(f.Commit), f.Path, f.Line, f.Name)
+		} else {
+			fmt.Printf("%s:%d: %s\n", f.Path, f.Line, f.Name)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "\n⚠️  Found %d occurrence(s) of stored secrets.\n", len(findings))
+	fmt.Fprintln(os.Stderr, "Remove them, and rotate any key that was committed or pushed:")
+	fmt.Fprintln(os.Stderr, "  lean_vault rotate <key-name>")
+	return fmt.Errorf("secrets found")
+}
+
+// InstallScanHook installs a git pre-commit hook that runs "lean_vault scan --staged"
+func InstallScanHook(repo string) error {
+	hookPath, err := scan.InstallHook(repo)
+	if err != nil {
+		return fmt.Errorf("failed to install hook: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "✓ Pre-commit hook installed at %s\n", hookPath)
+	return nil
+}
+
+// shortCommit abbreviates a commit hash for display
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// Set stores a static secret read from a hidden prompt or stdin
+func Set(keyName string) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	// Refuse early so the user isn't prompted for a value that can't be stored
+	if vault.IsSystemName(vault.CanonicalName(keyName)) {
+		return fmt.Errorf("secret name %q is reserved", keyName)
+	}
+	if entry, err := v.GetSecretEntry(keyName); err == nil && entry.SecretType() != vault.SecretTypeStatic {
+		fmt.Fprintf(os.Stderr, "'%s' is a provisioned %s key.\n", keyName, entry.SecretType())
+		fmt.Fprintf(os.Stderr, "Use 'lean_vault rotate %s' to replace it.\n", keyName)
+		return fmt.Errorf("cannot overwrite provisioned key")
+	} else if err != nil {
+		if err := vault.ValidateName(keyName); err != nil {
+			return err
+		}
+	}
+
+	value, err := readSecretInput(fmt.Sprintf("Value for '%s' (input hidden): ", keyName))
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		return fmt.Errorf("secret value cannot be empty")
+	}
+
+	if err := v.SetStaticSecret(keyName, value); err != nil {
+		return fmt.Errorf("failed to store secret: %w", err)
+	}
+
+	recordAudit(v, auditSet, keyName, nil)
+	fmt.Fprintf(os.Stderr, "✓ Secret '%s' stored successfully!\n", keyName)
+	return nil
+}
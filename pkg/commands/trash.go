@@ -0,0 +1,170 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spacebarlabs/lean_vault/pkg/api"
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// TrashList shows the removed secrets that can still be restored
+func TrashList() error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	trash, err := v.ListTrash()
+	if err != nil {
+		return fmt.Errorf("failed to list trash: %w", err)
+	}
+	settings, err := v.LoadSettings()
+	if err != nil {
+		return err
+	}
+
+	if len(trash) == 0 {
+		fmt.Println("The trash is empty.")
+		return nil
+	}
+
+	retention := settings.TrashRetention()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tREMOVED\tPURGED AFTER\tKEY ID\tKEY")
+	for _, trashed := range trash {
+		purgeAfter := "-"
+		if retention > 0 {
+			purgeAfter = formatTime(trashed.DeletedAt.Add(retention))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", trashed.Name, trashed.Entry.SecretType(), formatTime(trashed.DeletedAt),
+			purgeAfter, formatKeyID(trashed.Entry.ID), trashedKeyStatus(trashed))
+	}
+	w.Flush()
+	return nil
+}
+
+// trashedKeyStatus describes what happened to a trashed secret's provider key
+func trashedKeyStatus(trashed vault.TrashedSecret) string {
+	switch {
+	case trashed.NeedsRevocation():
+		return "active"
+	case trashed.Revoked:
+		return "revoked"
+	case trashed.KeepUpstream:
+		return "kept"
+	default:
+		return "-"
+	}
+}
+
+// TrashRestore moves a removed secret back into the vault, under newName if given
+func TrashRestore(name, newName string) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	trashed, err := v.RestoreSecret(name, newName)
+	if err != nil {
+		return fmt.Errorf("failed to restore secret: %w", err)
+	}
+	if newName == "" {
+		newName = name
+	}
+	recordAudit(v, auditRestore, newName, map[string]string{"from": name})
+
+	fmt.Fprintf(os.Stderr, "✓ Restored '%s'\n", newName)
+	if trashed.Revoked && trashed.Entry.IsProvisioned() {
+		fmt.Fprintln(os.Stderr, "⚠️  Its key was revoked when it was removed and no longer works.")
+		fmt.Fprintf(os.Stderr, "Run 'lean_vault rotate %s' to provision a new one.\n", newName)
+	}
+	return nil
+}
+
+// TrashPurge permanently deletes trashed secrets: those called names, or
+// all of them. Provider keys that are still active are revoked first unless
+// noRevoke is set.
+func TrashPurge(names []string, all, noRevoke bool) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	trash, err := v.ListTrash()
+	if err != nil {
+		return fmt.Errorf("failed to list trash: %w", err)
+	}
+
+	selected := make(map[string]bool, len(names))
+	for _, name := range names {
+		selected[name] = true
+	}
+	var targets []vault.TrashedSecret
+	for _, trashed := range trash {
+		if all || selected[trashed.Name] {
+			targets = append(targets, trashed)
+			delete(selected, trashed.Name)
+		}
+	}
+	for _, name := range sortedNames(selected) {
+		fmt.Fprintf(os.Stderr, "⚠️  Warning: '%s' is not in the trash\n", name)
+	}
+	if len(targets) == 0 {
+		fmt.Fprintln(os.Stderr, "Nothing to purge.")
+		return nil
+	}
+
+	clients := newAccountClients(v)
+	failures := 0
+	for _, trashed := range targets {
+		var client *api.Client
+		var err error
+		if !noRevoke && trashed.NeedsRevocation() {
+			client, err = clients.forEntry(trashed.Entry)
+		}
+		if err == nil {
+			err = purgeTrashed(v, client, trashed)
+		}
+		printGCResults([]gcResult{{description: fmt.Sprintf("'%s' from the trash", trashed.Name), purge: true, err: err}})
+		if err != nil {
+			failures++
+		}
+	}
+	if failures > 0 {
+		fmt.Fprintln(os.Stderr, "Secrets whose keys could not be revoked are kept; use --no-revoke to purge them anyway.")
+		return fmt.Errorf("failed to purge %d secret(s)", failures)
+	}
+	return nil
+}
+
+// purgeTrashed revokes a trashed secret's active provider keys, unless
+// client is nil, then deletes it for good
+func purgeTrashed(v *vault.Vault, client *api.Client, trashed vault.TrashedSecret) error {
+	revoked := false
+	if client != nil && trashed.NeedsRevocation() {
+		if previous := trashed.Entry.Previous; previous != nil && previous.ID != "" {
+			if err := client.RevokeKey(previous.ID); err != nil {
+				return fmt.Errorf("failed to revoke previous key: %w", err)
+			}
+		}
+		if trashed.Entry.IsProvisioned() {
+			if err := client.RevokeKey(trashed.Entry.ID); err != nil {
+				return fmt.Errorf("failed to revoke key: %w", err)
+			}
+		}
+		revoked = true
+	}
+
+	if _, err := v.PurgeTrash(trashed.Same); err != nil {
+		return err
+	}
+
+	details := map[string]string{"revoked": fmt.Sprint(revoked)}
+	if trashed.Entry.ID != "" {
+		details["key_id"] = trashed.Entry.ID
+	}
+	recordAudit(v, auditPurge, trashed.Name, details)
+	return nil
+}
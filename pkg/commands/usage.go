@@ -0,0 +1,191 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spacebarlabs/lean_vault/pkg/api"
+	"github.com/spacebarlabs/lean_vault/pkg/proxy"
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// Usage displays usage and limits for every provisioned key matching the
+// filter, as reported by OpenRouter
+func Usage(filter vault.Filter) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	entries, err := v.ListSecretEntries()
+	if err != nil {
+		return fmt.Errorf("failed to list secrets: %w", err)
+	}
+	entries = vault.FilterEntries(entries, filter)
+
+	names := make([]string, 0, len(entries))
+	for name, entry := range entries {
+		if entry.IsProvisioned() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		fmt.Println("No provisioned API keys found.")
+		return nil
+	}
+
+	clients := newAccountClients(v)
+	recordAudit(v, auditUsage, "", nil)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY NAME\tUSAGE ($)\tLIMIT ($)\tLIMIT REMAINING ($)\tSTATUS")
+
+	failures := 0
+	for _, name := range names {
+		client, err := clients.forEntry(entries[name])
+		var info *api.KeyInfo
+		if err == nil {
+			info, err = client.GetKey(entries[name].ID)
+		}
+		if err != nil {
+			failures++
+			fmt.Fprintf(w, "%s\t-\t-\t-\tError: %v\n", name, err)
+			continue
+		}
+
+		status := "OK"
+		if info.Data.Disabled {
+			status = "Disabled"
+		}
+		fmt.Fprintf(w, "%s\t%.2f\t%s\t%s\t%s\n", name, info.Data.Usage,
+			formatOptionalAmount(info.Data.Limit), formatOptionalAmount(info.Data.LimitRemaining), status)
+	}
+	w.Flush()
+
+	if failures > 0 {
+		return fmt.Errorf("failed to fetch usage for %d key(s)", failures)
+	}
+	return nil
+}
+
+// UsageLocal displays the usage recorded by the local proxy, per alias,
+// for aliases whose key matches the filter
+func UsageLocal(filter vault.Filter) error {
+	v, err := vault.New()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := proxy.LoadConfig(v.ProxyConfigPath())
+	if err != nil {
+		return err
+	}
+	ledger, err := proxy.OpenLedger(v.UsageLedgerPath())
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	totals := ledger.Totals(proxy.PeriodTotal, now)
+	recordAudit(v, auditUsage, "", map[string]string{"source": "local"})
+
+	// Include aliases with a budget even if they haven't made a request yet
+	aliases := make([]string, 0, len(totals))
+	for alias := range totals {
+		aliases = append(aliases, alias)
+	}
+	for alias := range cfg.Budgets {
+		if _, ok := totals[alias]; !ok {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+
+	if !filter.IsEmpty() {
+		entries, err := v.ListSecretEntries()
+		if err != nil {
+			return fmt.Errorf("failed to list secrets: %w", err)
+		}
+		matched := aliases[:0]
+		for _, alias := range aliases {
+			name := cfg.Aliases[alias]
+			if name == "" && totals[alias] != nil {
+				name = totals[alias].Key
+			}
+			if entry, ok := entries[name]; ok && filter.Matches(name, entry.Metadata()) {
+				matched = append(matched, alias)
+			}
+		}
+		aliases = matched
+	}
+
+	if len(aliases) == 0 {
+		fmt.Println("No local usage recorded.")
+		fmt.Println("Usage is recorded for requests made through 'lean_vault proxy'.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ALIAS\tKEY\tREQUESTS\tPROMPT TOKENS\tCOMPLETION TOKENS\tCOST ($)\tBUDGET\tSTATUS")
+	for _, alias := range aliases {
+		t := totals[alias]
+		if t == nil {
+			t = &proxy.Totals{Alias: alias, Key: cfg.Aliases[alias]}
+		}
+
+		budget, hasBudget := cfg.Budgets[alias]
+		budgetText := "-"
+		status := "OK"
+		if hasBudget {
+			budgetText = formatBudget(budget, ledger.Totals(budget.Period, now)[alias])
+			if ledger.Exceeded(alias, budget, now) {
+				status = "Budget exceeded"
+			}
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%.4f\t%s\t%s\n", alias, t.Key, t.Requests,
+			t.PromptTokens, t.CompletionTokens, t.Cost, budgetText, status)
+	}
+	w.Flush()
+	return nil
+}
+
+// formatBudget describes how much of a budget has been used in its current period
+func formatBudget(budget proxy.Budget, used *proxy.Totals) string {
+	if used == nil {
+		used = &proxy.Totals{}
+	}
+
+	period := budget.Period
+	if period == proxy.PeriodTotal {
+		period = "total"
+	}
+
+	var text string
+	if budget.Cost > 0 {
+		text = fmt.Sprintf("$%.2f/$%.2f", used.Cost, budget.Cost)
+	}
+	if budget.Tokens > 0 {
+		if text != "" {
+			text += ", "
+		}
+		text += fmt.Sprintf("%d/%d tokens", used.TotalTokens, budget.Tokens)
+	}
+	if text == "" {
+		return "-"
+	}
+	return text + " " + period
+}
+
+// formatOptionalAmount formats a dollar amount that the API may leave unset
+func formatOptionalAmount(amount *float64) string {
+	if amount == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", *amount)
+}
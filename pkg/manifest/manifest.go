@@ -0,0 +1,86 @@
+// Package manifest describes the keys a vault should contain and plans the
+// changes needed to get there
+package manifest
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+	"gopkg.in/yaml.v3"
+)
+
+// ProviderOpenRouter is the only provider keys can be provisioned from
+const ProviderOpenRouter = "openrouter"
+
+// Manifest is the desired set of keys. It is read from the keys section of
+// a YAML file, so it can share .lean_vault.yml with the project settings.
+type Manifest struct {
+	Keys []Key `yaml:"keys"`
+}
+
+// Key is the desired state of one key
+type Key struct {
+	Name string `yaml:"name"`
+	// Provider is empty or ProviderOpenRouter
+	Provider string `yaml:"provider,omitempty"`
+	// Limit is the spend cap in dollars; nil leaves the key's limit alone
+	Limit       *float64          `yaml:"limit,omitempty"`
+	Disabled    bool              `yaml:"disabled,omitempty"`
+	Description string            `yaml:"description,omitempty"`
+	Owner       string            `yaml:"owner,omitempty"`
+	Tags        map[string]string `yaml:"tags,omitempty"`
+	Policy      *vault.Policy     `yaml:"policy,omitempty"`
+}
+
+// Load reads and validates a manifest
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	m := &Manifest{}
+	if err := yaml.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Validate checks that every key is well formed and named once
+func (m *Manifest) Validate() error {
+	seen := make(map[string]bool)
+	for i, key := range m.Keys {
+		if key.Name == "" {
+			return fmt.Errorf("key %d has no name", i+1)
+		}
+		if err := vault.ValidateName(key.Name); err != nil {
+			return err
+		}
+		if seen[key.Name] {
+			return fmt.Errorf("key %s is listed more than once", key.Name)
+		}
+		seen[key.Name] = true
+
+		if key.Provider != "" && key.Provider != ProviderOpenRouter {
+			return fmt.Errorf("key %s: unsupported provider %q", key.Name, key.Provider)
+		}
+		for tag := range key.Tags {
+			if err := vault.ValidateTagKey(tag); err != nil {
+				return fmt.Errorf("key %s: %w", key.Name, err)
+			}
+		}
+		if key.Limit != nil && *key.Limit < 0 {
+			return fmt.Errorf("key %s: limit can't be negative", key.Name)
+		}
+		if key.Policy != nil {
+			if err := key.Policy.Validate(); err != nil {
+				return fmt.Errorf("key %s: %w", key.Name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+func limit(v float64) *float64 {
+	return &v
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name: "Valid manifest",
+			content: `key_file: ~/keys/project.key
+keys:
+  - name: chatbot
+    limit: 10
+    tags: {team: ml}
+    policy: {max_age: 30d}
+  - name: batch
+    provider: openrouter
+    disabled: true
+`,
+		},
+		{name: "Duplicate name", content: "keys: [{name: a}, {name: a}]", wantErr: true},
+		{name: "Missing name", content: "keys: [{limit: 5}]", wantErr: true},
+		{name: "Reserved name", content: "keys: [{name: " + vault.MainProvisioningKeyName + "}]", wantErr: true},
+		{name: "Unknown provider", content: "keys: [{name: a, provider: openai}]", wantErr: true},
+		{name: "Negative limit", content: "keys: [{name: a, limit: -1}]", wantErr: true},
+		{name: "Invalid policy", content: "keys: [{name: a, policy: {max_age: soon}}]", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "manifest.yml")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("Failed to write manifest: %v", err)
+			}
+			m, err := Load(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && len(m.Keys) != 2 {
+				t.Errorf("Got %d keys, want 2", len(m.Keys))
+			}
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	managed := func(id string) vault.SecretEntry {
+		return vault.SecretEntry{ID: id, Managed: true, Tags: map[string]string{"team": "ml"}}
+	}
+
+	m := &Manifest{Keys: []Key{
+		{Name: "new", Limit: limit(5)},
+		{Name: "unchanged", Limit: limit(10), Tags: map[string]string{"team": "ml"}},
+		{Name: "raised", Limit: limit(20), Tags: map[string]string{"team": "ml"}},
+		{Name: "disabled", Disabled: true, Tags: map[string]string{"team": "ml"}},
+		{Name: "adopted"},
+		{Name: "reowned", Owner: "alice", Tags: map[string]string{"team": "ml"}},
+	}}
+	current := map[string]Current{
+		"unchanged": {Entry: managed("1"), Limit: limit(10)},
+		"raised":    {Entry: managed("2"), Limit: limit(10)},
+		"disabled":  {Entry: managed("3")},
+		"adopted":   {Entry: vault.SecretEntry{ID: "4"}},
+		"dropped":   {Entry: managed("5")},
+		"reowned":   {Entry: managed("7")},
+		"by-hand":   {Entry: vault.SecretEntry{ID: "6"}},
+	}
+
+	changes, err := Plan(m, current)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+
+	want := []struct {
+		action string
+		name   string
+		fields []string
+	}{
+		{ActionUpdate, "adopted", []string{"managed"}},
+		{ActionUpdate, "disabled", []string{"disabled"}},
+		{ActionRemove, "dropped", nil},
+		{ActionCreate, "new", nil},
+		{ActionUpdate, "raised", []string{"limit"}},
+		{ActionUpdate, "reowned", []string{"owner"}},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("Got %d changes, want %d: %+v", len(changes), len(want), changes)
+	}
+	for i, w := range want {
+		c := changes[i]
+		if c.Action != w.action || c.Name != w.name || len(c.Diffs) != len(w.fields) {
+			t.Errorf("Change %d: got %s %s %+v, want %s %s %v", i, c.Action, c.Name, c.Diffs, w.action, w.name, w.fields)
+			continue
+		}
+		for _, field := range w.fields {
+			if !c.Has(field) {
+				t.Errorf("Change %d should update %s, got %+v", i, field, c.Diffs)
+			}
+		}
+	}
+
+	// Static secrets can't be provisioned, so they can't be managed either
+	current["static"] = Current{Entry: vault.SecretEntry{Type: vault.SecretTypeStatic}}
+	m.Keys = append(m.Keys, Key{Name: "static"})
+	if _, err := Plan(m, current); err == nil {
+		t.Error("Managing a static secret should fail")
+	}
+}
@@ -0,0 +1,143 @@
+package manifest
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// Change actions
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionRemove = "remove"
+)
+
+// Current is the existing state of a key in the vault and at the provider
+type Current struct {
+	Entry vault.SecretEntry
+	// Limit and Disabled come from the provider; Limit is nil for no limit
+	Limit    *float64
+	Disabled bool
+}
+
+// Change is one step of a plan
+type Change struct {
+	Action string
+	Name   string
+	// Key is the desired state; nil when removing
+	Key *Key
+	// Diffs lists what an update changes
+	Diffs []Diff
+}
+
+// Diff is a changed field
+type Diff struct {
+	Field string
+	From  string
+	To    string
+}
+
+// Has reports whether the change touches field
+func (c Change) Has(field string) bool {
+	for _, diff := range c.Diffs {
+		if diff.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Plan compares the manifest with the current keys, by name, and returns
+// the changes to make, sorted by name. Keys missing from the manifest are
+// only removed if they are managed, so keys added by hand are left alone.
+func Plan(m *Manifest, current map[string]Current) ([]Change, error) {
+	var changes []Change
+	desired := make(map[string]bool)
+
+	for i := range m.Keys {
+		key := &m.Keys[i]
+		desired[key.Name] = true
+
+		cur, exists := current[key.Name]
+		if !exists {
+			changes = append(changes, Change{Action: ActionCreate, Name: key.Name, Key: key})
+			continue
+		}
+		if !cur.Entry.IsProvisioned() {
+			return nil, fmt.Errorf("%s is not a provisioned key and can't be managed by the manifest", key.Name)
+		}
+		if cur.Entry.IsLease() {
+			return nil, fmt.Errorf("%s is a lease and can't be managed by the manifest", key.Name)
+		}
+
+		if diffs := diffKey(key, cur); len(diffs) > 0 {
+			changes = append(changes, Change{Action: ActionUpdate, Name: key.Name, Key: key, Diffs: diffs})
+		}
+	}
+
+	for name, cur := range current {
+		if !desired[name] && cur.Entry.Managed {
+			changes = append(changes, Change{Action: ActionRemove, Name: name})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes, nil
+}
+
+// diffKey lists the fields of cur that differ from key
+func diffKey(key *Key, cur Current) []Diff {
+	var diffs []Diff
+	if key.Limit != nil && (cur.Limit == nil || *cur.Limit != *key.Limit) {
+		diffs = append(diffs, Diff{Field: "limit", From: FormatLimit(cur.Limit), To: FormatLimit(key.Limit)})
+	}
+	if key.Disabled != cur.Disabled {
+		diffs = append(diffs, Diff{Field: "disabled", From: fmt.Sprint(cur.Disabled), To: fmt.Sprint(key.Disabled)})
+	}
+	if key.Description != cur.Entry.Description {
+		diffs = append(diffs, Diff{Field: "description", From: formatText(cur.Entry.Description), To: formatText(key.Description)})
+	}
+	if key.Owner != cur.Entry.Owner {
+		diffs = append(diffs, Diff{Field: "owner", From: formatText(cur.Entry.Owner), To: formatText(key.Owner)})
+	}
+	if from, to := vault.FormatTags(cur.Entry.Tags), vault.FormatTags(key.Tags); from != to {
+		diffs = append(diffs, Diff{Field: "tags", From: from, To: to})
+	}
+	if from, to := FormatPolicy(cur.Entry.Policy), FormatPolicy(key.Policy); from != to {
+		diffs = append(diffs, Diff{Field: "policy", From: from, To: to})
+	}
+	if !cur.Entry.Managed {
+		diffs = append(diffs, Diff{Field: "managed", From: "false", To: "true"})
+	}
+	return diffs
+}
+
+// FormatLimit formats a spend cap, or "none"
+func FormatLimit(limit *float64) string {
+	if limit == nil {
+		return "none"
+	}
+	return fmt.Sprintf("$%.2f", *limit)
+}
+
+// formatText quotes a free-form field, or returns "none"
+func formatText(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return fmt.Sprintf("%q", s)
+}
+
+// FormatPolicy formats a rotation policy, or "none"
+func FormatPolicy(p *vault.Policy) string {
+	if p == nil {
+		return "none"
+	}
+	s := "max_age=" + p.MaxAge
+	if p.RotateBeforeExpiry != "" {
+		s += ",rotate_before_expiry=" + p.RotateBeforeExpiry
+	}
+	return s + ",on_expiry=" + p.Action()
+}
@@ -0,0 +1,173 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxAccountedBody is how much of a non-streamed response is kept for
+// reading usage; larger bodies are passed through without accounting
+const maxAccountedBody = 4 << 20
+
+// Response headers that report usage, as sent by some OpenAI-compatible
+// gateways. They are used when the body has no usage block, for example
+// because it was too large to keep.
+const (
+	headerPromptTokens     = "X-Usage-Prompt-Tokens"
+	headerCompletionTokens = "X-Usage-Completion-Tokens"
+	headerTotalTokens      = "X-Usage-Total-Tokens"
+	headerCost             = "X-Usage-Cost"
+	headerLiteLLMCost      = "X-Litellm-Response-Cost"
+)
+
+// usageReport is the usage block of an OpenAI/OpenRouter-style response
+type usageReport struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// usageFromHeaders returns the usage reported in response headers, or nil
+// if there is none. Values that don't parse are ignored.
+func usageFromHeaders(header http.Header) *usageReport {
+	var usage usageReport
+	found := false
+
+	intHeader := func(name string, field *int) {
+		if n, err := strconv.Atoi(header.Get(name)); err == nil {
+			*field = n
+			found = true
+		}
+	}
+	intHeader(headerPromptTokens, &usage.PromptTokens)
+	intHeader(headerCompletionTokens, &usage.CompletionTokens)
+	intHeader(headerTotalTokens, &usage.TotalTokens)
+
+	for _, name := range []string{headerCost, headerLiteLLMCost} {
+		if cost, err := strconv.ParseFloat(header.Get(name), 64); err == nil {
+			usage.Cost = cost
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return nil
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	return &usage
+}
+
+// responseSummary holds the fields of a response body used for accounting
+type responseSummary struct {
+	Model string       `json:"model"`
+	Usage *usageReport `json:"usage"`
+}
+
+// accountingBody passes a response body through untouched while watching it
+// for usage information, and calls done once when the body is finished
+type accountingBody struct {
+	body      io.ReadCloser
+	streaming bool
+	done      func(responseSummary)
+
+	buf     bytes.Buffer
+	summary responseSummary
+	partial []byte
+	tooBig  bool
+	closed  bool
+}
+
+// newAccountingBody wraps a response body; streaming selects SSE parsing
+func newAccountingBody(body io.ReadCloser, contentType string, done func(responseSummary)) *accountingBody {
+	return &accountingBody{
+		body:      body,
+		streaming: strings.HasPrefix(contentType, "text/event-stream"),
+		done:      done,
+	}
+}
+
+func (a *accountingBody) Read(p []byte) (int, error) {
+	n, err := a.body.Read(p)
+	if n > 0 {
+		a.observe(p[:n])
+	}
+	if err == io.EOF {
+		a.finish()
+	}
+	return n, err
+}
+
+func (a *accountingBody) Close() error {
+	a.finish()
+	return a.body.Close()
+}
+
+// observe inspects a chunk of the body as it passes through
+func (a *accountingBody) observe(chunk []byte) {
+	if !a.streaming {
+		if a.buf.Len()+len(chunk) > maxAccountedBody {
+			a.tooBig = true
+			a.buf.Reset()
+		}
+		if !a.tooBig {
+			a.buf.Write(chunk)
+		}
+		return
+	}
+
+	// Server-sent events arrive as "data: {...}" lines; usage comes in the
+	// last event before "data: [DONE]"
+	a.partial = append(a.partial, chunk...)
+	rest := a.partial
+	for {
+		nl := bytes.IndexByte(rest, '\n')
+		if nl < 0 {
+			break
+		}
+		a.observeEvent(rest[:nl])
+		rest = rest[nl+1:]
+	}
+	if len(rest) > maxAccountedBody {
+		rest = nil
+	}
+	a.partial = append(a.partial[:0], rest...)
+}
+
+// observeEvent records the model and usage from one SSE data line
+func (a *accountingBody) observeEvent(line []byte) {
+	payload, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:"))
+	if !ok {
+		return
+	}
+	var event responseSummary
+	if json.Unmarshal(bytes.TrimSpace(payload), &event) != nil {
+		return
+	}
+	if event.Model != "" {
+		a.summary.Model = event.Model
+	}
+	if event.Usage != nil {
+		a.summary.Usage = event.Usage
+	}
+}
+
+// finish reports the summary once the body is fully read or closed
+func (a *accountingBody) finish() {
+	if a.closed {
+		return
+	}
+	a.closed = true
+
+	if !a.streaming && !a.tooBig {
+		json.Unmarshal(a.buf.Bytes(), &a.summary)
+	}
+	a.done(a.summary)
+}
@@ -0,0 +1,42 @@
+package proxy
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultListenAddr is where the proxy listens unless configured otherwise
+const DefaultListenAddr = "127.0.0.1:8787"
+
+// Config describes where the proxy listens, where it forwards to and which
+// vault key each alias token stands for
+type Config struct {
+	Listen  string `yaml:"listen,omitempty"`
+	BaseURL string `yaml:"base_url,omitempty"`
+	// Aliases maps the token an application sends to a vault secret name
+	Aliases map[string]string `yaml:"aliases,omitempty"`
+	// Budgets limits local spending per alias; requests are refused once
+	// an alias has used up its budget
+	Budgets map[string]Budget `yaml:"budgets,omitempty"`
+}
+
+// LoadConfig reads a proxy configuration file. A missing file is not an
+// error and yields an empty configuration.
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read proxy config: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse proxy config %s: %w", path, err)
+	}
+	return cfg, nil
+}
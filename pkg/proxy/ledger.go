@@ -0,0 +1,212 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Budget periods
+const (
+	// PeriodTotal counts all recorded usage
+	PeriodTotal = ""
+	// PeriodDaily counts usage since midnight UTC
+	PeriodDaily = "daily"
+	// PeriodMonthly counts usage since the start of the month (UTC)
+	PeriodMonthly = "monthly"
+)
+
+// Budget limits what one alias may spend through the proxy. Zero fields are
+// not enforced.
+type Budget struct {
+	Cost   float64 `yaml:"cost,omitempty"`
+	Tokens int     `yaml:"tokens,omitempty"`
+	Period string  `yaml:"period,omitempty"`
+}
+
+// Since returns the start of the budget's current period
+func (b Budget) Since(now time.Time) time.Time {
+	now = now.UTC()
+	switch b.Period {
+	case PeriodDaily:
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	case PeriodMonthly:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Time{}
+	}
+}
+
+// Record is one proxied request in the usage ledger
+type Record struct {
+	Time             time.Time `json:"time"`
+	Alias            string    `json:"alias"`
+	Key              string    `json:"key"`
+	Model            string    `json:"model,omitempty"`
+	Status           int       `json:"status"`
+	PromptTokens     int       `json:"prompt_tokens,omitempty"`
+	CompletionTokens int       `json:"completion_tokens,omitempty"`
+	TotalTokens      int       `json:"total_tokens,omitempty"`
+	Cost             float64   `json:"cost,omitempty"`
+}
+
+// Totals sums the usage of one alias
+type Totals struct {
+	Alias            string  `json:"alias"`
+	Key              string  `json:"key"`
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// add includes a record in the totals
+func (t *Totals) add(rec Record) {
+	t.Key = rec.Key
+	t.Requests++
+	t.PromptTokens += rec.PromptTokens
+	t.CompletionTokens += rec.CompletionTokens
+	t.TotalTokens += rec.TotalTokens
+	t.Cost += rec.Cost
+}
+
+// budgetPeriods lists the periods the ledger keeps running totals for
+var budgetPeriods = []string{PeriodTotal, PeriodDaily, PeriodMonthly}
+
+// periodTotals sums usage per alias since the start of a period
+type periodTotals struct {
+	start  time.Time
+	totals map[string]*Totals
+}
+
+// Ledger is an append-only file of usage records, one JSON object per line.
+// Only running totals for the current period of each kind are kept in
+// memory, so checking a budget costs the same however many requests have
+// been recorded.
+type Ledger struct {
+	path string
+
+	mu      sync.Mutex
+	periods map[string]*periodTotals
+}
+
+// OpenLedger loads the ledger at path, which need not exist yet
+func OpenLedger(path string) (*Ledger, error) {
+	l := &Ledger{path: path, periods: make(map[string]*periodTotals)}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer f.Close()
+
+	lines := bufio.NewScanner(f)
+	for lines.Scan() {
+		var rec Record
+		if err := json.Unmarshal(lines.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("corrupt usage ledger %s: %w", path, err)
+		}
+		l.add(rec)
+	}
+	if err := lines.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+
+	return l, nil
+}
+
+// Append adds a record to the ledger
+func (l *Ledger) Append(rec Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage record: %w", err)
+	}
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write usage record: %w", err)
+	}
+
+	l.add(rec)
+	return nil
+}
+
+// add includes a record in the running totals of each period it falls in.
+// A record from a later period starts that period's totals afresh; the
+// caller must hold l.mu.
+func (l *Ledger) add(rec Record) {
+	for _, period := range budgetPeriods {
+		start := Budget{Period: period}.Since(rec.Time)
+		p := l.periods[period]
+		if p == nil || start.After(p.start) {
+			p = &periodTotals{start: start, totals: make(map[string]*Totals)}
+			l.periods[period] = p
+		} else if start.Before(p.start) {
+			continue
+		}
+
+		t, ok := p.totals[rec.Alias]
+		if !ok {
+			t = &Totals{Alias: rec.Alias}
+			p.totals[rec.Alias] = t
+		}
+		t.add(rec)
+	}
+}
+
+// current returns the running totals for period if they cover the period
+// containing now, and nil if nothing has been recorded in it yet; the
+// caller must hold l.mu
+func (l *Ledger) current(period string, now time.Time) map[string]*Totals {
+	p := l.periods[period]
+	if p == nil || !p.start.Equal(Budget{Period: period}.Since(now)) {
+		return nil
+	}
+	return p.totals
+}
+
+// Totals returns usage per alias in the current instance of period, which
+// is one of the Period constants
+func (l *Ledger) Totals(period string, now time.Time) map[string]*Totals {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	totals := make(map[string]*Totals)
+	for alias, t := range l.current(period, now) {
+		copied := *t
+		totals[alias] = &copied
+	}
+	return totals
+}
+
+// Exceeded reports whether an alias has used up its budget for the current period
+func (l *Ledger) Exceeded(alias string, budget Budget, now time.Time) bool {
+	if budget.Cost <= 0 && budget.Tokens <= 0 {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	t, ok := l.current(budget.Period, now)[alias]
+	if !ok {
+		return false
+	}
+	return (budget.Cost > 0 && t.Cost >= budget.Cost) ||
+		(budget.Tokens > 0 && t.TotalTokens >= budget.Tokens)
+}
@@ -0,0 +1,179 @@
+// Package proxy forwards OpenAI/OpenRouter-style requests to the upstream
+// API, replacing a local alias token with the real key from the vault so
+// applications never see it
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// KeySource looks up the current value of a vault secret
+type KeySource interface {
+	Get(name string) (string, error)
+}
+
+// Proxy is an http.Handler that swaps alias tokens for vault keys
+type Proxy struct {
+	target  *url.URL
+	aliases map[string]string
+	keys    KeySource
+	reverse *httputil.ReverseProxy
+	ledger  *Ledger
+	budgets map[string]Budget
+}
+
+// aliasContextKey carries the caller's alias from the request to its response
+type aliasContextKey struct{}
+
+// New creates a proxy forwarding to baseURL. Keys are looked up on every
+// request, so a rotation takes effect without restarting the proxy.
+func New(baseURL string, aliases map[string]string, keys KeySource) (*Proxy, error) {
+	target, err := url.Parse(baseURL)
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q", baseURL)
+	}
+
+	for alias, name := range aliases {
+		if alias == "" || name == "" {
+			return nil, fmt.Errorf("aliases need both a token and a secret name")
+		}
+		if vault.IsSystemName(vault.CanonicalName(name)) {
+			return nil, fmt.Errorf("alias %s cannot expose %s", alias, name)
+		}
+	}
+
+	p := &Proxy{
+		target:  target,
+		aliases: aliases,
+		keys:    keys,
+	}
+	p.reverse = &httputil.ReverseProxy{
+		Rewrite: p.rewrite,
+		// Flush every write so streamed (SSE) responses reach the client as they arrive
+		FlushInterval:  -1,
+		ModifyResponse: p.account,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			fmt.Fprintf(os.Stderr, "Proxy error for %s: %v\n", r.URL.Path, err)
+			writeError(w, http.StatusBadGateway, "upstream request failed")
+		},
+	}
+	return p, nil
+}
+
+// SetAccounting records usage of every proxied request in ledger and
+// refuses requests from aliases that have used up their budget
+func (p *Proxy) SetAccounting(ledger *Ledger, budgets map[string]Budget) {
+	p.ledger = ledger
+	p.budgets = budgets
+}
+
+// ServeHTTP resolves the caller's alias and forwards the request
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	alias := bearerToken(r)
+	name, ok := p.aliases[alias]
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unknown lean_vault alias token")
+		return
+	}
+
+	if p.ledger != nil && p.ledger.Exceeded(alias, p.budgets[alias], time.Now()) {
+		writeError(w, http.StatusTooManyRequests, fmt.Sprintf("local budget for alias %s is used up", alias))
+		return
+	}
+
+	key, err := p.keys.Get(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Proxy failed to load key for alias %s: %v\n", alias, err)
+		writeError(w, http.StatusInternalServerError, "key for alias is unavailable")
+		return
+	}
+
+	r.Header.Set("Authorization", "Bearer "+key)
+	p.reverse.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), aliasContextKey{}, alias)))
+}
+
+// account arranges for the response's usage to be recorded once the body
+// has been passed on to the caller. Usage comes from the body, or from the
+// headers if the body doesn't report it.
+func (p *Proxy) account(resp *http.Response) error {
+	if p.ledger == nil {
+		return nil
+	}
+
+	alias, _ := resp.Request.Context().Value(aliasContextKey{}).(string)
+	status := resp.StatusCode
+	headerUsage := usageFromHeaders(resp.Header)
+	resp.Body = newAccountingBody(resp.Body, resp.Header.Get("Content-Type"), func(summary responseSummary) {
+		if summary.Usage == nil {
+			summary.Usage = headerUsage
+		}
+		rec := Record{
+			Time:   time.Now().UTC(),
+			Alias:  alias,
+			Key:    p.aliases[alias],
+			Model:  summary.Model,
+			Status: status,
+		}
+		if summary.Usage != nil {
+			rec.PromptTokens = summary.Usage.PromptTokens
+			rec.CompletionTokens = summary.Usage.CompletionTokens
+			rec.TotalTokens = summary.Usage.TotalTokens
+			rec.Cost = summary.Usage.Cost
+		}
+		if err := p.ledger.Append(rec); err != nil {
+			fmt.Fprintf(os.Stderr, "Proxy failed to record usage for alias %s: %v\n", alias, err)
+		}
+	})
+	return nil
+}
+
+// rewrite points the outgoing request at the upstream API
+func (p *Proxy) rewrite(pr *httputil.ProxyRequest) {
+	pr.Out.URL.Scheme = p.target.Scheme
+	pr.Out.URL.Host = p.target.Host
+	pr.Out.Host = p.target.Host
+
+	// Accept both "/chat/completions" and "/api/v1/chat/completions"
+	basePath := strings.TrimSuffix(p.target.Path, "/")
+	path := pr.In.URL.Path
+	if !strings.HasPrefix(path, basePath+"/") {
+		path = basePath + path
+	}
+	pr.Out.URL.Path = path
+	pr.Out.URL.RawPath = ""
+
+	// Let the transport negotiate compression and decompress the response,
+	// so usage can be read from the body whatever the caller accepts
+	pr.Out.Header.Del("Accept-Encoding")
+}
+
+// bearerToken returns the token from an "Authorization: Bearer" header
+func bearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
+
+// writeError replies with an OpenAI-style JSON error
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"code":    status,
+		},
+	})
+}
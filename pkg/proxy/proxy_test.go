@@ -0,0 +1,345 @@
+package proxy
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+// staticKeys is a KeySource backed by a map
+type staticKeys map[string]string
+
+func (k staticKeys) Get(name string) (string, error) {
+	value, ok := k[name]
+	if !ok {
+		return "", fmt.Errorf("secret %s not found", name)
+	}
+	return value, nil
+}
+
+func TestProxySwapsAliasForKey(t *testing.T) {
+	var gotAuth, gotPath string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotPath = r.URL.Path
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+	defer upstream.Close()
+
+	keys := staticKeys{"chatbot-key": "sk-or-v1-real"}
+	p, err := New(upstream.URL+"/api/v1", map[string]string{"chatbot": "chatbot-key"}, keys)
+	if err != nil {
+		t.Fatalf("Failed to create proxy: %v", err)
+	}
+	server := httptest.NewServer(p)
+	defer server.Close()
+
+	for _, path := range []string{"/chat/completions", "/api/v1/chat/completions"} {
+		req, _ := http.NewRequest("POST", server.URL+path, strings.NewReader(`{}`))
+		req.Header.Set("Authorization", "Bearer chatbot")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Got status %d for %s", resp.StatusCode, path)
+		}
+		if gotAuth != "Bearer sk-or-v1-real" {
+			t.Errorf("Upstream got wrong authorization: %q", gotAuth)
+		}
+		if gotPath != "/api/v1/chat/completions" {
+			t.Errorf("Upstream got wrong path for %s: %q", path, gotPath)
+		}
+	}
+
+	// A rotated key is used on the next request
+	keys["chatbot-key"] = "sk-or-v1-rotated"
+	req, _ := http.NewRequest("POST", server.URL+"/chat/completions", nil)
+	req.Header.Set("Authorization", "Bearer chatbot")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if gotAuth != "Bearer sk-or-v1-rotated" {
+		t.Errorf("Proxy did not pick up rotated key: %q", gotAuth)
+	}
+}
+
+func TestProxyRejectsUnknownAlias(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Request with unknown alias reached upstream")
+	}))
+	defer upstream.Close()
+
+	p, err := New(upstream.URL, map[string]string{"chatbot": "chatbot-key"}, staticKeys{})
+	if err != nil {
+		t.Fatalf("Failed to create proxy: %v", err)
+	}
+	server := httptest.NewServer(p)
+	defer server.Close()
+
+	for _, auth := range []string{"", "Bearer other", "Bearer chatbot-key"} {
+		req, _ := http.NewRequest("POST", server.URL+"/chat/completions", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Got status %d for %q, want %d", resp.StatusCode, auth, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestProxyStreamsEvents(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: first\n\n")
+		w.(http.Flusher).Flush()
+		<-release
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer upstream.Close()
+
+	p, err := New(upstream.URL, map[string]string{"chatbot": "chatbot-key"}, staticKeys{"chatbot-key": "sk"})
+	if err != nil {
+		t.Fatalf("Failed to create proxy: %v", err)
+	}
+	server := httptest.NewServer(p)
+	defer server.Close()
+
+	req, _ := http.NewRequest("POST", server.URL+"/chat/completions", nil)
+	req.Header.Set("Authorization", "Bearer chatbot")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// The first event must arrive while upstream is still holding the stream open
+	lines := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(resp.Body).ReadString('\n')
+		lines <- line
+	}()
+	select {
+	case line := <-lines:
+		if line != "data: first\n" {
+			t.Errorf("Got wrong first event: %q", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Streamed event was buffered by the proxy")
+	}
+	close(release)
+}
+
+func TestNewRejectsProvisioningKeyAlias(t *testing.T) {
+	if _, err := New("https://openrouter.ai/api/v1", map[string]string{"x": vault.MainProvisioningKeyName}, staticKeys{}); err == nil {
+		t.Error("Aliasing the main provisioning key should fail")
+	}
+	if _, err := New("not a url", nil, staticKeys{}); err == nil {
+		t.Error("Invalid base URL should fail")
+	}
+}
+
+func TestProxyAccountingAndBudgets(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/headers" {
+			w.Header().Set(headerPromptTokens, "1")
+			w.Header().Set(headerCompletionTokens, "2")
+			w.Header().Set(headerLiteLLMCost, "0.125")
+			fmt.Fprint(w, "not json")
+			return
+		}
+		if r.URL.Path == "/stream" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"model\":\"m1\",\"choices\":[]}\n\n")
+			fmt.Fprint(w, "data: {\"model\":\"m1\",\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":4,\"total_tokens\":7,\"cost\":0.25}}\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"model":"m2","usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15,"cost":0.5}}`)
+	}))
+	defer upstream.Close()
+
+	ledger, err := OpenLedger(t.TempDir() + "/usage.ledger")
+	if err != nil {
+		t.Fatalf("Failed to open ledger: %v", err)
+	}
+
+	p, err := New(upstream.URL, map[string]string{"chatbot": "chatbot-key", "indexer": "indexer-key"},
+		staticKeys{"chatbot-key": "sk-1", "indexer-key": "sk-2"})
+	if err != nil {
+		t.Fatalf("Failed to create proxy: %v", err)
+	}
+	p.SetAccounting(ledger, map[string]Budget{"chatbot": {Cost: 0.7}})
+	server := httptest.NewServer(p)
+	defer server.Close()
+
+	send := func(alias, path string) int {
+		req, _ := http.NewRequest("POST", server.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer "+alias)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := send("chatbot", "/chat/completions"); status != http.StatusOK {
+		t.Fatalf("Got status %d", status)
+	}
+	if status := send("chatbot", "/stream"); status != http.StatusOK {
+		t.Fatalf("Got status %d", status)
+	}
+	if status := send("indexer", "/chat/completions"); status != http.StatusOK {
+		t.Fatalf("Got status %d", status)
+	}
+	if status := send("indexer", "/headers"); status != http.StatusOK {
+		t.Fatalf("Got status %d", status)
+	}
+
+	totals := ledger.Totals(PeriodTotal, time.Now())
+	chatbot := totals["chatbot"]
+	if chatbot == nil || chatbot.Requests != 2 || chatbot.TotalTokens != 22 || chatbot.Cost != 0.75 || chatbot.Key != "chatbot-key" {
+		t.Errorf("Got wrong totals for chatbot: %+v", chatbot)
+	}
+	// Usage reported only in headers is counted too
+	if indexer := totals["indexer"]; indexer == nil || indexer.Requests != 2 || indexer.TotalTokens != 18 || indexer.Cost != 0.625 {
+		t.Errorf("Got wrong totals for indexer: %+v", indexer)
+	}
+
+	// chatbot has spent 0.75 of its 0.70 budget; indexer has no budget
+	if status := send("chatbot", "/chat/completions"); status != http.StatusTooManyRequests {
+		t.Errorf("Request over budget got status %d, want %d", status, http.StatusTooManyRequests)
+	}
+	if status := send("indexer", "/chat/completions"); status != http.StatusOK {
+		t.Errorf("Request without budget got status %d", status)
+	}
+
+	// Records survive reopening the ledger
+	reopened, err := OpenLedger(ledger.path)
+	if err != nil {
+		t.Fatalf("Failed to reopen ledger: %v", err)
+	}
+	if got := reopened.Totals(PeriodTotal, time.Now())["chatbot"]; got == nil || got.Requests != 2 {
+		t.Errorf("Reopened ledger has wrong totals: %+v", got)
+	}
+}
+
+func TestProxyAccountsCompressedResponses(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body := `{"model":"m1","usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15,"cost":0.5}}`
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			fmt.Fprint(w, body)
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		fmt.Fprint(gz, body)
+		gz.Close()
+	}))
+	defer upstream.Close()
+
+	ledger, err := OpenLedger(t.TempDir() + "/usage.ledger")
+	if err != nil {
+		t.Fatalf("Failed to open ledger: %v", err)
+	}
+	p, err := New(upstream.URL, map[string]string{"chatbot": "chatbot-key"}, staticKeys{"chatbot-key": "sk-1"})
+	if err != nil {
+		t.Fatalf("Failed to create proxy: %v", err)
+	}
+	p.SetAccounting(ledger, nil)
+	server := httptest.NewServer(p)
+	defer server.Close()
+
+	// Like the OpenAI SDKs, the caller accepts gzip
+	req, _ := http.NewRequest("POST", server.URL+"/chat/completions", nil)
+	req.Header.Set("Authorization", "Bearer chatbot")
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), `"total_tokens":15`) {
+		t.Errorf("Caller should get a readable body, got %q", body)
+	}
+
+	if got := ledger.Totals(PeriodTotal, time.Now())["chatbot"]; got == nil || got.TotalTokens != 15 || got.Cost != 0.5 {
+		t.Errorf("Compressed response was not accounted: %+v", got)
+	}
+}
+
+func TestBudgetPeriods(t *testing.T) {
+	now := time.Date(2026, 3, 15, 13, 30, 0, 0, time.UTC)
+
+	if since := (Budget{Period: PeriodDaily}).Since(now); !since.Equal(time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Got wrong daily start: %v", since)
+	}
+	if since := (Budget{Period: PeriodMonthly}).Since(now); !since.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Got wrong monthly start: %v", since)
+	}
+	if since := (Budget{}).Since(now); !since.IsZero() {
+		t.Errorf("Total budgets should count everything: %v", since)
+	}
+}
+
+func TestLedgerPeriods(t *testing.T) {
+	ledger, err := OpenLedger(t.TempDir() + "/usage.ledger")
+	if err != nil {
+		t.Fatalf("Failed to open ledger: %v", err)
+	}
+
+	day1 := time.Date(2026, 3, 15, 23, 0, 0, 0, time.UTC)
+	day2 := day1.Add(2 * time.Hour)
+	for _, at := range []time.Time{day1, day1, day2} {
+		if err := ledger.Append(Record{Time: at, Alias: "chatbot", Cost: 1}); err != nil {
+			t.Fatalf("Failed to append record: %v", err)
+		}
+	}
+
+	daily := Budget{Cost: 2, Period: PeriodDaily}
+	monthly := Budget{Cost: 2, Period: PeriodMonthly}
+	if ledger.Exceeded("chatbot", daily, day2) {
+		t.Error("Only one record falls on the second day")
+	}
+	if !ledger.Exceeded("chatbot", monthly, day2) {
+		t.Error("All three records fall in the month")
+	}
+	if got := ledger.Totals(PeriodDaily, day2.AddDate(0, 0, 1))["chatbot"]; got != nil {
+		t.Errorf("A day without records should have no totals, got %+v", got)
+	}
+	if got := ledger.Totals(PeriodTotal, day2)["chatbot"]; got == nil || got.Requests != 3 {
+		t.Errorf("Got wrong total: %+v", got)
+	}
+
+	// Reopening rebuilds the same totals from the file
+	reopened, err := OpenLedger(ledger.path)
+	if err != nil {
+		t.Fatalf("Failed to reopen ledger: %v", err)
+	}
+	if got := reopened.Totals(PeriodDaily, day2)["chatbot"]; got == nil || got.Requests != 1 {
+		t.Errorf("Reopened ledger has wrong daily totals: %+v", got)
+	}
+}
@@ -0,0 +1,181 @@
+// Package redact scrubs known secret values from text streams
+package redact
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/url"
+	"sort"
+)
+
+// MinValueLength is the shortest value that will be redacted. Shorter values
+// would match ordinary text far too often to be useful.
+const MinValueLength = 6
+
+// pattern is a byte sequence to replace and what to replace it with
+type pattern struct {
+	needle      []byte
+	replacement []byte
+}
+
+// Redactor replaces secret values, and their common encodings, with markers
+type Redactor struct {
+	patterns []pattern
+	// byFirst indexes patterns by their first byte, longest first
+	byFirst map[byte][]int
+	maxLen  int
+	// lineSafe is true when no pattern spans a newline, so complete lines can
+	// be flushed without waiting for more input
+	lineSafe bool
+}
+
+// New creates a Redactor for the given secrets, keyed by name. Each value is
+// also matched in its base64 and URL-encoded forms.
+func New(secrets map[string]string) *Redactor {
+	r := &Redactor{
+		byFirst:  make(map[byte][]int),
+		lineSafe: true,
+	}
+
+	// Visit names in order so overlapping values are resolved deterministically
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	seen := make(map[string]bool)
+	for _, name := range names {
+		value := secrets[name]
+		if len(value) < MinValueLength {
+			continue
+		}
+		replacement := []byte("[REDACTED:" + name + "]")
+		for _, variant := range encodings(value) {
+			if seen[variant] {
+				continue
+			}
+			seen[variant] = true
+			r.patterns = append(r.patterns, pattern{needle: []byte(variant), replacement: replacement})
+		}
+	}
+
+	// Longest first, so a value wins over any shorter value it contains
+	sort.SliceStable(r.patterns, func(i, j int) bool {
+		return len(r.patterns[i].needle) > len(r.patterns[j].needle)
+	})
+
+	for i, p := range r.patterns {
+		r.byFirst[p.needle[0]] = append(r.byFirst[p.needle[0]], i)
+		if len(p.needle) > r.maxLen {
+			r.maxLen = len(p.needle)
+		}
+		if bytes.IndexByte(p.needle, '\n') >= 0 {
+			r.lineSafe = false
+		}
+	}
+
+	return r
+}
+
+// encodings returns the value along with the encoded forms it commonly leaks in
+func encodings(value string) []string {
+	raw := []byte(value)
+	return []string{
+		value,
+		base64.StdEncoding.EncodeToString(raw),
+		base64.RawStdEncoding.EncodeToString(raw),
+		base64.URLEncoding.EncodeToString(raw),
+		base64.RawURLEncoding.EncodeToString(raw),
+		url.QueryEscape(value),
+		url.PathEscape(value),
+	}
+}
+
+// String redacts a complete piece of text
+func (r *Redactor) String(s string) string {
+	out, _ := r.redact([]byte(s), true)
+	return string(out)
+}
+
+// redact replaces matches in buf. Unless final is set, it stops where a match
+// could still be completed by later input and returns how much was consumed.
+func (r *Redactor) redact(buf []byte, final bool) ([]byte, int) {
+	limit := len(buf)
+	if !final {
+		limit = len(buf) - r.maxLen + 1
+		if r.lineSafe {
+			if nl := bytes.LastIndexByte(buf, '\n'); nl+1 > limit {
+				limit = nl + 1
+			}
+		}
+		if limit > len(buf) {
+			limit = len(buf)
+		}
+	}
+
+	out := make([]byte, 0, len(buf))
+	i := 0
+	for i < limit {
+		if p, ok := r.matchAt(buf, i); ok {
+			out = append(out, p.replacement...)
+			i += len(p.needle)
+			continue
+		}
+		out = append(out, buf[i])
+		i++
+	}
+
+	return out, i
+}
+
+// matchAt returns the longest pattern that starts at position i of buf
+func (r *Redactor) matchAt(buf []byte, i int) (pattern, bool) {
+	for _, idx := range r.byFirst[buf[i]] {
+		p := r.patterns[idx]
+		if bytes.HasPrefix(buf[i:], p.needle) {
+			return p, true
+		}
+	}
+	return pattern{}, false
+}
+
+// Writer redacts everything written to it before passing it on. It holds back
+// just enough trailing input to catch values split across writes, so Close
+// must be called to flush the remainder.
+type Writer struct {
+	r   *Redactor
+	w   io.Writer
+	buf []byte
+}
+
+// NewWriter returns a Writer that redacts into w
+func (r *Redactor) NewWriter(w io.Writer) *Writer {
+	return &Writer{r: r, w: w}
+}
+
+// Write redacts p and writes out everything that can no longer be part of a match
+func (w *Writer) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	if err := w.flush(false); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close flushes any held-back input. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	return w.flush(true)
+}
+
+// flush writes the redacted prefix of the buffer and keeps the rest
+func (w *Writer) flush(final bool) error {
+	out, consumed := w.r.redact(w.buf, final)
+	w.buf = append(w.buf[:0], w.buf[consumed:]...)
+	if len(out) == 0 {
+		return nil
+	}
+	_, err := w.w.Write(out)
+	return err
+}
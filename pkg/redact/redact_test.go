@@ -0,0 +1,120 @@
+package redact
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRedactString(t *testing.T) {
+	r := New(map[string]string{
+		"api-key": "sk-or-v1-abcdef123456",
+		"db":      "postgres://user:p@ss w0rd@host/db",
+		"short":   "abc",
+	})
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "Plain value",
+			input: "key=sk-or-v1-abcdef123456 done",
+			want:  "key=[REDACTED:api-key] done",
+		},
+		{
+			name:  "Repeated value",
+			input: "sk-or-v1-abcdef123456sk-or-v1-abcdef123456",
+			want:  "[REDACTED:api-key][REDACTED:api-key]",
+		},
+		{
+			name:  "Base64 encoded",
+			input: "auth: " + base64.StdEncoding.EncodeToString([]byte("sk-or-v1-abcdef123456")),
+			want:  "auth: [REDACTED:api-key]",
+		},
+		{
+			name:  "URL encoded",
+			input: "?dsn=" + url.QueryEscape("postgres://user:p@ss w0rd@host/db"),
+			want:  "?dsn=[REDACTED:db]",
+		},
+		{
+			name:  "Short values are ignored",
+			input: "abc abc",
+			want:  "abc abc",
+		},
+		{
+			name:  "No secrets",
+			input: "nothing to see here",
+			want:  "nothing to see here",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.String(tt.input); got != tt.want {
+				t.Errorf("Got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriterAcrossChunkBoundaries(t *testing.T) {
+	r := New(map[string]string{"api-key": "sk-or-v1-abcdef123456"})
+	input := "first sk-or-v1-abcdef123456 second sk-or-v1-abcdef123456 end"
+
+	// Write one byte at a time so every value is split across writes
+	var out bytes.Buffer
+	w := r.NewWriter(&out)
+	for i := 0; i < len(input); i++ {
+		if _, err := w.Write([]byte{input[i]}); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+
+	want := "first [REDACTED:api-key] second [REDACTED:api-key] end"
+	if out.String() != want {
+		t.Errorf("Got %q, want %q", out.String(), want)
+	}
+}
+
+func TestWriterFlushesCompleteLines(t *testing.T) {
+	r := New(map[string]string{"api-key": "sk-or-v1-abcdef123456"})
+
+	var out bytes.Buffer
+	w := r.NewWriter(&out)
+	if _, err := w.Write([]byte("hi\n")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+
+	// A complete line can't hold part of a value, so it shouldn't wait for more input
+	if out.String() != "hi\n" {
+		t.Errorf("Complete line was held back: got %q", out.String())
+	}
+
+	if _, err := w.Write([]byte("sk-or-v1-abc")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	if strings.Contains(out.String(), "sk-or") {
+		t.Errorf("Partial value was written before it could be matched: %q", out.String())
+	}
+}
+
+func TestWriterWithoutSecrets(t *testing.T) {
+	var out bytes.Buffer
+	w := New(nil).NewWriter(&out)
+	if _, err := w.Write([]byte("plain text")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+	if out.String() != "plain text" {
+		t.Errorf("Got %q, want %q", out.String(), "plain text")
+	}
+}
@@ -0,0 +1,141 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// hookMarker identifies a pre-commit hook written by InstallHook
+const hookMarker = "# Installed by lean_vault"
+
+// hookScript is the pre-commit hook installed by InstallHook
+const hookScript = `#!/bin/sh
+` + hookMarker + `: block commits that contain values stored in the vault
+exec lean_vault scan --staged
+`
+
+// ScanGitHistory scans every line added in any commit reachable from any ref
+func (s *Scanner) ScanGitHistory(repo string) ([]Finding, error) {
+	return s.scanGit(repo, "log", "-p", "--all", "--no-color", "--no-ext-diff", "--unified=0", "--format=commit %H")
+}
+
+// ScanStaged scans the lines added in the changes staged for the next commit
+func (s *Scanner) ScanStaged(repo string) ([]Finding, error) {
+	return s.scanGit(repo, "diff", "--cached", "--no-color", "--no-ext-diff", "--unified=0")
+}
+
+// scanGit runs a git command producing a patch and scans the added lines
+func (s *Scanner) scanGit(repo string, args ...string) ([]Finding, error) {
+	cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run git: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to run git: %w", err)
+	}
+
+	findings, scanErr := s.ScanPatch(stdout)
+	if scanErr != nil {
+		// Drain so git isn't blocked writing to a pipe nobody reads
+		io.Copy(io.Discard, stdout)
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+
+	return findings, scanErr
+}
+
+// ScanPatch scans the added lines of unified diff output, as produced by
+// "git log -p" or "git diff". Lines starting with "commit " set the commit
+// that later findings are attributed to.
+func (s *Scanner) ScanPatch(r io.Reader) ([]Finding, error) {
+	var findings []Finding
+
+	lines := bufio.NewScanner(r)
+	lines.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	var commit, path string
+	lineNum := 0
+	afterOldPath := false
+	for lines.Scan() {
+		line := lines.Bytes()
+		// "+++ " only names a file straight after the "--- " line; anywhere
+		// else it is an added line that happens to start with "++"
+		isNewPath := afterOldPath && bytes.HasPrefix(line, []byte("+++ "))
+		afterOldPath = bytes.HasPrefix(line, []byte("--- "))
+		switch {
+		case bytes.HasPrefix(line, []byte("commit ")):
+			commit = string(bytes.TrimPrefix(line, []byte("commit ")))
+			path = ""
+		case isNewPath:
+			path = strings.TrimPrefix(string(line[4:]), "b/")
+		case bytes.HasPrefix(line, []byte("@@ ")):
+			lineNum = hunkStart(string(line))
+		case bytes.HasPrefix(line, []byte("+")) && path != "":
+			for _, name := range s.scanLine(line[1:]) {
+				findings = append(findings, Finding{Path: path, Line: lineNum, Name: name, Commit: commit})
+			}
+			lineNum++
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return findings, fmt.Errorf("failed to read patch: %w", err)
+	}
+
+	return findings, nil
+}
+
+// hunkStart returns the first new-file line number from a hunk header
+// such as "@@ -10,2 +12,3 @@"
+func hunkStart(header string) int {
+	for _, field := range strings.Fields(header) {
+		if !strings.HasPrefix(field, "+") {
+			continue
+		}
+		start, _, _ := strings.Cut(field[1:], ",")
+		n, err := strconv.Atoi(start)
+		if err != nil {
+			return 0
+		}
+		return n
+	}
+	return 0
+}
+
+// InstallHook installs a pre-commit hook in the repository that runs
+// "lean_vault scan --staged". An existing hook that lean_vault didn't
+// write is left alone.
+func InstallHook(repo string) (string, error) {
+	out, err := exec.Command("git", "-C", repo, "rev-parse", "--git-path", "hooks").Output()
+	if err != nil {
+		return "", fmt.Errorf("not a git repository: %s", repo)
+	}
+	hooksDir := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(hooksDir) {
+		hooksDir = filepath.Join(repo, hooksDir)
+	}
+
+	hookPath := filepath.Join(hooksDir, "pre-commit")
+	if existing, err := os.ReadFile(hookPath); err == nil && !strings.Contains(string(existing), hookMarker) {
+		return "", fmt.Errorf("a pre-commit hook already exists at %s", hookPath)
+	}
+
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+	if err := os.WriteFile(hookPath, []byte(hookScript), 0755); err != nil {
+		return "", fmt.Errorf("failed to write hook: %w", err)
+	}
+
+	return hookPath, nil
+}
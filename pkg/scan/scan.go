@@ -0,0 +1,215 @@
+// Package scan finds values stored in the vault in files and git history.
+// It only ever holds hashes of the values it looks for.
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spacebarlabs/lean_vault/pkg/redact"
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+const (
+	// maxFileSize is the largest file that will be scanned
+	maxFileSize = 10 << 20
+	// maxLineSize is the longest line the line reader will accept
+	maxLineSize = 1 << 20
+	// binarySniffSize is how much of a file is checked for NUL bytes
+	binarySniffSize = 8000
+)
+
+// Finding records where a stored value was found
+type Finding struct {
+	Path string
+	Line int
+	Name string
+	// Commit is set for findings in git history
+	Commit string
+}
+
+// Scanner matches text against fingerprints of stored values
+type Scanner struct {
+	// names maps a value hash to the secret names it was stored under
+	names map[string][]string
+	// windows lists the distinct value lengths in increasing order, so only
+	// windows of those sizes need to be hashed
+	windows []*window
+}
+
+// window holds what is known about the values of one length
+type window struct {
+	length int
+	// pow is RollingHashBase to the power of length-1, for sliding the
+	// rolling hash
+	pow uint32
+	// rolling holds the rolling hashes of the values, so most positions are
+	// ruled out without computing a SHA-256 hash
+	rolling map[uint32]bool
+	// unfiltered is set when a value has no rolling hash, so every position
+	// must be hashed
+	unfiltered bool
+}
+
+// New creates a Scanner for the given fingerprints. Values shorter than
+// redact.MinValueLength are ignored, since they would match ordinary text.
+func New(fingerprints []vault.Fingerprint) *Scanner {
+	s := &Scanner{names: make(map[string][]string)}
+
+	byLength := make(map[int]*window)
+	for _, fp := range fingerprints {
+		if fp.Length < redact.MinValueLength {
+			continue
+		}
+		if !contains(s.names[fp.Hash], fp.Name) {
+			s.names[fp.Hash] = append(s.names[fp.Hash], fp.Name)
+		}
+
+		w := byLength[fp.Length]
+		if w == nil {
+			w = &window{length: fp.Length, pow: 1, rolling: make(map[uint32]bool)}
+			for i := 1; i < fp.Length; i++ {
+				w.pow *= vault.RollingHashBase
+			}
+			byLength[fp.Length] = w
+			s.windows = append(s.windows, w)
+		}
+		if fp.Rolling == 0 {
+			w.unfiltered = true
+		}
+		w.rolling[fp.Rolling] = true
+	}
+	sort.Slice(s.windows, func(i, j int) bool {
+		return s.windows[i].length < s.windows[j].length
+	})
+
+	return s
+}
+
+// scanLine returns the names of any stored values that appear in line
+func (s *Scanner) scanLine(line []byte) []string {
+	var found []string
+	for _, w := range s.windows {
+		if w.length > len(line) {
+			break
+		}
+
+		rolling := vault.RollingHash(line[:w.length])
+		for offset := 0; ; offset++ {
+			if w.unfiltered || w.rolling[rolling] {
+				for _, name := range s.names[vault.HashValue(line[offset:offset+w.length])] {
+					if !contains(found, name) {
+						found = append(found, name)
+					}
+				}
+			}
+
+			next := offset + w.length
+			if next >= len(line) {
+				break
+			}
+			rolling = (rolling-uint32(line[offset])*w.pow)*vault.RollingHashBase + uint32(line[next])
+		}
+	}
+	return found
+}
+
+// ScanReader scans r line by line, reporting findings against path
+func (s *Scanner) ScanReader(path string, r io.Reader) ([]Finding, error) {
+	var findings []Finding
+
+	lines := bufio.NewScanner(r)
+	lines.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	lineNum := 0
+	for lines.Scan() {
+		lineNum++
+		for _, name := range s.scanLine(lines.Bytes()) {
+			findings = append(findings, Finding{Path: path, Line: lineNum, Name: name})
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return findings, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return findings, nil
+}
+
+// ScanPath scans a file, or every file below a directory. Git metadata,
+// binary files and very large files are skipped. Files and directories that
+// can't be read are skipped too, and returned as warnings; only failing to
+// read root itself is an error.
+func (s *Scanner) ScanPath(root string) ([]Finding, []error, error) {
+	var findings []Finding
+	var warnings []error
+
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			warnings = append(warnings, err)
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		fileFindings, err := s.scanFile(path)
+		if err != nil {
+			if path == root {
+				return err
+			}
+			warnings = append(warnings, err)
+		}
+		findings = append(findings, fileFindings...)
+		return nil
+	})
+
+	return findings, warnings, err
+}
+
+// scanFile scans a single regular file unless it looks binary or is too large
+func (s *Scanner) scanFile(path string) ([]Finding, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxFileSize {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sniff := data
+	if len(sniff) > binarySniffSize {
+		sniff = sniff[:binarySniffSize]
+	}
+	if bytes.IndexByte(sniff, 0) >= 0 {
+		return nil, nil
+	}
+
+	return s.ScanReader(path, bytes.NewReader(data))
+}
+
+// contains reports whether list includes name
+func contains(list []string, name string) bool {
+	for _, item := range list {
+		if item == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package scan
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spacebarlabs/lean_vault/pkg/vault"
+)
+
+func testFingerprints(values map[string]string) []vault.Fingerprint {
+	var fingerprints []vault.Fingerprint
+	for name, value := range values {
+		fingerprints = append(fingerprints, vault.Fingerprint{
+			Name:    name,
+			Hash:    vault.HashValue([]byte(value)),
+			Length:  len(value),
+			Rolling: vault.RollingHash([]byte(value)),
+		})
+	}
+	return fingerprints
+}
+
+func TestScanReader(t *testing.T) {
+	s := New(testFingerprints(map[string]string{
+		"api-key": "sk-or-v1-abcdef",
+		"db":      "postgres://secret",
+	}))
+
+	input := "first line\nexport KEY=sk-or-v1-abcdef\nurl: postgres://secret # and sk-or-v1-abcdef\n"
+	findings, err := s.ScanReader("config.yml", strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to scan: %v", err)
+	}
+
+	if len(findings) != 3 {
+		t.Fatalf("Expected 3 findings, got %d: %v", len(findings), findings)
+	}
+	if findings[0].Line != 2 || findings[0].Name != "api-key" || findings[0].Path != "config.yml" {
+		t.Errorf("Unexpected first finding: %+v", findings[0])
+	}
+	for _, f := range findings[1:] {
+		if f.Line != 3 {
+			t.Errorf("Finding reported on wrong line: %+v", f)
+		}
+	}
+}
+
+func TestScanPath(t *testing.T) {
+	s := New(testFingerprints(map[string]string{"api-key": "sk-or-v1-abcdef"}))
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"clean.txt":       "nothing here\n",
+		"sub/leak.env":    "KEY=sk-or-v1-abcdef\n",
+		".git/config":     "sk-or-v1-abcdef\n",
+		"binary.bin":      "\x00sk-or-v1-abcdef\n",
+		"sub/another.txt": "line\nline\nsk-or-v1-abcdef",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	findings, warnings, err := s.ScanPath(dir)
+	if err != nil || len(warnings) != 0 {
+		t.Fatalf("Failed to scan: %v %v", err, warnings)
+	}
+
+	found := make(map[string]int)
+	for _, f := range findings {
+		rel, _ := filepath.Rel(dir, f.Path)
+		found[rel] = f.Line
+	}
+	if len(found) != 2 || found[filepath.Join("sub", "leak.env")] != 1 || found[filepath.Join("sub", "another.txt")] != 3 {
+		t.Errorf("Unexpected findings: %v", found)
+	}
+}
+
+func TestScannerMatching(t *testing.T) {
+	values := map[string]string{"api-key": "sk-or-v1-abcdef", "pin": "1234"}
+	line := []byte("pin 1234 and key sk-or-v1-abcdef at the end sk-or-v1-abcdef")
+
+	// Values without a rolling hash, recorded before it was kept, are still
+	// found by hashing every position
+	unfiltered := testFingerprints(values)
+	for i := range unfiltered {
+		unfiltered[i].Rolling = 0
+	}
+
+	for name, fingerprints := range map[string][]vault.Fingerprint{
+		"rolling":    testFingerprints(values),
+		"unfiltered": unfiltered,
+	} {
+		found := New(fingerprints).scanLine(line)
+		if len(found) != 1 || found[0] != "api-key" {
+			t.Errorf("%s: expected only api-key, since short values are ignored, got %v", name, found)
+		}
+	}
+}
+
+func TestScanPathSkipsUnreadable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+	s := New(testFingerprints(map[string]string{"api-key": "sk-or-v1-abcdef"}))
+
+	dir := t.TempDir()
+	for _, name := range []string{"a-locked.env", "b-leak.env"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("KEY=sk-or-v1-abcdef\n"), 0600); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	if err := os.Chmod(filepath.Join(dir, "a-locked.env"), 0); err != nil {
+		t.Fatalf("Failed to chmod file: %v", err)
+	}
+
+	findings, warnings, err := s.ScanPath(dir)
+	if err != nil {
+		t.Fatalf("Failed to scan: %v", err)
+	}
+	if len(warnings) != 1 || len(findings) != 1 || filepath.Base(findings[0].Path) != "b-leak.env" {
+		t.Errorf("Expected the locked file as a warning and the other scanned, got %v %v", findings, warnings)
+	}
+}
+
+func TestScanPatch(t *testing.T) {
+	s := New(testFingerprints(map[string]string{"api-key": "sk-or-v1-abcdef"}))
+
+	patch := `commit 1111111111111111111111111111111111111111
+diff --git a/app.rb b/app.rb
+--- a/app.rb
++++ b/app.rb
+@@ -3,0 +4,2 @@
++puts "hello"
++KEY = "sk-or-v1-abcdef"
+commit 2222222222222222222222222222222222222222
+diff --git a/app.rb b/app.rb
+--- a/app.rb
++++ b/app.rb
+@@ -5 +5 @@
+-KEY = "sk-or-v1-abcdef"
++KEY = ENV["KEY"]
+`
+	findings, err := s.ScanPatch(strings.NewReader(patch))
+	if err != nil {
+		t.Fatalf("Failed to scan patch: %v", err)
+	}
+
+	// Removed lines don't count; only the commit that added the value does
+	if len(findings) != 1 {
+		t.Fatalf("Expected 1 finding, got %d: %v", len(findings), findings)
+	}
+	f := findings[0]
+	if f.Path != "app.rb" || f.Line != 5 || f.Commit != "1111111111111111111111111111111111111111" || f.Name != "api-key" {
+		t.Errorf("Unexpected finding: %+v", f)
+	}
+}
@@ -0,0 +1,223 @@
+package vault
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spacebarlabs/lean_vault/pkg/crypto"
+)
+
+// DefaultAccount is the account whose provisioning key is entered at init
+const DefaultAccount = "default"
+
+// accountsNamespace holds the provisioning keys of named accounts
+const accountsNamespace = SystemNamespace + "accounts/"
+
+// Account is a provider account with its own provisioning key
+type Account struct {
+	Name    string
+	AddedAt time.Time
+	// Keys counts the stored secrets provisioned through the account
+	Keys int
+}
+
+// ValidateAccountName checks the name of a new account
+func ValidateAccountName(name string) error {
+	if name == "" || name == "." || name == ".." {
+		return fmt.Errorf("invalid account name %q", name)
+	}
+	for _, r := range name {
+		if !isNameRune(r) {
+			return fmt.Errorf("invalid account name %q: use letters, digits, '.', '-' and '_'", name)
+		}
+	}
+	return nil
+}
+
+// AccountKeyName returns where an account's provisioning key is stored. The
+// default account uses MainProvisioningKeyName so existing vaults keep working.
+func AccountKeyName(account string) string {
+	if account == "" || account == DefaultAccount {
+		return MainProvisioningKeyName
+	}
+	return accountsNamespace + account + "/provisioning-key"
+}
+
+// accountFromKeyName is the inverse of AccountKeyName
+func accountFromKeyName(name string) (string, bool) {
+	if name == MainProvisioningKeyName {
+		return DefaultAccount, true
+	}
+	account := strings.TrimSuffix(strings.TrimPrefix(name, accountsNamespace), "/provisioning-key")
+	if !strings.HasPrefix(name, accountsNamespace) || account == "" || strings.Contains(account, "/") ||
+		AccountKeyName(account) != name {
+		return "", false
+	}
+	return account, true
+}
+
+// AccountName returns the account that provisioned the entry
+func (e SecretEntry) AccountName() string {
+	if e.Account == "" {
+		return DefaultAccount
+	}
+	return e.Account
+}
+
+// GetProvisioningKey returns an account's provisioning key
+func (v *Vault) GetProvisioningKey(account string) (string, error) {
+	vaultData, masterKey, err := v.load()
+	if err != nil {
+		return "", err
+	}
+
+	entry, exists := vaultData.Secrets[AccountKeyName(account)]
+	if !exists {
+		return "", fmt.Errorf("account %s has no provisioning key", accountOrDefault(account))
+	}
+
+	plaintext, err := crypto.Decrypt(masterKey, entry.Value)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt provisioning key: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// SetProvisioningKey replaces an account's provisioning key, keeping the old
+// one in its history. Stored API keys are left alone.
+func (v *Vault) SetProvisioningKey(account, value, reason string) error {
+	if value == "" {
+		return fmt.Errorf("provisioning key cannot be empty")
+	}
+
+	vaultData, masterKey, err := v.load()
+	if err != nil {
+		return err
+	}
+	return v.storeProvisioningKey(vaultData, masterKey, account, value, reason)
+}
+
+// AddAccount stores the provisioning key for a new account
+func (v *Vault) AddAccount(name, provisioningKey string) error {
+	if err := ValidateAccountName(name); err != nil {
+		return err
+	}
+	if provisioningKey == "" {
+		return fmt.Errorf("provisioning key cannot be empty")
+	}
+
+	vaultData, masterKey, err := v.load()
+	if err != nil {
+		return err
+	}
+	if _, exists := vaultData.Secrets[AccountKeyName(name)]; exists {
+		return fmt.Errorf("account %s already exists", name)
+	}
+	return v.storeProvisioningKey(vaultData, masterKey, name, provisioningKey, "")
+}
+
+// storeProvisioningKey encrypts and saves an account's provisioning key
+func (v *Vault) storeProvisioningKey(vaultData *VaultData, masterKey []byte, account, value, reason string) error {
+	encryptedKey, err := crypto.Encrypt(masterKey, []byte(value))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt provisioning key: %w", err)
+	}
+
+	name := AccountKeyName(account)
+	now := time.Now().UTC()
+	entry, exists := vaultData.Secrets[name]
+	if exists {
+		entry.retire(reason, now)
+	} else {
+		entry = SecretEntry{Version: 1, CreatedAt: now}
+	}
+	entry.Value = encryptedKey
+	entry.UpdatedAt = now
+	vaultData.Secrets[name] = entry
+	recordFingerprint(vaultData, name, value)
+
+	return v.save(vaultData, masterKey)
+}
+
+// ListAccounts returns the accounts with a provisioning key, sorted by name
+func (v *Vault) ListAccounts() ([]Account, error) {
+	vaultData, _, err := v.load()
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*Account)
+	for name, entry := range vaultData.Secrets {
+		if account, ok := accountFromKeyName(name); ok {
+			byName[account] = &Account{Name: account, AddedAt: entry.CreatedAt}
+		}
+	}
+	for name, entry := range vaultData.Secrets {
+		if account := byName[entry.AccountName()]; account != nil && entry.IsProvisioned() && !IsSystemName(name) {
+			account.Keys++
+		}
+	}
+
+	accounts := make([]Account, 0, len(byName))
+	for _, account := range byName {
+		accounts = append(accounts, *account)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].Name < accounts[j].Name
+	})
+	return accounts, nil
+}
+
+// RemoveAccount deletes a named account's provisioning key. It refuses while
+// stored or trashed secrets still need the account to be revoked.
+func (v *Vault) RemoveAccount(name string) error {
+	if name == DefaultAccount {
+		return fmt.Errorf("the default account can't be removed; replace its key with 'provisioning-key set'")
+	}
+
+	vaultData, masterKey, err := v.load()
+	if err != nil {
+		return err
+	}
+	if _, exists := vaultData.Secrets[AccountKeyName(name)]; !exists {
+		return fmt.Errorf("account %s not found", name)
+	}
+
+	var users []string
+	for secretName, entry := range vaultData.Secrets {
+		if entry.Account == name {
+			users = append(users, secretName)
+		}
+	}
+	for _, trashed := range vaultData.Trash {
+		if trashed.Entry.Account == name && trashed.NeedsRevocation() {
+			users = append(users, trashed.Name+" (trash)")
+		}
+	}
+	if len(users) > 0 {
+		sort.Strings(users)
+		return fmt.Errorf("account %s is still used by %s", name, strings.Join(users, ", "))
+	}
+
+	delete(vaultData.Secrets, AccountKeyName(name))
+	return v.save(vaultData, masterKey)
+}
+
+// storedAccount is how an entry records account: empty for the default
+// account, so entries look the same as before accounts existed
+func storedAccount(account string) string {
+	if account == DefaultAccount {
+		return ""
+	}
+	return account
+}
+
+// accountOrDefault names the default account when account is empty
+func accountOrDefault(account string) string {
+	if account == "" {
+		return DefaultAccount
+	}
+	return account
+}
@@ -0,0 +1,54 @@
+package vault
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+// auditKeyLabel separates the audit signing key from other uses of the master key
+const auditKeyLabel = "lean_vault audit log"
+
+// deriveAuditKey derives the audit signing key and its ID from a master key
+func deriveAuditKey(masterKey []byte) (string, []byte) {
+	mac := hmac.New(sha256.New, masterKey)
+	mac.Write([]byte(auditKeyLabel))
+	key := mac.Sum(nil)
+
+	id := sha256.Sum256(key)
+	return hex.EncodeToString(id[:8]), key
+}
+
+// AuditKey returns the key used to sign audit log entries, derived from the
+// current master key, and an ID for it. Only the key file is read, so
+// signing doesn't require decrypting the vault.
+func (v *Vault) AuditKey() (string, []byte, error) {
+	masterKey, err := os.ReadFile(v.keyFile)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read master key: %w", err)
+	}
+
+	id, key := deriveAuditKey(masterKey)
+	return id, key, nil
+}
+
+// AuditKeys returns the audit signing keys for the current and every earlier
+// master key, by ID, so entries signed before a master key rotation can
+// still be verified
+func (v *Vault) AuditKeys() (map[string][]byte, error) {
+	vaultData, masterKey, err := v.load()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string][]byte)
+	id, key := deriveAuditKey(masterKey)
+	keys[id] = key
+	for _, version := range vaultData.KeyVersions {
+		id, key := deriveAuditKey(version.Key)
+		keys[id] = key
+	}
+	return keys, nil
+}
@@ -0,0 +1,107 @@
+package vault
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Cache keeps decrypted secrets in memory for long-running processes and
+// reloads them whenever the vault file changes, so rotations are picked up
+// without a restart
+type Cache struct {
+	vault *Vault
+
+	mu       sync.Mutex
+	secrets  map[string]string
+	entries  map[string]SecretEntry
+	modTime  time.Time
+	size     int64
+	lastUsed time.Time
+}
+
+// NewCache creates an empty cache for the vault
+func NewCache(v *Vault) *Cache {
+	return &Cache{vault: v}
+}
+
+// Get returns the decrypted value of a secret
+func (c *Cache) Get(name string) (string, error) {
+	secrets, _, err := c.Snapshot()
+	if err != nil {
+		return "", err
+	}
+	value, ok := secrets[CanonicalName(name)]
+	if !ok {
+		return "", fmt.Errorf("secret %s not found", name)
+	}
+	return value, nil
+}
+
+// Snapshot returns every decrypted value (including the system namespace)
+// and every entry (excluding it). Callers must not modify the maps.
+func (c *Cache) Snapshot() (map[string]string, map[string]SecretEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lastUsed = time.Now()
+
+	info, err := c.vault.Stat()
+	if err != nil {
+		c.clear()
+		return nil, nil, fmt.Errorf("failed to read vault: %w", err)
+	}
+	if c.secrets != nil && info.ModTime().Equal(c.modTime) && info.Size() == c.size {
+		return c.secrets, c.entries, nil
+	}
+
+	secrets, err := c.vault.GetAllSecrets()
+	if err != nil {
+		c.clear()
+		return nil, nil, err
+	}
+	entries, err := c.vault.ListSecretEntries()
+	if err != nil {
+		c.clear()
+		return nil, nil, err
+	}
+
+	c.secrets = secrets
+	c.entries = entries
+	c.modTime = info.ModTime()
+	c.size = info.Size()
+	return c.secrets, c.entries, nil
+}
+
+// ClearIfIdle drops decrypted secrets if they haven't been used for idle,
+// reporting whether anything was dropped
+func (c *Cache) ClearIfIdle(now time.Time, idle time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.secrets == nil || now.Sub(c.lastUsed) < idle {
+		return false
+	}
+	c.clear()
+	return true
+}
+
+// Clear drops decrypted secrets; the next lookup reloads them
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clear()
+}
+
+// Loaded reports whether decrypted secrets are currently held in memory
+func (c *Cache) Loaded() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.secrets != nil
+}
+
+// clear drops decrypted secrets; the caller must hold c.mu
+func (c *Cache) clear() {
+	c.secrets = nil
+	c.entries = nil
+}
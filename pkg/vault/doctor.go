@@ -0,0 +1,240 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/spacebarlabs/lean_vault/pkg/crypto"
+	"gopkg.in/yaml.v3"
+)
+
+// Diagnosis severities
+const (
+	SeverityOK      = "ok"
+	SeverityWarning = "warning"
+	SeverityProblem = "problem"
+)
+
+// Diagnosis is the result of one integrity check
+type Diagnosis struct {
+	Severity string
+	Message  string
+	// Fix suggests how to resolve a warning or problem
+	Fix string
+}
+
+// Diagnose checks the vault's files and contents step by step, so that a
+// vault that fails to load gets a specific explanation rather than a bare
+// decryption error. Checks that depend on an earlier failed step are skipped.
+func (v *Vault) Diagnose() []Diagnosis {
+	var results []Diagnosis
+	ok := func(format string, args ...interface{}) {
+		results = append(results, Diagnosis{Severity: SeverityOK, Message: fmt.Sprintf(format, args...)})
+	}
+	warn := func(fix, format string, args ...interface{}) {
+		results = append(results, Diagnosis{Severity: SeverityWarning, Message: fmt.Sprintf(format, args...), Fix: fix})
+	}
+	problem := func(fix, format string, args ...interface{}) {
+		results = append(results, Diagnosis{Severity: SeverityProblem, Message: fmt.Sprintf(format, args...), Fix: fix})
+	}
+
+	// Files and permissions
+	if !v.checkPath(v.vaultDir, DefaultDirMode, true, ok, problem) {
+		return results
+	}
+	keyOK := v.checkPath(v.keyFile, DefaultFileMode, false, ok, problem)
+	vaultOK := v.checkPath(v.vaultFile, DefaultFileMode, false, ok, problem)
+	if !keyOK || !vaultOK {
+		return results
+	}
+
+	// A project's key must stay out of its repository
+	if v.projectFile != "" {
+		root := filepath.Dir(v.projectFile)
+		if rel, err := filepath.Rel(root, v.keyFile); err == nil && !strings.HasPrefix(rel, "..") {
+			warn("Move the key out of the project and update key_file in "+v.projectFile,
+				"Master key %s is inside the project and could be committed", v.keyFile)
+		}
+	}
+
+	// Master key
+	masterKey, err := os.ReadFile(v.keyFile)
+	if err != nil {
+		problem("Check that you own "+v.keyFile, "Cannot read master key: %v", err)
+		return results
+	}
+	if len(masterKey) != crypto.KeySize {
+		problem("Restore the key file from a backup; the vault cannot be decrypted without the original key",
+			"Master key is %d bytes, expected %d (the key file was truncated or replaced)", len(masterKey), crypto.KeySize)
+		return results
+	}
+	ok("Master key has the expected size")
+
+	// Vault decryption and format
+	encrypted, err := os.ReadFile(v.vaultFile)
+	if err != nil {
+		problem("Check that you own "+v.vaultFile, "Cannot read vault file: %v", err)
+		return results
+	}
+	decrypted, err := crypto.Decrypt(masterKey, string(encrypted))
+	if err != nil {
+		problem("The key file does not match the vault file, or the vault file is corrupted. Restore the matching pair from a backup",
+			"Vault does not decrypt with the master key: %v", err)
+		return results
+	}
+	ok("Vault decrypts with the master key")
+
+	var vaultData VaultData
+	if err := yaml.Unmarshal(decrypted, &vaultData); err != nil {
+		problem("Restore the vault file from a backup", "Vault contents are not valid: %v", err)
+		return results
+	}
+	ok("Vault contents parse")
+	if err := migrateNames(&vaultData, masterKey); err != nil {
+		problem("Restore the vault from a backup, or remove one of the entries with the lean_vault version that wrote it",
+			"Provisioning key entries conflict: %v", err)
+		return results
+	}
+
+	// Key versions
+	current, exists := vaultData.KeyVersions[vaultData.CurrentKeyID]
+	switch {
+	case vaultData.CurrentKeyID == "":
+		warn("Run a master key rotation to start tracking key versions", "Vault has no current key version recorded")
+	case !exists:
+		problem("Rotate the master key to record a new current version",
+			"Current key version %s is missing from the key versions", vaultData.CurrentKeyID)
+	case !bytes.Equal(current.Key, masterKey):
+		problem("Rotate the master key to record a version matching the key file",
+			"Current key version %s does not match the key file", vaultData.CurrentKeyID)
+	default:
+		ok("Current key version %s matches the key file", vaultData.CurrentKeyID)
+	}
+
+	// Secrets
+	// A vault created with --no-provisioning-key only needs one once it
+	// holds provisioned keys
+	if _, exists := vaultData.Secrets[MainProvisioningKeyName]; !exists {
+		provisioned := 0
+		for name, entry := range vaultData.Secrets {
+			if entry.IsProvisioned() && entry.Account == "" && !IsSystemName(name) {
+				provisioned++
+			}
+		}
+		if provisioned > 0 {
+			problem("Store one with 'lean_vault provisioning-key set'",
+				"Main provisioning key is missing, so %d provisioned key(s) can't be rotated or revoked", provisioned)
+		} else {
+			ok("No main provisioning key is stored, and no keys need one")
+		}
+	}
+
+	names := make([]string, 0, len(vaultData.Secrets))
+	for name := range vaultData.Secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	undecryptable := 0
+	owners := make(map[string][]string)
+	for _, name := range names {
+		entry := vaultData.Secrets[name]
+		if _, err := crypto.Decrypt(masterKey, entry.Value); err != nil {
+			undecryptable++
+			problem(fmt.Sprintf("Roll back with 'lean_vault rollback %s --to <version>' or replace it with 'lean_vault rotate %s'", name, name),
+				"Secret '%s' does not decrypt: %v", name, err)
+		}
+		for _, past := range entry.History {
+			if _, err := crypto.Decrypt(masterKey, past.Value); err != nil {
+				undecryptable++
+				warn("The version can't be restored; it will age out of the history",
+					"Version %d of '%s' does not decrypt", past.Version, name)
+			}
+			if past.Version >= entry.CurrentVersion() {
+				warn("The history is inconsistent; rotate the secret to record a new version",
+					"Version %d of '%s' in its history is not older than the current version %d", past.Version, name, entry.CurrentVersion())
+			}
+		}
+		if p := entry.Previous; p != nil {
+			if p.RevokeAt == nil {
+				problem(fmt.Sprintf("Revoke key %s manually, then run 'lean_vault remove %s' and re-create it if needed", p.ID, name),
+					"Previous version of '%s' has no scheduled revocation and would never be revoked", name)
+			} else if _, err := crypto.Decrypt(masterKey, p.Value); err != nil {
+				undecryptable++
+				warn("'get --previous' will fail; the key is still revoked on schedule",
+					"Previous version of '%s' does not decrypt", name)
+			}
+			if p.ID != "" {
+				owners[p.ID] = append(owners[p.ID], name+" (previous)")
+			}
+		}
+		if entry.ID != "" {
+			owners[entry.ID] = append(owners[entry.ID], name)
+		}
+		if _, exists := vaultData.Secrets[AccountKeyName(entry.Account)]; entry.Account != "" && !exists {
+			problem(fmt.Sprintf("Add the account again with 'lean_vault account add %s'", entry.Account),
+				"Secret '%s' belongs to account %s, which has no provisioning key", name, entry.Account)
+		}
+	}
+	if undecryptable == 0 {
+		ok("All %d secrets decrypt", len(names))
+	}
+
+	// Provider IDs shared between entries mean revoking one breaks the other
+	ids := make([]string, 0, len(owners))
+	for id := range owners {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	duplicates := 0
+	for _, id := range ids {
+		if len(owners[id]) > 1 {
+			duplicates++
+			problem("Rotate all but one of them so each has its own key",
+				"Provider key %s is shared by %v; revoking one revokes them all", id, owners[id])
+		}
+	}
+	if duplicates == 0 {
+		ok("No provider key IDs are shared")
+	}
+
+	return results
+}
+
+// checkPath reports whether path exists with the expected type and
+// permissions no wider than mode
+func (v *Vault) checkPath(path string, mode os.FileMode, dir bool,
+	ok func(string, ...interface{}), problem func(string, string, ...interface{})) bool {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if dir {
+			problem("Run 'lean_vault init' to create a vault", "%s does not exist", path)
+		} else {
+			problem("Restore it from a backup, or remove "+v.vaultDir+" and run 'lean_vault init' to start over", "%s is missing", path)
+		}
+		return false
+	}
+	if err != nil {
+		problem("Check that you own "+path, "Cannot access %s: %v", path, err)
+		return false
+	}
+	if info.IsDir() != dir {
+		problem("Move it aside and restore the vault from a backup", "%s has the wrong file type", path)
+		return false
+	}
+
+	// Windows doesn't have Unix permission bits
+	if runtime.GOOS != "windows" && info.Mode().Perm()&^mode != 0 {
+		problem(fmt.Sprintf("Run: chmod %o %s", mode, path),
+			"%s has permissions %o, expected %o", path, info.Mode().Perm(), mode)
+		return true
+	}
+	ok("%s exists with permissions %o", path, info.Mode().Perm())
+	return true
+}
@@ -0,0 +1,38 @@
+package vault
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDuration parses a duration like time.ParseDuration, additionally
+// accepting a leading number of days such as "7d" or "1d12h"
+func ParseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	days := 0
+	rest := s
+	if i := strings.Index(s, "d"); i > 0 {
+		n, err := strconv.Atoi(s[:i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		days = n
+		rest = s[i+1:]
+	}
+
+	var d time.Duration
+	if rest != "" {
+		parsed, err := time.ParseDuration(rest)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		d = parsed
+	}
+
+	return time.Duration(days)*24*time.Hour + d, nil
+}
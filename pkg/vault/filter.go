@@ -0,0 +1,84 @@
+package vault
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Filter selects secrets by their metadata. The zero Filter matches everything.
+type Filter struct {
+	// Prefix is a namespace from NormalizePrefix, such as "prod/"
+	Prefix string
+	// Tags must all be present with these values
+	Tags  map[string]string
+	Owner string
+}
+
+// ParseTag parses a "key=value" tag
+func ParseTag(s string) (string, string, error) {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", "", fmt.Errorf("invalid tag %q: use key=value", s)
+	}
+	if err := ValidateTagKey(key); err != nil {
+		return "", "", err
+	}
+	return key, value, nil
+}
+
+// ValidateTagKey checks that a tag key can be written and parsed as key=value
+func ValidateTagKey(key string) error {
+	if key == "" || strings.ContainsAny(key, "=, \t\n") {
+		return fmt.Errorf("invalid tag key %q: it can't be empty or contain '=', ',' or spaces", key)
+	}
+	return nil
+}
+
+// FormatTags formats tags as sorted key=value pairs, or "none"
+func FormatTags(tags map[string]string) string {
+	if len(tags) == 0 {
+		return "none"
+	}
+	pairs := make([]string, 0, len(tags))
+	for k, v := range tags {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// IsEmpty reports whether the filter matches everything
+func (f Filter) IsEmpty() bool {
+	return f.Prefix == "" && len(f.Tags) == 0 && f.Owner == ""
+}
+
+// Matches reports whether a secret satisfies every condition of the filter
+func (f Filter) Matches(name string, m Metadata) bool {
+	if !strings.HasPrefix(name, f.Prefix) {
+		return false
+	}
+	if f.Owner != "" && m.Owner != f.Owner {
+		return false
+	}
+	for key, value := range f.Tags {
+		if got, ok := m.Tags[key]; !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterEntries returns the entries matching the filter
+func FilterEntries(entries map[string]SecretEntry, f Filter) map[string]SecretEntry {
+	if f.IsEmpty() {
+		return entries
+	}
+	matched := make(map[string]SecretEntry)
+	for name, entry := range entries {
+		if f.Matches(name, entry.Metadata()) {
+			matched[name] = entry
+		}
+	}
+	return matched
+}
@@ -0,0 +1,84 @@
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/spacebarlabs/lean_vault/pkg/crypto"
+)
+
+// Fingerprint identifies a value that was stored in the vault without keeping
+// the value itself, so leak scanners can match it after it has been replaced
+type Fingerprint struct {
+	Name   string `yaml:"name"`
+	Hash   string `yaml:"hash"`
+	Length int    `yaml:"length"`
+	// Rolling is the value's RollingHash, which lets a scanner skip most
+	// positions before computing Hash; zero for values recorded before it
+	// was kept
+	Rolling    uint32    `yaml:"rolling,omitempty"`
+	RecordedAt time.Time `yaml:"recorded_at"`
+}
+
+// HashValue returns the hex-encoded SHA-256 hash used in fingerprints
+func HashValue(value []byte) string {
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:])
+}
+
+// RollingHashBase is the multiplier of RollingHash. A scanner can slide the
+// hash along text one byte at a time by removing the leading byte times
+// RollingHashBase to the power of the length minus one.
+const RollingHashBase = 16777619
+
+// RollingHash returns a cheap polynomial hash of value, computed modulo 2^32
+func RollingHash(value []byte) uint32 {
+	var hash uint32
+	for _, c := range value {
+		hash = hash*RollingHashBase + uint32(c)
+	}
+	return hash
+}
+
+// recordFingerprint remembers a value written under name
+func recordFingerprint(vaultData *VaultData, name, value string) {
+	hash := HashValue([]byte(value))
+	for i, fp := range vaultData.Fingerprints {
+		if fp.Hash == hash && fp.Name == name {
+			// Fill in the rolling hash of values recorded before it was kept
+			vaultData.Fingerprints[i].Rolling = RollingHash([]byte(value))
+			return
+		}
+	}
+
+	vaultData.Fingerprints = append(vaultData.Fingerprints, Fingerprint{
+		Name:       name,
+		Hash:       hash,
+		Length:     len(value),
+		Rolling:    RollingHash([]byte(value)),
+		RecordedAt: time.Now(),
+	})
+}
+
+// SecretFingerprints returns fingerprints of every value currently or
+// previously stored in the vault, including the main provisioning key
+func (v *Vault) SecretFingerprints() ([]Fingerprint, error) {
+	vaultData, masterKey, err := v.load()
+	if err != nil {
+		return nil, err
+	}
+
+	// Current values are fingerprinted on the fly, which also covers
+	// values stored before fingerprints were recorded
+	for name, secret := range vaultData.Secrets {
+		plaintext, err := crypto.Decrypt(masterKey, secret.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt secret %s: %w", name, err)
+		}
+		recordFingerprint(vaultData, name, string(plaintext))
+	}
+
+	return vaultData.Fingerprints, nil
+}
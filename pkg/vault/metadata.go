@@ -0,0 +1,82 @@
+package vault
+
+import "fmt"
+
+// Metadata is the part of an entry that describes it rather than its value
+type Metadata struct {
+	Description string
+	Owner       string
+	Tags        map[string]string
+	// Limit is the spend cap in dollars recorded for the key
+	Limit   float64
+	Policy  *Policy
+	Managed bool
+}
+
+// Metadata returns the entry's metadata
+func (e SecretEntry) Metadata() Metadata {
+	return Metadata{
+		Description: e.Description,
+		Owner:       e.Owner,
+		Limit:       e.Limit,
+		Tags:        copyTags(e.Tags),
+		Policy:      e.Policy,
+		Managed:     e.Managed,
+	}
+}
+
+// SetMetadata replaces a secret's metadata without changing its value
+func (v *Vault) SetMetadata(name string, metadata Metadata) error {
+	vaultData, masterKey, err := v.load()
+	if err != nil {
+		return err
+	}
+
+	if IsSystemName(CanonicalName(name)) {
+		return fmt.Errorf("cannot set metadata on %s", name)
+	}
+
+	secret, exists := vaultData.Secrets[name]
+	if !exists {
+		return fmt.Errorf("secret %s not found", name)
+	}
+
+	if metadata.Policy != nil {
+		if err := metadata.Policy.Validate(); err != nil {
+			return err
+		}
+		if secret.IsLease() {
+			return fmt.Errorf("secret %s is a lease and already expires", name)
+		}
+	}
+	if metadata.Limit < 0 {
+		return fmt.Errorf("limit can't be negative")
+	}
+	for key := range metadata.Tags {
+		if err := ValidateTagKey(key); err != nil {
+			return err
+		}
+	}
+
+	secret.Description = metadata.Description
+	secret.Owner = metadata.Owner
+	secret.Limit = metadata.Limit
+	secret.Tags = copyTags(metadata.Tags)
+	secret.Policy = metadata.Policy
+	secret.Managed = metadata.Managed
+	vaultData.Secrets[name] = secret
+	return v.save(vaultData, masterKey)
+}
+
+// copyTags copies tags so callers can't change an entry through a shared
+// map; empty tags become nil so they are omitted from the vault
+func copyTags(tags map[string]string) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	copied := make(map[string]string, len(tags))
+	for k, v := range tags {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,119 @@
+package vault
+
+import "fmt"
+
+// RenameSecret moves a secret to a new name, keeping its encrypted value,
+// provider ID, metadata and history
+func (v *Vault) RenameSecret(oldName, newName string) error {
+	vaultData, masterKey, err := v.load()
+	if err != nil {
+		return err
+	}
+
+	entry, err := transferableEntry(vaultData, oldName, newName)
+	if err != nil {
+		return err
+	}
+
+	delete(vaultData.Secrets, oldName)
+	vaultData.Secrets[newName] = entry
+	// Leaks of earlier values are reported under the name they now live at
+	for i := range vaultData.Fingerprints {
+		if vaultData.Fingerprints[i].Name == oldName {
+			vaultData.Fingerprints[i].Name = newName
+		}
+	}
+
+	return v.save(vaultData, masterKey)
+}
+
+// CopySecret stores a copy of a static secret under a new name, keeping its
+// value, metadata and history. Entries backed by a provider key are refused,
+// since revoking one copy would revoke the other; use CopyProvisionedSecret.
+func (v *Vault) CopySecret(srcName, dstName string) error {
+	vaultData, masterKey, err := v.load()
+	if err != nil {
+		return err
+	}
+
+	entry, err := transferableEntry(vaultData, srcName, dstName)
+	if err != nil {
+		return err
+	}
+	if entry.SecretType() != SecretTypeStatic {
+		return fmt.Errorf("%s is backed by a provider key; a copy needs a key of its own", srcName)
+	}
+
+	entry = copiedEntry(entry)
+	entry.History = append([]SecretVersion(nil), entry.History...)
+	vaultData.Secrets[dstName] = entry
+
+	for _, fp := range vaultData.Fingerprints {
+		if fp.Name == srcName {
+			fp.Name = dstName
+			vaultData.Fingerprints = append(vaultData.Fingerprints, fp)
+		}
+	}
+
+	return v.save(vaultData, masterKey)
+}
+
+// CopyProvisionedSecret stores value, a newly provisioned key with provider
+// ID id, under dstName with the labels, policy, limit and account of
+// srcName. The original's history belongs to its own key and isn't copied.
+func (v *Vault) CopyProvisionedSecret(srcName, dstName, value, id string) error {
+	vaultData, _, err := v.load()
+	if err != nil {
+		return err
+	}
+
+	entry, err := transferableEntry(vaultData, srcName, dstName)
+	if err != nil {
+		return err
+	}
+	if !entry.IsProvisioned() || entry.IsLease() {
+		return fmt.Errorf("%s is not a provisioned key that can be copied", srcName)
+	}
+	if id == "" {
+		return fmt.Errorf("a copy of %s needs the provider ID of its new key", srcName)
+	}
+
+	entry = copiedEntry(entry)
+	entry.ID = id
+	entry.History = nil
+	return v.addEntry(dstName, value, entry)
+}
+
+// copiedEntry returns a copy of entry that shares no labels or policy with
+// it. A pending grace revocation belongs to the original, and a manifest
+// only manages the names it lists.
+func copiedEntry(entry SecretEntry) SecretEntry {
+	entry.Tags = copyTags(entry.Tags)
+	if entry.Policy != nil {
+		policy := *entry.Policy
+		entry.Policy = &policy
+	}
+	entry.Previous = nil
+	entry.Managed = false
+	return entry
+}
+
+// transferableEntry returns the entry at from after checking it can be
+// stored under to
+func transferableEntry(vaultData *VaultData, from, to string) (SecretEntry, error) {
+	if IsSystemName(CanonicalName(from)) {
+		return SecretEntry{}, fmt.Errorf("cannot move or copy %s", from)
+	}
+	if err := ValidateName(to); err != nil {
+		return SecretEntry{}, err
+	}
+
+	entry, exists := vaultData.Secrets[from]
+	if !exists {
+		return SecretEntry{}, fmt.Errorf("secret %s not found", from)
+	}
+	if _, exists := vaultData.Secrets[to]; exists {
+		return SecretEntry{}, fmt.Errorf("secret %s already exists", to)
+	}
+	return entry, nil
+}
@@ -0,0 +1,85 @@
+package vault
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SystemNamespace holds the vault's own secrets, such as the main
+// provisioning key. Users can't create names in it.
+const SystemNamespace = "_system/"
+
+// ValidateName checks a name for a new secret. Names are paths of segments
+// separated by "/", like "prod/chatbot/openrouter". Each segment uses
+// letters, digits, '.', '-' and '_', and can't be "." or "..".
+func ValidateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("secret name can't be empty")
+	}
+	if IsSystemName(name) || name == LegacyMainProvisioningKeyName {
+		return fmt.Errorf("secret name %q is reserved", name)
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "" {
+			return fmt.Errorf("invalid secret name %q: it can't start or end with '/' or contain '//'", name)
+		}
+		if segment == "." || segment == ".." {
+			return fmt.Errorf("invalid secret name %q: '.' and '..' aren't allowed", name)
+		}
+		for _, r := range segment {
+			if !isNameRune(r) {
+				return fmt.Errorf("invalid secret name %q: %q isn't allowed; use letters, digits, '.', '-', '_' and '/'", name, r)
+			}
+		}
+	}
+	return nil
+}
+
+// isNameRune reports whether r can appear in a name segment
+func isNameRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+		r == '.' || r == '-' || r == '_'
+}
+
+// IsSystemName reports whether name is in the system namespace
+func IsSystemName(name string) bool {
+	return strings.HasPrefix(name, SystemNamespace)
+}
+
+// CanonicalName maps names that have moved to where they are stored now
+func CanonicalName(name string) string {
+	if name == LegacyMainProvisioningKeyName {
+		return MainProvisioningKeyName
+	}
+	return name
+}
+
+// NormalizePrefix turns a namespace such as "prod" or "prod/" into "prod/",
+// so a prefix always selects whole path segments
+func NormalizePrefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return ""
+	}
+	return prefix + "/"
+}
+
+// EnvVarName converts a secret name into an environment variable name,
+// e.g. "my-api-key" becomes "MY_API_KEY" and "prod/db" becomes "PROD_DB"
+func EnvVarName(name string) string {
+	var b strings.Builder
+	for i, r := range strings.ToUpper(name) {
+		switch {
+		case r >= 'A' && r <= 'Z', r == '_':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteRune('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,119 @@
+package vault
+
+import (
+	"fmt"
+	"time"
+)
+
+// Actions taken once a key is past its policy's maximum age
+const (
+	OnExpiryRotate = "rotate"
+	OnExpiryRevoke = "revoke"
+	OnExpiryWarn   = "warn"
+)
+
+// Policy states reported by CheckPolicy
+const (
+	// PolicyOK means the key is within its policy
+	PolicyOK = "ok"
+	// PolicyDue means the key is inside its rotate_before_expiry window
+	PolicyDue = "due"
+	// PolicyExpired means the key is older than its max_age
+	PolicyExpired = "expired"
+	// PolicyUnknownAge means the entry predates timestamps, so its age can't be checked
+	PolicyUnknownAge = "unknown-age"
+)
+
+// Policy is per-key rotation metadata. Durations use ParseDuration syntax,
+// such as "30d" or "12h", and are kept as written.
+type Policy struct {
+	MaxAge             string `yaml:"max_age,omitempty"`
+	RotateBeforeExpiry string `yaml:"rotate_before_expiry,omitempty"`
+	// OnExpiry is one of the OnExpiry constants; empty means rotate
+	OnExpiry string `yaml:"on_expiry,omitempty"`
+}
+
+// PolicyState is the result of checking an entry against its policy
+type PolicyState struct {
+	Status string
+	// ExpiresAt is when the key passes its max_age, if known
+	ExpiresAt time.Time
+	// DueAt is when the key enters its rotate_before_expiry window, if known
+	DueAt time.Time
+}
+
+// Violated reports whether the state needs attention
+func (s PolicyState) Violated() bool {
+	return s.Status != PolicyOK
+}
+
+// Validate checks that the policy's durations and action are well formed
+func (p *Policy) Validate() error {
+	if p.MaxAge == "" {
+		return fmt.Errorf("policy requires max_age")
+	}
+	maxAge, err := ParseDuration(p.MaxAge)
+	if err != nil {
+		return fmt.Errorf("invalid max_age: %w", err)
+	}
+	if maxAge <= 0 {
+		return fmt.Errorf("max_age must be positive")
+	}
+
+	if p.RotateBeforeExpiry != "" {
+		before, err := ParseDuration(p.RotateBeforeExpiry)
+		if err != nil {
+			return fmt.Errorf("invalid rotate_before_expiry: %w", err)
+		}
+		if before < 0 || before >= maxAge {
+			return fmt.Errorf("rotate_before_expiry must be between zero and max_age")
+		}
+	}
+
+	switch p.OnExpiry {
+	case "", OnExpiryRotate, OnExpiryRevoke, OnExpiryWarn:
+	default:
+		return fmt.Errorf("invalid on_expiry %q (expected rotate, revoke or warn)", p.OnExpiry)
+	}
+	return nil
+}
+
+// Action returns the on_expiry action, defaulting to rotate
+func (p *Policy) Action() string {
+	if p.OnExpiry == "" {
+		return OnExpiryRotate
+	}
+	return p.OnExpiry
+}
+
+// CheckPolicy evaluates the entry's policy at the given time. Entries
+// without a policy are always OK.
+func (e SecretEntry) CheckPolicy(now time.Time) (PolicyState, error) {
+	if e.Policy == nil {
+		return PolicyState{Status: PolicyOK}, nil
+	}
+	if err := e.Policy.Validate(); err != nil {
+		return PolicyState{}, err
+	}
+	if e.UpdatedAt.IsZero() {
+		return PolicyState{Status: PolicyUnknownAge}, nil
+	}
+
+	maxAge, _ := ParseDuration(e.Policy.MaxAge)
+	state := PolicyState{ExpiresAt: e.UpdatedAt.Add(maxAge)}
+	state.DueAt = state.ExpiresAt
+	if e.Policy.RotateBeforeExpiry != "" {
+		before, _ := ParseDuration(e.Policy.RotateBeforeExpiry)
+		state.DueAt = state.ExpiresAt.Add(-before)
+	}
+
+	switch {
+	case !now.Before(state.ExpiresAt):
+		state.Status = PolicyExpired
+	case !now.Before(state.DueAt):
+		state.Status = PolicyDue
+	default:
+		state.Status = PolicyOK
+	}
+	return state, nil
+}
@@ -0,0 +1,125 @@
+package vault
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultProfile is the profile whose vault lives directly in DefaultVaultDir
+	DefaultProfile = "default"
+	// ProfileEnvVar selects a profile, overriding the one chosen with 'profile use'
+	ProfileEnvVar = "LEAN_VAULT_PROFILE"
+	// ProfilesDir is the directory under DefaultVaultDir holding the other profiles
+	ProfilesDir = "profiles"
+	// CurrentProfileFile records the profile chosen with 'profile use'
+	CurrentProfileFile = "current_profile"
+)
+
+var profileNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+// Profile describes a named vault
+type Profile struct {
+	Name string
+	Dir  string
+	// Initialized reports whether the profile's vault file exists
+	Initialized bool
+}
+
+// ValidateProfileName checks that name is usable as a profile directory
+func ValidateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// baseDir returns DefaultVaultDir in the user's home directory
+func baseDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "."
+	}
+	return filepath.Join(homeDir, DefaultVaultDir)
+}
+
+// ProfileDir returns the directory holding a profile's vault. The default
+// profile keeps the original location so existing vaults keep working.
+func ProfileDir(name string) string {
+	if name == DefaultProfile {
+		return baseDir()
+	}
+	return filepath.Join(baseDir(), ProfilesDir, name)
+}
+
+// ActiveProfile returns the profile selected by LEAN_VAULT_PROFILE, then the
+// one chosen with 'profile use', then the default profile
+func ActiveProfile() (string, error) {
+	name := os.Getenv(ProfileEnvVar)
+	if name == "" {
+		data, err := os.ReadFile(filepath.Join(baseDir(), CurrentProfileFile))
+		if err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to read current profile: %w", err)
+		}
+		name = strings.TrimSpace(string(data))
+	}
+	if name == "" {
+		return DefaultProfile, nil
+	}
+	if err := ValidateProfileName(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// UseProfile makes name the profile used when LEAN_VAULT_PROFILE isn't set
+func UseProfile(name string) error {
+	if err := ValidateProfileName(name); err != nil {
+		return err
+	}
+	if name != DefaultProfile {
+		if _, err := os.Stat(ProfileDir(name)); err != nil {
+			return fmt.Errorf("profile %s does not exist", name)
+		}
+	}
+	if err := os.MkdirAll(baseDir(), DefaultDirMode); err != nil {
+		return fmt.Errorf("failed to create vault directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(baseDir(), CurrentProfileFile), []byte(name+"\n"), DefaultFileMode); err != nil {
+		return fmt.Errorf("failed to save current profile: %w", err)
+	}
+	return nil
+}
+
+// ListProfiles returns the default profile and every profile under ProfilesDir, sorted by name
+func ListProfiles() ([]Profile, error) {
+	profiles := []Profile{NewProfile(DefaultProfile).profile()}
+
+	entries, err := os.ReadDir(filepath.Join(baseDir(), ProfilesDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read profiles: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && ValidateProfileName(entry.Name()) == nil && entry.Name() != DefaultProfile {
+			profiles = append(profiles, NewProfile(entry.Name()).profile())
+		}
+	}
+
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles, nil
+}
+
+// profile describes the vault's profile
+func (v *Vault) profile() Profile {
+	_, err := os.Stat(v.vaultFile)
+	return Profile{Name: v.profileName, Dir: v.vaultDir, Initialized: err == nil}
+}
+
+// ProfileName returns the name of the profile the vault belongs to
+func (v *Vault) ProfileName() string {
+	return v.profileName
+}
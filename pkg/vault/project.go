@@ -0,0 +1,155 @@
+package vault
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ProjectFile is the manifest that points a repository at its own vault
+	ProjectFile = ".lean_vault.yml"
+	// DefaultProjectVaultDir is the project vault directory, relative to the manifest
+	DefaultProjectVaultDir = ".lean_vault"
+	// VaultDirEnvVar selects a vault directory, overriding profiles and projects
+	VaultDirEnvVar = "LEAN_VAULT_DIR"
+)
+
+// Project is a .lean_vault.yml manifest
+type Project struct {
+	// VaultDir holds the encrypted vault, relative to the manifest
+	VaultDir string `yaml:"vault_dir,omitempty"`
+	// KeyFile is the master key. It must be kept out of the repository, so
+	// it has no default. A leading ~/ is expanded, and relative paths are
+	// relative to the manifest.
+	KeyFile string `yaml:"key_file"`
+}
+
+// FindProject looks for a .lean_vault.yml manifest in dir and each of its
+// parents, returning its path or "" if there is none
+func FindProject(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve directory: %w", err)
+	}
+	for {
+		path := filepath.Join(dir, ProjectFile)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// LoadProject reads a .lean_vault.yml manifest
+func LoadProject(path string) (*Project, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project manifest: %w", err)
+	}
+
+	project := &Project{}
+	if err := yaml.Unmarshal(data, project); err != nil {
+		return nil, fmt.Errorf("failed to parse project manifest %s: %w", path, err)
+	}
+	if project.KeyFile == "" {
+		return nil, fmt.Errorf("project manifest %s must set key_file", path)
+	}
+	return project, nil
+}
+
+// NewProjectVault creates a vault manager for the project manifest at path
+func NewProjectVault(path string) (*Vault, error) {
+	project, err := LoadProject(path)
+	if err != nil {
+		return nil, err
+	}
+
+	root := filepath.Dir(path)
+	vaultDir := project.VaultDir
+	if vaultDir == "" {
+		vaultDir = DefaultProjectVaultDir
+	}
+	if !filepath.IsAbs(vaultDir) {
+		vaultDir = filepath.Join(root, vaultDir)
+	}
+	keyFile, err := expandPath(project.KeyFile, root)
+	if err != nil {
+		return nil, err
+	}
+
+	v := NewDir(vaultDir)
+	v.keyFile = keyFile
+	v.projectFile = path
+	return v, nil
+}
+
+// NewDir creates a vault manager for a vault directory laid out like DefaultVaultDir
+func NewDir(dir string) *Vault {
+	return &Vault{
+		vaultDir:  dir,
+		vaultFile: filepath.Join(dir, DefaultVaultFile),
+		keyFile:   filepath.Join(dir, DefaultKeyFile),
+	}
+}
+
+// Resolve returns the vault commands should use: LEAN_VAULT_DIR, then a
+// profile named by LEAN_VAULT_PROFILE, then the nearest project manifest,
+// then the profile chosen with 'profile use' or the default profile
+func Resolve() (*Vault, error) {
+	if dir := os.Getenv(VaultDirEnvVar); dir != "" {
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", VaultDirEnvVar, err)
+		}
+		return NewDir(dir), nil
+	}
+
+	if os.Getenv(ProfileEnvVar) == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get working directory: %w", err)
+		}
+		path, err := FindProject(cwd)
+		if err != nil {
+			return nil, err
+		}
+		if path != "" {
+			return NewProjectVault(path)
+		}
+	}
+
+	name, err := ActiveProfile()
+	if err != nil {
+		return nil, err
+	}
+	return NewProfile(name), nil
+}
+
+// ProjectFile returns the manifest the vault was found through, or "" if
+// it isn't a project vault
+func (v *Vault) ProjectFile() string {
+	return v.projectFile
+}
+
+// expandPath expands a leading ~/ and makes path absolute relative to base
+func expandPath(path, base string) (string, error) {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to expand %s: %w", path, err)
+		}
+		path = filepath.Join(homeDir, strings.TrimPrefix(path, "~"))
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, path)
+	}
+	return path, nil
+}
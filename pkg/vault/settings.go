@@ -0,0 +1,63 @@
+package vault
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultSettingsFile is the default name for a profile's settings
+const DefaultSettingsFile = "settings.yml"
+
+// Settings holds a profile's unencrypted preferences
+type Settings struct {
+	// APIBaseURL overrides the OpenRouter API endpoint for this profile
+	APIBaseURL string `yaml:"api_base_url,omitempty"`
+	// TrashRetentionDays is how long removed secrets are kept before gc
+	// purges them; unset means DefaultTrashRetentionDays and 0 keeps them
+	// until they are purged by hand
+	TrashRetentionDays *int `yaml:"trash_retention_days,omitempty"`
+}
+
+// TrashRetention returns how long removed secrets are kept, or 0 to keep
+// them until they are purged by hand
+func (s *Settings) TrashRetention() time.Duration {
+	days := DefaultTrashRetentionDays
+	if s.TrashRetentionDays != nil {
+		days = *s.TrashRetentionDays
+	}
+	if days <= 0 {
+		return 0
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// SettingsPath returns the path of the settings file for this vault
+func (v *Vault) SettingsPath() string {
+	return filepath.Join(v.vaultDir, DefaultSettingsFile)
+}
+
+// LoadSettings reads the vault's settings. A missing file is not an error
+// and yields empty settings.
+func (v *Vault) LoadSettings() (*Settings, error) {
+	settings := &Settings{}
+
+	data, err := os.ReadFile(v.SettingsPath())
+	if os.IsNotExist(err) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, settings); err != nil {
+		return nil, fmt.Errorf("failed to parse settings %s: %w", v.SettingsPath(), err)
+	}
+	if settings.TrashRetentionDays != nil && *settings.TrashRetentionDays < 0 {
+		return nil, fmt.Errorf("invalid settings %s: trash_retention_days can't be negative", v.SettingsPath())
+	}
+	return settings, nil
+}
@@ -0,0 +1,164 @@
+package vault
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DefaultTrashRetentionDays is how long removed secrets stay in the trash
+// when settings don't say otherwise
+const DefaultTrashRetentionDays = 30
+
+// TrashedSecret is a removed secret kept so it can be restored, or its
+// provider key revoked, until it is purged
+type TrashedSecret struct {
+	Name      string      `yaml:"name"`
+	Entry     SecretEntry `yaml:"entry"`
+	DeletedAt time.Time   `yaml:"deleted_at"`
+	// Revoked records whether the provider key was revoked on removal
+	Revoked bool `yaml:"revoked,omitempty"`
+	// KeepUpstream records that the secret was removed without revoking its
+	// provider key on purpose, so purging it must not revoke the key either
+	KeepUpstream bool `yaml:"keep_upstream,omitempty"`
+}
+
+// NeedsRevocation reports whether purging the secret would forget a
+// provider key that is still active
+func (t TrashedSecret) NeedsRevocation() bool {
+	if t.Revoked || t.KeepUpstream {
+		return false
+	}
+	return t.Entry.IsProvisioned() || (t.Entry.Previous != nil && t.Entry.Previous.ID != "")
+}
+
+// Same reports whether two trash items are the same removal
+func (t TrashedSecret) Same(other TrashedSecret) bool {
+	return t.Name == other.Name && t.DeletedAt.Equal(other.DeletedAt)
+}
+
+// TrashSecret moves a secret to the trash. revoked records whether its
+// provider key was revoked first; keepUpstream that it must never be.
+func (v *Vault) TrashSecret(name string, revoked, keepUpstream bool) error {
+	vaultData, masterKey, err := v.load()
+	if err != nil {
+		return err
+	}
+
+	if IsSystemName(CanonicalName(name)) {
+		return fmt.Errorf("cannot remove %s", name)
+	}
+
+	entry, exists := vaultData.Secrets[name]
+	if !exists {
+		return fmt.Errorf("secret %s not found", name)
+	}
+
+	delete(vaultData.Secrets, name)
+	vaultData.Trash = append(vaultData.Trash, TrashedSecret{
+		Name:         name,
+		Entry:        entry,
+		DeletedAt:    time.Now().UTC(),
+		Revoked:      revoked,
+		KeepUpstream: keepUpstream,
+	})
+	return v.save(vaultData, masterKey)
+}
+
+// ListTrash returns the trashed secrets, oldest first
+func (v *Vault) ListTrash() ([]TrashedSecret, error) {
+	vaultData, _, err := v.load()
+	if err != nil {
+		return nil, err
+	}
+
+	trash := append([]TrashedSecret(nil), vaultData.Trash...)
+	sort.SliceStable(trash, func(i, j int) bool {
+		return trash[i].DeletedAt.Before(trash[j].DeletedAt)
+	})
+	return trash, nil
+}
+
+// RestoreSecret moves the most recently trashed secret called name back into
+// the vault, under newName if it isn't empty
+func (v *Vault) RestoreSecret(name, newName string) (TrashedSecret, error) {
+	vaultData, masterKey, err := v.load()
+	if err != nil {
+		return TrashedSecret{}, err
+	}
+
+	found := -1
+	for i, trashed := range vaultData.Trash {
+		if trashed.Name == name && (found < 0 || !trashed.DeletedAt.Before(vaultData.Trash[found].DeletedAt)) {
+			found = i
+		}
+	}
+	if found < 0 {
+		return TrashedSecret{}, fmt.Errorf("secret %s is not in the trash", name)
+	}
+
+	if newName == "" {
+		newName = name
+	}
+	if err := ValidateName(newName); err != nil {
+		return TrashedSecret{}, err
+	}
+	if _, exists := vaultData.Secrets[newName]; exists {
+		return TrashedSecret{}, fmt.Errorf("secret %s already exists", newName)
+	}
+
+	trashed := vaultData.Trash[found]
+	vaultData.Trash = append(vaultData.Trash[:found], vaultData.Trash[found+1:]...)
+	vaultData.Secrets[newName] = trashed.Entry
+	if err := v.save(vaultData, masterKey); err != nil {
+		return TrashedSecret{}, err
+	}
+	return trashed, nil
+}
+
+// PurgeTrash permanently deletes the trashed secrets that match and returns them
+func (v *Vault) PurgeTrash(match func(TrashedSecret) bool) ([]TrashedSecret, error) {
+	vaultData, masterKey, err := v.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var purged, kept []TrashedSecret
+	for _, trashed := range vaultData.Trash {
+		if match(trashed) {
+			purged = append(purged, trashed)
+		} else {
+			kept = append(kept, trashed)
+		}
+	}
+	if len(purged) == 0 {
+		return nil, nil
+	}
+
+	vaultData.Trash = kept
+	if err := v.save(vaultData, masterKey); err != nil {
+		return nil, err
+	}
+	return purged, nil
+}
+
+// ExpiredTrash returns the trashed secrets deleted more than retention ago.
+// A zero retention keeps everything.
+func (v *Vault) ExpiredTrash(retention time.Duration, now time.Time) ([]TrashedSecret, error) {
+	if retention <= 0 {
+		return nil, nil
+	}
+
+	trash, err := v.ListTrash()
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []TrashedSecret
+	for _, trashed := range trash {
+		if now.Sub(trashed.DeletedAt) >= retention {
+			expired = append(expired, trashed)
+		}
+	}
+	return expired, nil
+}
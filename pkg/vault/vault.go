@@ -1,9 +1,11 @@
 package vault
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,12 +20,30 @@ const (
 	DefaultVaultFile = "secrets.vault"
 	// DefaultKeyFile is the default name for the master key file
 	DefaultKeyFile = ".secret_vault.key"
+	// DefaultAuditFile is the default name for the audit log
+	DefaultAuditFile = "audit.log"
+	// DefaultAgentSocket is the default name for the agent's Unix socket
+	DefaultAgentSocket = "agent.sock"
+	// DefaultProxyConfigFile is the default name for the proxy configuration
+	DefaultProxyConfigFile = "proxy.yml"
+	// DefaultUsageLedgerFile is the default name for the proxy's usage ledger
+	DefaultUsageLedgerFile = "usage.ledger"
 	// DefaultFileMode is the default file permissions for sensitive files
 	DefaultFileMode = 0600
 	// DefaultDirMode is the default directory permissions
 	DefaultDirMode = 0700
 	// MainProvisioningKeyName is the reserved name for the main OpenRouter provisioning key
-	MainProvisioningKeyName = "_MAIN_OPENROUTER_PROVISIONING_KEY_"
+	MainProvisioningKeyName = SystemNamespace + "openrouter/provisioning-key"
+	// LegacyMainProvisioningKeyName is where vaults created before the
+	// system namespace keep the main provisioning key
+	LegacyMainProvisioningKeyName = "_MAIN_OPENROUTER_PROVISIONING_KEY_"
+)
+
+const (
+	// SecretTypeOpenRouter marks a key provisioned through the OpenRouter API
+	SecretTypeOpenRouter = "openrouter"
+	// SecretTypeStatic marks a user-supplied value with no provider behind it
+	SecretTypeStatic = "static"
 )
 
 // KeyVersion represents a master key version
@@ -39,56 +59,149 @@ type VaultData struct {
 	// Add key version tracking
 	CurrentKeyID string
 	KeyVersions  map[string]KeyVersion
+	// Fingerprints of every value ever stored, for leak scanning
+	Fingerprints []Fingerprint `yaml:"fingerprints,omitempty"`
+	// Trash holds removed secrets until they are purged
+	Trash []TrashedSecret `yaml:"trash,omitempty"`
 }
 
 // SecretEntry represents a single secret entry in the vault
 type SecretEntry struct {
 	Value string `yaml:"value"`
 	ID    string `yaml:"id,omitempty"`
+	// Type is empty for entries written before secret types existed,
+	// which are treated as OpenRouter keys
+	Type string `yaml:"type,omitempty"`
+	// ExpiresAt is set on leased keys, which are revoked once it passes
+	ExpiresAt *time.Time `yaml:"expires_at,omitempty"`
+	// Limit is the spend cap in dollars the key was created with, if any
+	Limit float64 `yaml:"limit,omitempty"`
+	// CreatedAt and UpdatedAt are zero for entries written before
+	// timestamps were recorded. UpdatedAt changes whenever the value does.
+	CreatedAt time.Time `yaml:"created_at,omitempty"`
+	UpdatedAt time.Time `yaml:"updated_at,omitempty"`
+	// Policy controls when the key is due for rotation
+	Policy *Policy `yaml:"policy,omitempty"`
+	// Version numbers the entry's values, starting at 1
+	Version int `yaml:"version,omitempty"`
+	// History holds up to MaxHistory replaced versions, oldest first
+	History []SecretVersion `yaml:"history,omitempty"`
+	// Previous is the value replaced by a rotation with a grace period,
+	// kept until its scheduled revocation
+	Previous *SecretVersion `yaml:"previous,omitempty"`
+	// Description, Owner and Tags are free-form labels
+	Description string            `yaml:"description,omitempty"`
+	Owner       string            `yaml:"owner,omitempty"`
+	Tags        map[string]string `yaml:"tags,omitempty"`
+	// Managed marks entries created or adopted by 'apply', which removes
+	// them once they are dropped from the manifest
+	Managed bool `yaml:"managed,omitempty"`
+	// Account names the provider account that provisioned the key; empty
+	// means DefaultAccount
+	Account string `yaml:"account,omitempty"`
+}
+
+// SecretType returns the type of the entry, defaulting to OpenRouter
+func (e SecretEntry) SecretType() string {
+	if e.Type == "" {
+		return SecretTypeOpenRouter
+	}
+	return e.Type
+}
+
+// IsProvisioned reports whether the entry is backed by a provider key that can be revoked
+func (e SecretEntry) IsProvisioned() bool {
+	return e.SecretType() != SecretTypeStatic && e.ID != ""
+}
+
+// IsLease reports whether the entry is a short-lived key with an expiry
+func (e SecretEntry) IsLease() bool {
+	return e.ExpiresAt != nil
+}
+
+// Expired reports whether the entry is a lease whose expiry has passed
+func (e SecretEntry) Expired(now time.Time) bool {
+	return e.ExpiresAt != nil && !now.Before(*e.ExpiresAt)
 }
 
 // Vault represents the vault manager
 type Vault struct {
-	vaultDir  string
-	vaultFile string
-	keyFile   string
-	data      *VaultData
-	masterKey []byte
+	profileName string
+	projectFile string
+	vaultDir    string
+	vaultFile   string
+	keyFile     string
+	data        *VaultData
+	masterKey   []byte
 }
 
-// New creates a new vault manager
-func New() *Vault {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		homeDir = "."
-	}
+// New creates a vault manager for the vault chosen by Resolve. An invalid
+// profile or project manifest is an error rather than a reason to fall back
+// to another vault.
+func New() (*Vault, error) {
+	return Resolve()
+}
 
+// NewProfile creates a vault manager for the named profile
+func NewProfile(name string) *Vault {
+	dir := ProfileDir(name)
 	return &Vault{
-		vaultDir:  filepath.Join(homeDir, DefaultVaultDir),
-		vaultFile: filepath.Join(homeDir, DefaultVaultDir, DefaultVaultFile),
-		keyFile:   filepath.Join(homeDir, DefaultVaultDir, DefaultKeyFile),
+		profileName: name,
+		vaultDir:    dir,
+		vaultFile:   filepath.Join(dir, DefaultVaultFile),
+		keyFile:     filepath.Join(dir, DefaultKeyFile),
 	}
 }
 
+// InitOptions configures a new vault
+type InitOptions struct {
+	// ProvisioningKey is the main OpenRouter provisioning key; empty creates
+	// a vault for static secrets only
+	ProvisioningKey string
+	// MasterKey is a pre-generated master key; nil generates one
+	MasterKey []byte
+}
+
 // Init initializes a new vault
 func (v *Vault) Init(mainProvisioningKey string) error {
+	return v.InitWith(InitOptions{ProvisioningKey: mainProvisioningKey})
+}
+
+// InitWith initializes a new vault with options. An existing key file is
+// only accepted if it already holds opts.MasterKey.
+func (v *Vault) InitWith(opts InitOptions) error {
+	if opts.MasterKey != nil && len(opts.MasterKey) != crypto.KeySize {
+		return fmt.Errorf("master key must be %d bytes, got %d", crypto.KeySize, len(opts.MasterKey))
+	}
+
 	// Check if vault already exists
 	if _, err := os.Stat(v.vaultFile); err == nil {
 		return fmt.Errorf("vault file already exists at %s", v.vaultFile)
 	}
-	if _, err := os.Stat(v.keyFile); err == nil {
-		return fmt.Errorf("key file already exists at %s", v.keyFile)
+	keyFileExists := false
+	if existing, err := os.ReadFile(v.keyFile); err == nil {
+		if opts.MasterKey == nil || !bytes.Equal(existing, opts.MasterKey) {
+			return fmt.Errorf("key file already exists at %s", v.keyFile)
+		}
+		keyFileExists = true
 	}
 
-	// Create vault directory with restricted permissions
+	// Create vault directory with restricted permissions. A project's key
+	// file is kept elsewhere, so its directory may not exist yet either.
 	if err := os.MkdirAll(v.vaultDir, DefaultDirMode); err != nil {
 		return fmt.Errorf("failed to create vault directory: %w", err)
 	}
+	if err := os.MkdirAll(filepath.Dir(v.keyFile), DefaultDirMode); err != nil {
+		return fmt.Errorf("failed to create key directory: %w", err)
+	}
 
 	// Generate master key
-	masterKey, err := crypto.GenerateMasterKey()
-	if err != nil {
-		return fmt.Errorf("failed to generate master key: %w", err)
+	masterKey := opts.MasterKey
+	if masterKey == nil {
+		var err error
+		if masterKey, err = crypto.GenerateMasterKey(); err != nil {
+			return fmt.Errorf("failed to generate master key: %w", err)
+		}
 	}
 
 	// Create initial key version
@@ -99,28 +212,35 @@ func (v *Vault) Init(mainProvisioningKey string) error {
 		CreatedAt: time.Now(),
 	}
 
-	// Encrypt the main provisioning key
-	encryptedKey, err := crypto.Encrypt(masterKey, []byte(mainProvisioningKey))
-	if err != nil {
-		return fmt.Errorf("failed to encrypt main provisioning key: %w", err)
-	}
-
 	// Create initial vault data
 	vaultData := VaultData{
-		Secrets: map[string]SecretEntry{
-			MainProvisioningKeyName: {
-				Value: encryptedKey,
-			},
-		},
+		Secrets:      make(map[string]SecretEntry),
 		CurrentKeyID: initialKeyID,
 		KeyVersions: map[string]KeyVersion{
 			initialKeyID: keyVersion,
 		},
 	}
 
+	// Encrypt the main provisioning key
+	if opts.ProvisioningKey != "" {
+		encryptedKey, err := crypto.Encrypt(masterKey, []byte(opts.ProvisioningKey))
+		if err != nil {
+			return fmt.Errorf("failed to encrypt main provisioning key: %w", err)
+		}
+		now := time.Now().UTC()
+		vaultData.Secrets[MainProvisioningKeyName] = SecretEntry{
+			Value:     encryptedKey,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		recordFingerprint(&vaultData, MainProvisioningKeyName, opts.ProvisioningKey)
+	}
+
 	// Save master key
-	if err := os.WriteFile(v.keyFile, masterKey, DefaultFileMode); err != nil {
-		return fmt.Errorf("failed to save master key: %w", err)
+	if !keyFileExists {
+		if err := os.WriteFile(v.keyFile, masterKey, DefaultFileMode); err != nil {
+			return fmt.Errorf("failed to save master key: %w", err)
+		}
 	}
 
 	// Marshal vault data
@@ -171,10 +291,47 @@ func (v *Vault) load() (*VaultData, []byte, error) {
 	if err := yaml.Unmarshal(decrypted, &vaultData); err != nil {
 		return nil, nil, fmt.Errorf("failed to unmarshal vault data: %w", err)
 	}
+	if err := migrateNames(&vaultData, masterKey); err != nil {
+		return nil, nil, err
+	}
 
 	return &vaultData, masterKey, nil
 }
 
+// migrateNames moves entries stored under names that have since changed.
+// The change is written the next time the vault is saved. If the new name is
+// already taken by a different value, neither entry is touched and an error
+// is returned, since dropping either would lose a credential.
+func migrateNames(vaultData *VaultData, masterKey []byte) error {
+	entry, exists := vaultData.Secrets[LegacyMainProvisioningKeyName]
+	if !exists {
+		return nil
+	}
+	if current, taken := vaultData.Secrets[MainProvisioningKeyName]; taken {
+		legacyValue, err := crypto.Decrypt(masterKey, entry.Value)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt secret %s: %w", LegacyMainProvisioningKeyName, err)
+		}
+		currentValue, err := crypto.Decrypt(masterKey, current.Value)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt secret %s: %w", MainProvisioningKeyName, err)
+		}
+		if !bytes.Equal(legacyValue, currentValue) {
+			return fmt.Errorf("cannot migrate %s to %s: both are stored with different values; restore the vault from a backup, or remove one with the lean_vault version that wrote it",
+				LegacyMainProvisioningKeyName, MainProvisioningKeyName)
+		}
+	} else {
+		vaultData.Secrets[MainProvisioningKeyName] = entry
+	}
+	delete(vaultData.Secrets, LegacyMainProvisioningKeyName)
+	for i := range vaultData.Fingerprints {
+		if vaultData.Fingerprints[i].Name == LegacyMainProvisioningKeyName {
+			vaultData.Fingerprints[i].Name = MainProvisioningKeyName
+		}
+	}
+	return nil
+}
+
 // save encrypts and saves the vault
 func (v *Vault) save(vaultData *VaultData, masterKey []byte) error {
 	// Marshal vault data
@@ -200,8 +357,35 @@ func (v *Vault) save(vaultData *VaultData, masterKey []byte) error {
 	return nil
 }
 
-// AddSecret adds a new secret to the vault
+// AddSecret adds a new secret provisioned through the default account
 func (v *Vault) AddSecret(name, value, id string) error {
+	return v.AddAccountSecret(name, value, id, DefaultAccount)
+}
+
+// AddAccountSecret adds a new secret provisioned through the named account
+func (v *Vault) AddAccountSecret(name, value, id, account string) error {
+	return v.addEntry(name, value, SecretEntry{ID: id, Type: SecretTypeOpenRouter, Account: storedAccount(account)})
+}
+
+// AddLease adds a provisioned key that expires at the given time. The limit
+// is recorded for reference; zero means the key has no spend cap.
+func (v *Vault) AddLease(name, value, id string, expiresAt time.Time, limit float64, account string) error {
+	expiresAt = expiresAt.UTC()
+	return v.addEntry(name, value, SecretEntry{
+		ID:        id,
+		Type:      SecretTypeOpenRouter,
+		ExpiresAt: &expiresAt,
+		Limit:     limit,
+		Account:   storedAccount(account),
+	})
+}
+
+// addEntry stores a new secret with the metadata in entry
+func (v *Vault) addEntry(name, value string, entry SecretEntry) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+
 	vaultData, masterKey, err := v.load()
 	if err != nil {
 		return err
@@ -210,6 +394,11 @@ func (v *Vault) AddSecret(name, value, id string) error {
 	if _, exists := vaultData.Secrets[name]; exists {
 		return fmt.Errorf("secret %s already exists", name)
 	}
+	if entry.Account != "" {
+		if _, exists := vaultData.Secrets[AccountKeyName(entry.Account)]; !exists {
+			return fmt.Errorf("account %s not found", entry.Account)
+		}
+	}
 
 	// Encrypt the secret value
 	encryptedValue, err := crypto.Encrypt(masterKey, []byte(value))
@@ -217,14 +406,77 @@ func (v *Vault) AddSecret(name, value, id string) error {
 		return fmt.Errorf("failed to encrypt secret: %w", err)
 	}
 
-	vaultData.Secrets[name] = SecretEntry{
-		Value: encryptedValue,
-		ID:    id,
+	now := time.Now().UTC()
+	entry.Value = encryptedValue
+	entry.Version = 1
+	entry.CreatedAt = now
+	entry.UpdatedAt = now
+	vaultData.Secrets[name] = entry
+	recordFingerprint(vaultData, name, value)
+
+	return v.save(vaultData, masterKey)
+}
+
+// SetStaticSecret stores a static secret, creating it or replacing its value.
+// Existing provisioned keys cannot be overwritten this way.
+func (v *Vault) SetStaticSecret(name, value string) error {
+	vaultData, masterKey, err := v.load()
+	if err != nil {
+		return err
+	}
+
+	if IsSystemName(CanonicalName(name)) {
+		return fmt.Errorf("cannot overwrite %s", name)
 	}
 
+	existing, exists := vaultData.Secrets[name]
+	if exists && existing.SecretType() != SecretTypeStatic {
+		return fmt.Errorf("secret %s is a provisioned %s key", name, existing.SecretType())
+	}
+	// Names stored before validation existed can still be updated
+	if !exists {
+		if err := ValidateName(name); err != nil {
+			return err
+		}
+	}
+
+	// Encrypt the secret value
+	encryptedValue, err := crypto.Encrypt(masterKey, []byte(value))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+
+	// Replacing a value keeps the rest of the entry, such as its policy
+	now := time.Now().UTC()
+	entry := existing
+	if exists {
+		entry.retire(ReasonSet, now)
+	} else {
+		entry = SecretEntry{Type: SecretTypeStatic, Version: 1, CreatedAt: now}
+	}
+	entry.Value = encryptedValue
+	entry.UpdatedAt = now
+	vaultData.Secrets[name] = entry
+	recordFingerprint(vaultData, name, value)
+
 	return v.save(vaultData, masterKey)
 }
 
+// GetSecretEntry returns the stored entry for a secret with its value still encrypted
+func (v *Vault) GetSecretEntry(name string) (SecretEntry, error) {
+	vaultData, _, err := v.load()
+	if err != nil {
+		return SecretEntry{}, err
+	}
+
+	secret, exists := vaultData.Secrets[CanonicalName(name)]
+	if !exists {
+		return SecretEntry{}, fmt.Errorf("secret %s not found", name)
+	}
+
+	return secret, nil
+}
+
 // GetSecret retrieves a secret from the vault
 func (v *Vault) GetSecret(name string) (string, error) {
 	vaultData, masterKey, err := v.load()
@@ -232,7 +484,7 @@ func (v *Vault) GetSecret(name string) (string, error) {
 		return "", err
 	}
 
-	secret, exists := vaultData.Secrets[name]
+	secret, exists := vaultData.Secrets[CanonicalName(name)]
 	if !exists {
 		return "", fmt.Errorf("secret %s not found", name)
 	}
@@ -246,6 +498,25 @@ func (v *Vault) GetSecret(name string) (string, error) {
 	return string(plaintext), nil
 }
 
+// GetAllSecrets decrypts every secret in the vault, including the main provisioning key
+func (v *Vault) GetAllSecrets() (map[string]string, error) {
+	vaultData, masterKey, err := v.load()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(vaultData.Secrets))
+	for name, secret := range vaultData.Secrets {
+		plaintext, err := crypto.Decrypt(masterKey, secret.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt secret %s: %w", name, err)
+		}
+		values[name] = string(plaintext)
+	}
+
+	return values, nil
+}
+
 // ListSecrets returns a list of all secret names (excluding the main provisioning key)
 func (v *Vault) ListSecrets() ([]string, error) {
 	vaultData, _, err := v.load()
@@ -253,16 +524,83 @@ func (v *Vault) ListSecrets() ([]string, error) {
 		return nil, err
 	}
 
-	secrets := make([]string, 0, len(vaultData.Secrets)-1)
+	secrets := make([]string, 0, len(vaultData.Secrets))
 	for name := range vaultData.Secrets {
-		if name != MainProvisioningKeyName {
+		if !IsSystemName(name) {
 			secrets = append(secrets, name)
 		}
 	}
+	sort.Strings(secrets)
 
 	return secrets, nil
 }
 
+// ListSecretEntries returns all secret entries keyed by name (excluding the main provisioning key)
+func (v *Vault) ListSecretEntries() (map[string]SecretEntry, error) {
+	vaultData, _, err := v.load()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]SecretEntry, len(vaultData.Secrets))
+	for name, entry := range vaultData.Secrets {
+		if !IsSystemName(name) {
+			entries[name] = entry
+		}
+	}
+
+	return entries, nil
+}
+
+// SetPolicy attaches a rotation policy to a secret, or removes it when policy is nil
+func (v *Vault) SetPolicy(name string, policy *Policy) error {
+	vaultData, masterKey, err := v.load()
+	if err != nil {
+		return err
+	}
+
+	if IsSystemName(CanonicalName(name)) {
+		return fmt.Errorf("cannot set a policy on %s", name)
+	}
+
+	secret, exists := vaultData.Secrets[name]
+	if !exists {
+		return fmt.Errorf("secret %s not found", name)
+	}
+
+	if policy != nil {
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+		if secret.IsLease() {
+			return fmt.Errorf("secret %s is a lease and already expires", name)
+		}
+		if policy.Action() == OnExpiryRevoke && !secret.IsProvisioned() {
+			return fmt.Errorf("secret %s has no provider key to revoke; use on_expiry rotate or warn", name)
+		}
+	}
+
+	secret.Policy = policy
+	vaultData.Secrets[name] = secret
+	return v.save(vaultData, masterKey)
+}
+
+// ExpiredLeases returns the leased entries whose expiry has passed
+func (v *Vault) ExpiredLeases(now time.Time) (map[string]SecretEntry, error) {
+	entries, err := v.ListSecretEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	expired := make(map[string]SecretEntry)
+	for name, entry := range entries {
+		if entry.Expired(now) {
+			expired[name] = entry
+		}
+	}
+	return expired, nil
+}
+
 // RemoveSecret removes a secret from the vault
 func (v *Vault) RemoveSecret(name string) error {
 	vaultData, masterKey, err := v.load()
@@ -270,8 +608,8 @@ func (v *Vault) RemoveSecret(name string) error {
 		return err
 	}
 
-	if name == MainProvisioningKeyName {
-		return fmt.Errorf("cannot remove main provisioning key")
+	if IsSystemName(CanonicalName(name)) {
+		return fmt.Errorf("cannot remove %s", name)
 	}
 
 	if _, exists := vaultData.Secrets[name]; !exists {
@@ -307,26 +645,90 @@ func (v *Vault) VaultDir() string {
 	return v.vaultDir
 }
 
+// VaultFilePath returns the path to the encrypted vault file
+func (v *Vault) VaultFilePath() string {
+	return v.vaultFile
+}
+
+// KeyFilePath returns the path to the master key file
+func (v *Vault) KeyFilePath() string {
+	return v.keyFile
+}
+
+// AuditLogPath returns the path to the audit log kept alongside the vault
+func (v *Vault) AuditLogPath() string {
+	return filepath.Join(v.vaultDir, DefaultAuditFile)
+}
+
+// AgentSocketPath returns the path of the agent socket for this vault
+func (v *Vault) AgentSocketPath() string {
+	return filepath.Join(v.vaultDir, DefaultAgentSocket)
+}
+
+// ProxyConfigPath returns the path of the proxy configuration for this vault
+func (v *Vault) ProxyConfigPath() string {
+	return filepath.Join(v.vaultDir, DefaultProxyConfigFile)
+}
+
+// UsageLedgerPath returns the path of the proxy's usage ledger for this vault
+func (v *Vault) UsageLedgerPath() string {
+	return filepath.Join(v.vaultDir, DefaultUsageLedgerFile)
+}
+
+// Stat returns file information for the encrypted vault file, which callers
+// caching decrypted secrets can use to notice changes
+func (v *Vault) Stat() (os.FileInfo, error) {
+	return os.Stat(v.vaultFile)
+}
+
+// SecretUpdate is a new value and ID for an existing secret
+type SecretUpdate struct {
+	Value string
+	ID    string
+	// Reason is recorded in the history; empty means ReasonUpdate
+	Reason string
+}
+
 // UpdateSecret updates an existing secret in the vault
 func (v *Vault) UpdateSecret(name, value, id string) error {
+	return v.UpdateSecrets(map[string]SecretUpdate{name: {Value: value, ID: id}})
+}
+
+// UpdateSecrets updates several existing secrets with a single write. Nothing
+// is changed if any of them doesn't exist.
+func (v *Vault) UpdateSecrets(updates map[string]SecretUpdate) error {
 	vaultData, masterKey, err := v.load()
 	if err != nil {
 		return err
 	}
 
-	if _, exists := vaultData.Secrets[name]; !exists {
-		return fmt.Errorf("secret %s not found", name)
+	for name := range updates {
+		if _, exists := vaultData.Secrets[name]; !exists {
+			return fmt.Errorf("secret %s not found", name)
+		}
 	}
 
-	// Encrypt the secret value
-	encryptedValue, err := crypto.Encrypt(masterKey, []byte(value))
-	if err != nil {
-		return fmt.Errorf("failed to encrypt secret: %w", err)
-	}
+	now := time.Now().UTC()
+	for name, update := range updates {
+		// Encrypt the secret value
+		encryptedValue, err := crypto.Encrypt(masterKey, []byte(update.Value))
+		if err != nil {
+			return fmt.Errorf("failed to encrypt secret: %w", err)
+		}
 
-	vaultData.Secrets[name] = SecretEntry{
-		Value: encryptedValue,
-		ID:    id,
+		reason := update.Reason
+		if reason == "" {
+			reason = ReasonUpdate
+		}
+
+		// Keep the rest of the entry (such as its type) intact
+		secret := vaultData.Secrets[name]
+		secret.retire(reason, now)
+		secret.Value = encryptedValue
+		secret.ID = update.ID
+		secret.UpdatedAt = now
+		vaultData.Secrets[name] = secret
+		recordFingerprint(vaultData, name, update.Value)
 	}
 
 	return v.save(vaultData, masterKey)
@@ -354,24 +756,43 @@ func (v *Vault) RotateMasterKey() error {
 		CreatedAt: time.Now(),
 	}
 
-	// Re-encrypt all secrets with new key
-	for id, secret := range vaultData.Secrets {
-		// Decrypt with old key
-		plaintext, err := crypto.Decrypt(currentKey, secret.Value)
+	reencrypt := func(value string) (string, error) {
+		// Decrypt with old key. Versions left behind by earlier rotations
+		// may still be under an older key version.
+		plaintext, err := crypto.Decrypt(currentKey, value)
+		for _, version := range vaultData.KeyVersions {
+			if err == nil {
+				break
+			}
+			if decrypted, oldErr := crypto.Decrypt(version.Key, value); oldErr == nil {
+				plaintext, err = decrypted, nil
+			}
+		}
 		if err != nil {
-			return fmt.Errorf("failed to decrypt secret during rotation: %w", err)
+			return "", fmt.Errorf("failed to decrypt secret during rotation: %w", err)
 		}
 
 		// Re-encrypt with new key
 		newEncrypted, err := crypto.Encrypt(newKey, plaintext)
 		if err != nil {
-			return fmt.Errorf("failed to re-encrypt secret during rotation: %w", err)
+			return "", fmt.Errorf("failed to re-encrypt secret during rotation: %w", err)
 		}
+		return newEncrypted, nil
+	}
 
-		// Update secret with new encrypted value
-		secret.Value = newEncrypted
+	// Re-encrypt all secrets with new key
+	for id, secret := range vaultData.Secrets {
+		if err := reencryptEntry(&secret, reencrypt); err != nil {
+			return err
+		}
 		vaultData.Secrets[id] = secret
 	}
+	// Trashed secrets must stay readable so they can be restored
+	for i := range vaultData.Trash {
+		if err := reencryptEntry(&vaultData.Trash[i].Entry, reencrypt); err != nil {
+			return err
+		}
+	}
 
 	// Update key version information
 	if vaultData.KeyVersions == nil {
@@ -397,6 +818,37 @@ func (v *Vault) RotateMasterKey() error {
 	return nil
 }
 
+// reencryptEntry re-encrypts an entry's value, the earlier versions in its
+// history and a previous version still in its grace period
+func reencryptEntry(entry *SecretEntry, reencrypt func(string) (string, error)) error {
+	value, err := reencrypt(entry.Value)
+	if err != nil {
+		return err
+	}
+	entry.Value = value
+
+	// Copy the history so entries sharing it aren't changed twice
+	history := make([]SecretVersion, len(entry.History))
+	for i, past := range entry.History {
+		if past.Value, err = reencrypt(past.Value); err != nil {
+			return fmt.Errorf("version %d: %w", past.Version, err)
+		}
+		history[i] = past
+	}
+	if entry.History != nil {
+		entry.History = history
+	}
+
+	if entry.Previous != nil {
+		previous := *entry.Previous
+		if previous.Value, err = reencrypt(previous.Value); err != nil {
+			return fmt.Errorf("previous version: %w", err)
+		}
+		entry.Previous = &previous
+	}
+	return nil
+}
+
 // GetCurrentKeyVersion returns the current key version information
 func (v *Vault) GetCurrentKeyVersion() (*KeyVersion, error) {
 	vaultData, _, err := v.load()
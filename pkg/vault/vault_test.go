@@ -1,9 +1,15 @@
 package vault
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/spacebarlabs/lean_vault/pkg/crypto"
 )
 
 func setupTestVault(t *testing.T) (*Vault, func()) {
@@ -361,3 +367,1462 @@ func TestPermissionHandling(t *testing.T) {
 		t.Error("Key rotation with readonly key file should fail")
 	}
 }
+
+func TestStaticSecrets(t *testing.T) {
+	v, cleanup := setupTestVault(t)
+	defer cleanup()
+
+	// Initialize vault
+	if err := v.Init("test-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+
+	// Set a new static secret
+	if err := v.SetStaticSecret("database-url", "postgres://localhost/db"); err != nil {
+		t.Fatalf("Failed to set static secret: %v", err)
+	}
+
+	entry, err := v.GetSecretEntry("database-url")
+	if err != nil {
+		t.Fatalf("Failed to get secret entry: %v", err)
+	}
+	if entry.SecretType() != SecretTypeStatic {
+		t.Errorf("Got wrong secret type: got %v, want %v", entry.SecretType(), SecretTypeStatic)
+	}
+	if entry.IsProvisioned() {
+		t.Error("Static secret should not be provisioned")
+	}
+
+	// Replace the static value
+	if err := v.SetStaticSecret("database-url", "postgres://remote/db"); err != nil {
+		t.Fatalf("Failed to replace static secret: %v", err)
+	}
+	value, err := v.GetSecret("database-url")
+	if err != nil {
+		t.Fatalf("Failed to get static secret: %v", err)
+	}
+	if value != "postgres://remote/db" {
+		t.Errorf("Got wrong secret value: got %v, want %v", value, "postgres://remote/db")
+	}
+
+	// Provisioned keys cannot be overwritten with static values
+	if err := v.AddSecret("api-key", "sk-value", "hash"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+	if err := v.SetStaticSecret("api-key", "other"); err == nil {
+		t.Error("Overwriting a provisioned key with a static value should fail")
+	}
+	if err := v.SetStaticSecret(MainProvisioningKeyName, "other"); err == nil {
+		t.Error("Overwriting the main provisioning key should fail")
+	}
+
+	// Updating keeps the type of the entry
+	if err := v.UpdateSecret("database-url", "postgres://new/db", ""); err != nil {
+		t.Fatalf("Failed to update static secret: %v", err)
+	}
+	entry, err = v.GetSecretEntry("database-url")
+	if err != nil {
+		t.Fatalf("Failed to get secret entry: %v", err)
+	}
+	if entry.SecretType() != SecretTypeStatic {
+		t.Errorf("Update changed secret type to %v", entry.SecretType())
+	}
+
+	// Entries written before types existed are OpenRouter keys
+	legacy := SecretEntry{ID: "legacy-hash"}
+	if legacy.SecretType() != SecretTypeOpenRouter || !legacy.IsProvisioned() {
+		t.Error("Untyped entries should be treated as provisioned OpenRouter keys")
+	}
+}
+
+func TestGetAllSecrets(t *testing.T) {
+	v, cleanup := setupTestVault(t)
+	defer cleanup()
+
+	// Initialize vault
+	if err := v.Init("test-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+	if err := v.AddSecret("test-key", "test-value", "test-id"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+
+	secrets, err := v.GetAllSecrets()
+	if err != nil {
+		t.Fatalf("Failed to get all secrets: %v", err)
+	}
+	if secrets["test-key"] != "test-value" {
+		t.Errorf("Got wrong secret value: got %v, want %v", secrets["test-key"], "test-value")
+	}
+	if secrets[MainProvisioningKeyName] != "test-provisioning-key" {
+		t.Error("Main provisioning key should be included")
+	}
+}
+
+func TestSecretFingerprints(t *testing.T) {
+	v, cleanup := setupTestVault(t)
+	defer cleanup()
+
+	// Initialize vault
+	if err := v.Init("test-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+	if err := v.AddSecret("test-key", "initial-value", "initial-id"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+	if err := v.UpdateSecret("test-key", "updated-value", "updated-id"); err != nil {
+		t.Fatalf("Failed to update secret: %v", err)
+	}
+
+	fingerprints, err := v.SecretFingerprints()
+	if err != nil {
+		t.Fatalf("Failed to get fingerprints: %v", err)
+	}
+
+	// Both the current and the replaced value should be known, by hash only
+	want := map[string]bool{
+		HashValue([]byte("test-provisioning-key")): false,
+		HashValue([]byte("initial-value")):         false,
+		HashValue([]byte("updated-value")):         false,
+	}
+	for _, fp := range fingerprints {
+		if _, ok := want[fp.Hash]; ok {
+			want[fp.Hash] = true
+		}
+	}
+	for hash, found := range want {
+		if !found {
+			t.Errorf("Missing fingerprint %s", hash)
+		}
+	}
+}
+
+func TestLeases(t *testing.T) {
+	v, cleanup := setupTestVault(t)
+	defer cleanup()
+
+	if err := v.Init("test-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+
+	now := time.Now()
+	if err := v.AddLease("ci-job", "sk-lease", "lease-hash", now.Add(time.Hour), 5, DefaultAccount); err != nil {
+		t.Fatalf("Failed to add lease: %v", err)
+	}
+	if err := v.AddSecret("long-lived", "sk-value", "hash"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+
+	entry, err := v.GetSecretEntry("ci-job")
+	if err != nil {
+		t.Fatalf("Failed to get lease entry: %v", err)
+	}
+	if !entry.IsLease() || !entry.IsProvisioned() {
+		t.Error("Lease should be a provisioned lease entry")
+	}
+	if entry.Limit != 5 {
+		t.Errorf("Got wrong limit: got %v, want %v", entry.Limit, 5)
+	}
+
+	// Nothing has expired yet
+	expired, err := v.ExpiredLeases(now)
+	if err != nil {
+		t.Fatalf("Failed to list expired leases: %v", err)
+	}
+	if len(expired) != 0 {
+		t.Errorf("Got %d expired leases before expiry, want 0", len(expired))
+	}
+
+	// Only the lease expires
+	expired, err = v.ExpiredLeases(now.Add(2 * time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to list expired leases: %v", err)
+	}
+	if _, ok := expired["ci-job"]; !ok || len(expired) != 1 {
+		t.Errorf("Got expired leases %v, want only ci-job", expired)
+	}
+
+	// Leases can't reuse an existing name
+	if err := v.AddLease("long-lived", "sk-other", "other-hash", now.Add(time.Hour), 0, DefaultAccount); err == nil {
+		t.Error("Adding a lease with an existing name should fail")
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Duration
+	}{
+		{"2h", 2 * time.Hour},
+		{"30m", 30 * time.Minute},
+		{"7d", 7 * 24 * time.Hour},
+		{"1d12h", 36 * time.Hour},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.input)
+		if err != nil {
+			t.Errorf("ParseDuration(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+
+	for _, input := range []string{"", "d", "xd", "1d1x", "soon"} {
+		if _, err := ParseDuration(input); err == nil {
+			t.Errorf("ParseDuration(%q) should fail", input)
+		}
+	}
+}
+
+func TestSecretTimestamps(t *testing.T) {
+	v, cleanup := setupTestVault(t)
+	defer cleanup()
+
+	if err := v.Init("test-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+	if err := v.AddSecret("api-key", "sk-value", "hash"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+
+	added, err := v.GetSecretEntry("api-key")
+	if err != nil {
+		t.Fatalf("Failed to get secret entry: %v", err)
+	}
+	if added.CreatedAt.IsZero() || !added.UpdatedAt.Equal(added.CreatedAt) {
+		t.Errorf("New entry should have matching timestamps, got created %v updated %v", added.CreatedAt, added.UpdatedAt)
+	}
+
+	// Updating moves UpdatedAt but keeps CreatedAt and the policy
+	policy := &Policy{MaxAge: "30d"}
+	if err := v.SetPolicy("api-key", policy); err != nil {
+		t.Fatalf("Failed to set policy: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := v.UpdateSecret("api-key", "sk-new", "new-hash"); err != nil {
+		t.Fatalf("Failed to update secret: %v", err)
+	}
+	updated, err := v.GetSecretEntry("api-key")
+	if err != nil {
+		t.Fatalf("Failed to get secret entry: %v", err)
+	}
+	if !updated.CreatedAt.Equal(added.CreatedAt) {
+		t.Errorf("Update changed CreatedAt from %v to %v", added.CreatedAt, updated.CreatedAt)
+	}
+	if !updated.UpdatedAt.After(added.UpdatedAt) {
+		t.Errorf("Update should move UpdatedAt past %v, got %v", added.UpdatedAt, updated.UpdatedAt)
+	}
+	if updated.Policy == nil || updated.Policy.MaxAge != "30d" {
+		t.Errorf("Update dropped the policy, got %v", updated.Policy)
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	v, cleanup := setupTestVault(t)
+	defer cleanup()
+
+	if err := v.Init("test-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+	if err := v.AddSecret("api-key", "sk-value", "hash"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+
+	// Invalid policies are rejected
+	invalid := []Policy{
+		{},
+		{MaxAge: "soon"},
+		{MaxAge: "30d", RotateBeforeExpiry: "31d"},
+		{MaxAge: "30d", OnExpiry: "explode"},
+	}
+	for _, policy := range invalid {
+		policy := policy
+		if err := v.SetPolicy("api-key", &policy); err == nil {
+			t.Errorf("SetPolicy(%+v) should fail", policy)
+		}
+	}
+	if err := v.SetPolicy(MainProvisioningKeyName, &Policy{MaxAge: "30d"}); err == nil {
+		t.Error("Setting a policy on the main provisioning key should fail")
+	}
+
+	// Evaluate the policy over the key's life
+	updated := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	entry := SecretEntry{
+		UpdatedAt: updated,
+		Policy:    &Policy{MaxAge: "30d", RotateBeforeExpiry: "3d"},
+	}
+	tests := []struct {
+		now  time.Time
+		want string
+	}{
+		{updated.Add(24 * time.Hour), PolicyOK},
+		{updated.Add(28 * 24 * time.Hour), PolicyDue},
+		{updated.Add(30 * 24 * time.Hour), PolicyExpired},
+	}
+	for _, tt := range tests {
+		state, err := entry.CheckPolicy(tt.now)
+		if err != nil {
+			t.Fatalf("Failed to check policy: %v", err)
+		}
+		if state.Status != tt.want {
+			t.Errorf("CheckPolicy(%v) = %v, want %v", tt.now, state.Status, tt.want)
+		}
+	}
+
+	// Entries without timestamps can't be checked
+	entry.UpdatedAt = time.Time{}
+	state, err := entry.CheckPolicy(updated)
+	if err != nil {
+		t.Fatalf("Failed to check policy: %v", err)
+	}
+	if state.Status != PolicyUnknownAge || !state.Violated() {
+		t.Errorf("Got %v for an entry without timestamps, want %v", state.Status, PolicyUnknownAge)
+	}
+
+	// Clearing the policy
+	if err := v.SetPolicy("api-key", &Policy{MaxAge: "30d"}); err != nil {
+		t.Fatalf("Failed to set policy: %v", err)
+	}
+	if err := v.SetPolicy("api-key", nil); err != nil {
+		t.Fatalf("Failed to clear policy: %v", err)
+	}
+	cleared, err := v.GetSecretEntry("api-key")
+	if err != nil {
+		t.Fatalf("Failed to get secret entry: %v", err)
+	}
+	if cleared.Policy != nil {
+		t.Errorf("Policy should be cleared, got %+v", cleared.Policy)
+	}
+}
+
+func TestUpdateSecrets(t *testing.T) {
+	v, cleanup := setupTestVault(t)
+	defer cleanup()
+
+	if err := v.Init("test-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+	for _, name := range []string{"key-a", "key-b"} {
+		if err := v.AddSecret(name, "old-"+name, "old-id"); err != nil {
+			t.Fatalf("Failed to add secret: %v", err)
+		}
+	}
+
+	// An unknown name leaves every secret untouched
+	err := v.UpdateSecrets(map[string]SecretUpdate{
+		"key-a":   {Value: "new-key-a", ID: "new-id"},
+		"missing": {Value: "value", ID: "id"},
+	})
+	if err == nil {
+		t.Error("Updating a missing secret should fail")
+	}
+	if value, _ := v.GetSecret("key-a"); value != "old-key-a" {
+		t.Errorf("Failed update changed key-a to %v", value)
+	}
+
+	// All updates land together
+	err = v.UpdateSecrets(map[string]SecretUpdate{
+		"key-a": {Value: "new-key-a", ID: "new-id-a"},
+		"key-b": {Value: "new-key-b", ID: "new-id-b"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to update secrets: %v", err)
+	}
+	for _, name := range []string{"key-a", "key-b"} {
+		value, err := v.GetSecret(name)
+		if err != nil {
+			t.Fatalf("Failed to get secret: %v", err)
+		}
+		if value != "new-"+name {
+			t.Errorf("Got wrong value for %s: got %v, want %v", name, value, "new-"+name)
+		}
+		id, err := v.GetSecretID(name)
+		if err != nil {
+			t.Fatalf("Failed to get secret ID: %v", err)
+		}
+		if id != "new-id-"+name[len(name)-1:] {
+			t.Errorf("Got wrong ID for %s: %v", name, id)
+		}
+	}
+}
+
+func TestRotateSecretWithGrace(t *testing.T) {
+	v, cleanup := setupTestVault(t)
+	defer cleanup()
+
+	if err := v.Init("test-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+	if err := v.AddSecret("api-key", "sk-old", "old-id"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+	if _, err := v.GetPreviousSecret("api-key"); err == nil {
+		t.Error("Getting a previous value that doesn't exist should fail")
+	}
+
+	now := time.Now()
+	revokeAt := now.Add(time.Hour)
+	if err := v.RotateSecretWithGrace("api-key", "sk-new", "new-id", revokeAt); err != nil {
+		t.Fatalf("Failed to rotate with grace: %v", err)
+	}
+
+	// Both values are available during the grace period
+	current, err := v.GetSecret("api-key")
+	if err != nil || current != "sk-new" {
+		t.Errorf("Got current value %q (%v), want %q", current, err, "sk-new")
+	}
+	previous, err := v.GetPreviousSecret("api-key")
+	if err != nil || previous != "sk-old" {
+		t.Errorf("Got previous value %q (%v), want %q", previous, err, "sk-old")
+	}
+
+	// A second grace rotation would lose track of the old key
+	if err := v.RotateSecretWithGrace("api-key", "sk-newer", "newer-id", revokeAt); err == nil {
+		t.Error("Rotating with grace while a previous key is pending should fail")
+	}
+
+	// The previous key is due for revocation once the grace period ends
+	pending, err := v.PendingRevocations(now)
+	if err != nil {
+		t.Fatalf("Failed to list pending revocations: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("Got %d pending revocations during the grace period, want 0", len(pending))
+	}
+	pending, err = v.PendingRevocations(revokeAt)
+	if err != nil {
+		t.Fatalf("Failed to list pending revocations: %v", err)
+	}
+	if pending["api-key"].ID != "old-id" {
+		t.Errorf("Got pending revocations %v, want old-id for api-key", pending)
+	}
+
+	// Clearing with a stale ID does nothing
+	if err := v.ClearPrevious("api-key", "other-id"); err != nil {
+		t.Fatalf("Failed to clear previous: %v", err)
+	}
+	if _, err := v.GetPreviousSecret("api-key"); err != nil {
+		t.Error("Clearing with another ID should keep the previous value")
+	}
+	if err := v.ClearPrevious("api-key", "old-id"); err != nil {
+		t.Fatalf("Failed to clear previous: %v", err)
+	}
+	if _, err := v.GetPreviousSecret("api-key"); err == nil {
+		t.Error("Previous value should be gone after clearing")
+	}
+}
+
+func TestSecretHistory(t *testing.T) {
+	v, cleanup := setupTestVault(t)
+	defer cleanup()
+
+	if err := v.Init("test-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+	if err := v.SetStaticSecret("webhook", "value-1"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+	if err := v.SetStaticSecret("webhook", "value-2"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+	if err := v.UpdateSecrets(map[string]SecretUpdate{"webhook": {Value: "value-3", Reason: ReasonRotate}}); err != nil {
+		t.Fatalf("Failed to update secret: %v", err)
+	}
+
+	entry, err := v.GetSecretEntry("webhook")
+	if err != nil {
+		t.Fatalf("Failed to get secret entry: %v", err)
+	}
+	if entry.CurrentVersion() != 3 {
+		t.Errorf("Got current version %d, want 3", entry.CurrentVersion())
+	}
+	if len(entry.History) != 2 || entry.History[0].Version != 1 || entry.History[1].Reason != ReasonRotate {
+		t.Errorf("Got unexpected history %+v", entry.History)
+	}
+
+	// Roll back the mistaken update
+	restored, err := v.RollbackSecret("webhook", 1)
+	if err != nil {
+		t.Fatalf("Failed to roll back: %v", err)
+	}
+	if restored.Version != 1 {
+		t.Errorf("Restored version %d, want 1", restored.Version)
+	}
+	value, err := v.GetSecret("webhook")
+	if err != nil || value != "value-1" {
+		t.Errorf("Got value %q (%v) after rollback, want %q", value, err, "value-1")
+	}
+	entry, err = v.GetSecretEntry("webhook")
+	if err != nil {
+		t.Fatalf("Failed to get secret entry: %v", err)
+	}
+	if entry.CurrentVersion() != 4 || entry.History[len(entry.History)-1].Version != 3 {
+		t.Errorf("Rollback should record version 3 and become version 4, got version %d with history %+v", entry.CurrentVersion(), entry.History)
+	}
+
+	if _, err := v.RollbackSecret("webhook", 4); err == nil {
+		t.Error("Rolling back to the current version should fail")
+	}
+	if _, err := v.RollbackSecret("webhook", 42); err == nil {
+		t.Error("Rolling back to an unknown version should fail")
+	}
+
+	// History is bounded
+	for i := 0; i < MaxHistory+5; i++ {
+		if err := v.SetStaticSecret("webhook", "value"); err != nil {
+			t.Fatalf("Failed to set secret: %v", err)
+		}
+	}
+	entry, err = v.GetSecretEntry("webhook")
+	if err != nil {
+		t.Fatalf("Failed to get secret entry: %v", err)
+	}
+	if len(entry.History) != MaxHistory {
+		t.Errorf("Got %d history entries, want %d", len(entry.History), MaxHistory)
+	}
+	if newest := entry.History[len(entry.History)-1].Version; newest != entry.CurrentVersion()-1 {
+		t.Errorf("Newest history version %d should precede current version %d", newest, entry.CurrentVersion())
+	}
+}
+
+func TestRollbackCancelsGraceRevocation(t *testing.T) {
+	v, cleanup := setupTestVault(t)
+	defer cleanup()
+
+	if err := v.Init("test-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+	if err := v.AddSecret("api-key", "sk-old", "old-id"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+	if err := v.RotateSecretWithGrace("api-key", "sk-new", "new-id", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to rotate with grace: %v", err)
+	}
+
+	if _, err := v.RollbackSecret("api-key", 1); err != nil {
+		t.Fatalf("Failed to roll back: %v", err)
+	}
+	entry, err := v.GetSecretEntry("api-key")
+	if err != nil {
+		t.Fatalf("Failed to get secret entry: %v", err)
+	}
+	if entry.ID != "old-id" {
+		t.Errorf("Got ID %q after rollback, want %q", entry.ID, "old-id")
+	}
+	if entry.Previous != nil {
+		t.Error("Rolling back to the previous version should cancel its revocation")
+	}
+}
+
+func TestAuditKeys(t *testing.T) {
+	v, cleanup := setupTestVault(t)
+	defer cleanup()
+
+	if err := v.Init("test-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+
+	oldID, oldKey, err := v.AuditKey()
+	if err != nil {
+		t.Fatalf("Failed to get audit key: %v", err)
+	}
+	if err := v.RotateMasterKey(); err != nil {
+		t.Fatalf("Failed to rotate master key: %v", err)
+	}
+	newID, _, err := v.AuditKey()
+	if err != nil {
+		t.Fatalf("Failed to get audit key: %v", err)
+	}
+	if newID == oldID {
+		t.Error("Audit key should change with the master key")
+	}
+
+	// Keys from before the rotation are still available for verification
+	keys, err := v.AuditKeys()
+	if err != nil {
+		t.Fatalf("Failed to get audit keys: %v", err)
+	}
+	if string(keys[oldID]) != string(oldKey) || keys[newID] == nil {
+		t.Errorf("Audit keys should include the old and new keys, got IDs %v", sortedKeys(keys))
+	}
+}
+
+func sortedKeys(m map[string][]byte) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestDiagnose(t *testing.T) {
+	v, cleanup := setupTestVault(t)
+	defer cleanup()
+
+	problems := func() []string {
+		var messages []string
+		for _, d := range v.Diagnose() {
+			if d.Severity == SeverityProblem {
+				messages = append(messages, d.Message)
+			}
+		}
+		return messages
+	}
+
+	if found := problems(); len(found) != 1 {
+		t.Errorf("A missing vault should be one problem, got %v", found)
+	}
+
+	if err := v.Init("test-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+	if err := v.AddSecret("my-key", "test-value", "test-id"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+	if found := problems(); len(found) != 0 {
+		t.Errorf("A healthy vault should have no problems, got %v", found)
+	}
+
+	// Two entries sharing a provider key
+	if err := v.AddSecret("copy", "test-value", "test-id"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+	if found := problems(); len(found) != 1 {
+		t.Errorf("A shared provider key should be one problem, got %v", found)
+	}
+	if err := v.RemoveSecret("copy"); err != nil {
+		t.Fatalf("Failed to remove secret: %v", err)
+	}
+
+	// A secret that no longer decrypts
+	vaultData, masterKey, err := v.load()
+	if err != nil {
+		t.Fatalf("Failed to load vault: %v", err)
+	}
+	entry := vaultData.Secrets["my-key"]
+	entry.Value = "corrupted"
+	vaultData.Secrets["my-key"] = entry
+	if err := v.save(vaultData, masterKey); err != nil {
+		t.Fatalf("Failed to save vault: %v", err)
+	}
+	if found := problems(); len(found) != 1 {
+		t.Errorf("An undecryptable secret should be one problem, got %v", found)
+	}
+
+	// A truncated key file stops the remaining checks
+	if err := os.WriteFile(v.keyFile, masterKey[:16], DefaultFileMode); err != nil {
+		t.Fatalf("Failed to truncate key file: %v", err)
+	}
+	if found := problems(); len(found) != 1 {
+		t.Errorf("A truncated key should be one problem, got %v", found)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(ProfileEnvVar, "")
+
+	if name, err := ActiveProfile(); err != nil || name != DefaultProfile {
+		t.Errorf("Active profile should default to %s, got %q (%v)", DefaultProfile, name, err)
+	}
+	if got, want := mustNew(t).VaultDir(), filepath.Join(home, DefaultVaultDir); got != want {
+		t.Errorf("Default profile should use the original location: got %v, want %v", got, want)
+	}
+
+	if err := mustNew(t).Init("default-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize default vault: %v", err)
+	}
+	prod := NewProfile("prod")
+	if err := os.MkdirAll(prod.VaultDir(), DefaultDirMode); err != nil {
+		t.Fatalf("Failed to create profile directory: %v", err)
+	}
+	if err := prod.Init("prod-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize prod vault: %v", err)
+	}
+
+	if err := UseProfile("staging"); err == nil {
+		t.Error("Using a profile that doesn't exist should fail")
+	}
+	if err := UseProfile("prod"); err != nil {
+		t.Fatalf("Failed to use profile: %v", err)
+	}
+	key, err := mustNew(t).GetMainProvisioningKey()
+	if err != nil || key != "prod-provisioning-key" {
+		t.Errorf("Vault should use the chosen profile: got %q (%v)", key, err)
+	}
+
+	// The environment overrides the chosen profile
+	t.Setenv(ProfileEnvVar, DefaultProfile)
+	key, err = mustNew(t).GetMainProvisioningKey()
+	if err != nil || key != "default-provisioning-key" {
+		t.Errorf("%s should override the chosen profile: got %q (%v)", ProfileEnvVar, key, err)
+	}
+
+	t.Setenv(ProfileEnvVar, "../elsewhere")
+	if _, err := ActiveProfile(); err == nil {
+		t.Error("An invalid profile name should be rejected")
+	}
+
+	profiles, err := ListProfiles()
+	if err != nil {
+		t.Fatalf("Failed to list profiles: %v", err)
+	}
+	if len(profiles) != 2 || profiles[0].Name != DefaultProfile || profiles[1].Name != "prod" || !profiles[1].Initialized {
+		t.Errorf("Got wrong profiles: %+v", profiles)
+	}
+}
+
+func TestSettings(t *testing.T) {
+	v, cleanup := setupTestVault(t)
+	defer cleanup()
+
+	settings, err := v.LoadSettings()
+	if err != nil || settings.APIBaseURL != "" {
+		t.Errorf("Missing settings should be empty: got %+v (%v)", settings, err)
+	}
+
+	if err := os.MkdirAll(v.VaultDir(), DefaultDirMode); err != nil {
+		t.Fatalf("Failed to create vault directory: %v", err)
+	}
+	if err := os.WriteFile(v.SettingsPath(), []byte("api_base_url: http://localhost:8080/v1\n"), DefaultFileMode); err != nil {
+		t.Fatalf("Failed to write settings: %v", err)
+	}
+	settings, err = v.LoadSettings()
+	if err != nil || settings.APIBaseURL != "http://localhost:8080/v1" {
+		t.Errorf("Got wrong settings: %+v (%v)", settings, err)
+	}
+	if got := settings.TrashRetention(); got != DefaultTrashRetentionDays*24*time.Hour {
+		t.Errorf("Default trash retention = %v", got)
+	}
+
+	if err := os.WriteFile(v.SettingsPath(), []byte("trash_retention_days: 0\n"), DefaultFileMode); err != nil {
+		t.Fatalf("Failed to write settings: %v", err)
+	}
+	settings, err = v.LoadSettings()
+	if err != nil || settings.TrashRetention() != 0 {
+		t.Errorf("trash_retention_days: 0 should keep trash forever: %+v (%v)", settings, err)
+	}
+
+	if err := os.WriteFile(v.SettingsPath(), []byte("trash_retention_days: -1\n"), DefaultFileMode); err != nil {
+		t.Fatalf("Failed to write settings: %v", err)
+	}
+	if _, err := v.LoadSettings(); err == nil {
+		t.Error("Negative trash retention should be rejected")
+	}
+}
+
+func TestProjectVault(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(ProfileEnvVar, "")
+	t.Setenv(VaultDirEnvVar, "")
+
+	root := t.TempDir()
+	nested := filepath.Join(root, "services", "api")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+	manifest := filepath.Join(root, ProjectFile)
+	if err := os.WriteFile(manifest, []byte("key_file: ~/keys/project.key\n"), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	found, err := FindProject(nested)
+	if err != nil || found != manifest {
+		t.Errorf("Manifest should be found from a subdirectory: got %q (%v)", found, err)
+	}
+	if found, _ := FindProject(home); found != "" {
+		t.Errorf("No manifest should be found outside the project, got %q", found)
+	}
+
+	t.Chdir(nested)
+	v, err := Resolve()
+	if err != nil {
+		t.Fatalf("Failed to resolve vault: %v", err)
+	}
+	if v.ProjectFile() != manifest || v.VaultDir() != filepath.Join(root, DefaultProjectVaultDir) ||
+		v.KeyFilePath() != filepath.Join(home, "keys", "project.key") {
+		t.Errorf("Got wrong project vault: dir %v, key %v", v.VaultDir(), v.KeyFilePath())
+	}
+	if err := v.Init("project-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize project vault: %v", err)
+	}
+	if key, err := mustNew(t).GetMainProvisioningKey(); err != nil || key != "project-provisioning-key" {
+		t.Errorf("New should use the project vault: got %q (%v)", key, err)
+	}
+
+	// A profile or directory named explicitly overrides the project
+	t.Setenv(ProfileEnvVar, DefaultProfile)
+	if v, _ := Resolve(); v.ProjectFile() != "" || v.ProfileName() != DefaultProfile {
+		t.Errorf("%s should override the project vault", ProfileEnvVar)
+	}
+	t.Setenv(VaultDirEnvVar, filepath.Join(root, "other"))
+	if v, _ := Resolve(); v.VaultDir() != filepath.Join(root, "other") {
+		t.Errorf("%s should override profiles and projects, got %v", VaultDirEnvVar, v.VaultDir())
+	}
+
+	// The key file has no default, so it can't end up in the repository by accident
+	t.Setenv(ProfileEnvVar, "")
+	t.Setenv(VaultDirEnvVar, "")
+	if err := os.WriteFile(manifest, []byte("vault_dir: secrets\n"), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	if _, err := Resolve(); err == nil {
+		t.Error("A manifest without key_file should be rejected")
+	}
+	if _, err := New(); err == nil {
+		t.Error("New should report an invalid manifest instead of falling back to another vault")
+	}
+}
+
+// mustNew returns the vault New resolves to, failing the test on error
+func mustNew(t *testing.T) *Vault {
+	t.Helper()
+	v, err := New()
+	if err != nil {
+		t.Fatalf("Failed to resolve vault: %v", err)
+	}
+	return v
+}
+
+func TestSetMetadata(t *testing.T) {
+	v, cleanup := setupTestVault(t)
+	defer cleanup()
+
+	if err := v.Init("test-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+	if err := v.AddSecret("my-key", "test-value", "test-id"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+
+	tags := map[string]string{"team": "ml"}
+	metadata := Metadata{Limit: 10, Tags: tags, Policy: &Policy{MaxAge: "30d"}, Managed: true}
+	if err := v.SetMetadata("my-key", metadata); err != nil {
+		t.Fatalf("Failed to set metadata: %v", err)
+	}
+	tags["team"] = "changed"
+
+	entry, err := v.GetSecretEntry("my-key")
+	if err != nil {
+		t.Fatalf("Failed to get secret: %v", err)
+	}
+	got := entry.Metadata()
+	if got.Limit != 10 || got.Tags["team"] != "ml" || got.Policy == nil || !got.Managed {
+		t.Errorf("Got wrong metadata: %+v", got)
+	}
+	if value, _ := v.GetSecret("my-key"); value != "test-value" || entry.Version > 1 {
+		t.Errorf("Setting metadata should not change the value: got %q, version %d", value, entry.Version)
+	}
+
+	if err := v.SetMetadata("my-key", Metadata{Policy: &Policy{MaxAge: "soon"}}); err == nil {
+		t.Error("An invalid policy should be rejected")
+	}
+	if err := v.SetMetadata(MainProvisioningKeyName, Metadata{}); err == nil {
+		t.Error("Setting metadata on the main provisioning key should fail")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	v, cleanup := setupTestVault(t)
+	defer cleanup()
+
+	if err := v.Init("test-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+	for _, name := range []string{"c-key", "a-key", "b-key"} {
+		if err := v.AddSecret(name, "value-"+name, "id-"+name); err != nil {
+			t.Fatalf("Failed to add secret: %v", err)
+		}
+	}
+	if err := v.SetMetadata("a-key", Metadata{Owner: "alice", Tags: map[string]string{"team": "ml", "env": "prod"}}); err != nil {
+		t.Fatalf("Failed to set metadata: %v", err)
+	}
+	if err := v.SetMetadata("b-key", Metadata{Owner: "bob", Tags: map[string]string{"team": "ml"}}); err != nil {
+		t.Fatalf("Failed to set metadata: %v", err)
+	}
+
+	names, err := v.ListSecrets()
+	if err != nil {
+		t.Fatalf("Failed to list secrets: %v", err)
+	}
+	if strings.Join(names, ",") != "a-key,b-key,c-key" {
+		t.Errorf("ListSecrets should be sorted, got %v", names)
+	}
+
+	entries, err := v.ListSecretEntries()
+	if err != nil {
+		t.Fatalf("Failed to list secrets: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   string
+	}{
+		{name: "Empty filter", filter: Filter{}, want: "a-key,b-key,c-key"},
+		{name: "Tag", filter: Filter{Tags: map[string]string{"team": "ml"}}, want: "a-key,b-key"},
+		{name: "All tags must match", filter: Filter{Tags: map[string]string{"team": "ml", "env": "prod"}}, want: "a-key"},
+		{name: "Owner", filter: Filter{Owner: "bob"}, want: "b-key"},
+		{name: "Tag and owner", filter: Filter{Owner: "bob", Tags: map[string]string{"env": "prod"}}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched := FilterEntries(entries, tt.filter)
+			names := make([]string, 0, len(matched))
+			for name := range matched {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if got := strings.Join(names, ","); got != tt.want {
+				t.Errorf("Got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if key, value, err := ParseTag("team=ml=ops"); err != nil || key != "team" || value != "ml=ops" {
+		t.Errorf("ParseTag() = %q, %q, %v", key, value, err)
+	}
+	for _, bad := range []string{"team", "=ml", "a b=c"} {
+		if _, _, err := ParseTag(bad); err == nil {
+			t.Errorf("ParseTag(%q) should fail", bad)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	for _, name := range []string{"chatbot", "prod/chatbot/openrouter", "a.b_c-d/e"} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("ValidateName(%q) failed: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "/prod", "prod/", "prod//db", "prod/../db", "./db", "my key", "_system/x", LegacyMainProvisioningKeyName} {
+		if err := ValidateName(name); err == nil {
+			t.Errorf("ValidateName(%q) should fail", name)
+		}
+	}
+	for in, want := range map[string]string{"": "", "/": "", "prod": "prod/", "prod/chatbot/": "prod/chatbot/"} {
+		if got := NormalizePrefix(in); got != want {
+			t.Errorf("NormalizePrefix(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	v, cleanup := setupTestVault(t)
+	defer cleanup()
+
+	if err := v.Init("test-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+	for _, name := range []string{"prod/chatbot/openrouter", "prod/db", "production"} {
+		if err := v.AddSecret(name, "value-"+name, "id-"+name); err != nil {
+			t.Fatalf("Failed to add secret %q: %v", name, err)
+		}
+	}
+	if err := v.AddSecret("bad//name", "value", "id"); err == nil {
+		t.Error("AddSecret should reject an invalid name")
+	}
+	if err := v.SetStaticSecret(MainProvisioningKeyName, "value"); err == nil {
+		t.Error("SetStaticSecret should reject a system name")
+	}
+	if err := v.RemoveSecret(MainProvisioningKeyName); err == nil {
+		t.Error("RemoveSecret should reject a system name")
+	}
+
+	entries, err := v.ListSecretEntries()
+	if err != nil {
+		t.Fatalf("Failed to list secrets: %v", err)
+	}
+	matched := FilterEntries(entries, Filter{Prefix: "prod/"})
+	if _, ok := matched["production"]; len(matched) != 2 || ok {
+		t.Errorf("Prefix prod/ should match whole segments only, got %v", matched)
+	}
+
+	// A vault written before the system namespace stores the provisioning
+	// key under its old name
+	vaultData, masterKey, err := v.load()
+	if err != nil {
+		t.Fatalf("Failed to load vault: %v", err)
+	}
+	vaultData.Secrets[LegacyMainProvisioningKeyName] = vaultData.Secrets[MainProvisioningKeyName]
+	delete(vaultData.Secrets, MainProvisioningKeyName)
+	if err := v.save(vaultData, masterKey); err != nil {
+		t.Fatalf("Failed to save vault: %v", err)
+	}
+
+	if key, err := v.GetMainProvisioningKey(); err != nil || key != "test-provisioning-key" {
+		t.Errorf("GetMainProvisioningKey() = %q, %v after migration", key, err)
+	}
+	names, err := v.ListSecrets()
+	if err != nil {
+		t.Fatalf("Failed to list secrets: %v", err)
+	}
+	if strings.Join(names, ",") != "prod/chatbot/openrouter,prod/db,production" {
+		t.Errorf("ListSecrets should hide system names, got %v", names)
+	}
+
+	// A different value under the old name is never dropped
+	vaultData, masterKey, err = v.load()
+	if err != nil {
+		t.Fatalf("Failed to load vault: %v", err)
+	}
+	legacy, err := crypto.Encrypt(masterKey, []byte("other-provisioning-key"))
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	vaultData.Secrets[LegacyMainProvisioningKeyName] = SecretEntry{Value: legacy}
+	if err := v.save(vaultData, masterKey); err != nil {
+		t.Fatalf("Failed to save vault: %v", err)
+	}
+	if _, err := v.GetMainProvisioningKey(); err == nil || !strings.Contains(err.Error(), LegacyMainProvisioningKeyName) || !strings.Contains(err.Error(), MainProvisioningKeyName) {
+		t.Errorf("Loading conflicting provisioning keys should fail naming both, got %v", err)
+	}
+}
+
+func TestRenameAndCopy(t *testing.T) {
+	v, cleanup := setupTestVault(t)
+	defer cleanup()
+
+	if err := v.Init("test-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+	if err := v.AddSecret("chatbot", "value-1", "id-1"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+	if err := v.UpdateSecret("chatbot", "value-2", "id-2"); err != nil {
+		t.Fatalf("Failed to update secret: %v", err)
+	}
+	if err := v.SetMetadata("chatbot", Metadata{Owner: "alice", Tags: map[string]string{"team": "ml"}, Managed: true}); err != nil {
+		t.Fatalf("Failed to set metadata: %v", err)
+	}
+	if err := v.AddSecret("other", "value", "id-other"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+
+	if err := v.RenameSecret("chatbot", "prod/chatbot"); err != nil {
+		t.Fatalf("Failed to rename secret: %v", err)
+	}
+	if _, err := v.GetSecretEntry("chatbot"); err == nil {
+		t.Error("Old name should be gone after a rename")
+	}
+	entry, err := v.GetSecretEntry("prod/chatbot")
+	if err != nil {
+		t.Fatalf("Failed to get renamed secret: %v", err)
+	}
+	if entry.ID != "id-2" || entry.Owner != "alice" || !entry.Managed || len(entry.History) != 1 || entry.CurrentVersion() != 2 {
+		t.Errorf("Rename should keep the entry, got %+v", entry)
+	}
+	if value, err := v.GetSecret("prod/chatbot"); err != nil || value != "value-2" {
+		t.Errorf("GetSecret() = %q, %v after rename", value, err)
+	}
+
+	// Two entries must never share a provider key
+	if err := v.CopySecret("prod/chatbot", "staging/chatbot"); err == nil {
+		t.Fatal("CopySecret should refuse a provisioned key")
+	}
+	if err := v.CopyProvisionedSecret("prod/chatbot", "staging/chatbot", "value-3", "id-3"); err != nil {
+		t.Fatalf("Failed to copy secret: %v", err)
+	}
+	copied, err := v.GetSecretEntry("staging/chatbot")
+	if err != nil {
+		t.Fatalf("Failed to get copy: %v", err)
+	}
+	if copied.ID != "id-3" || copied.Tags["team"] != "ml" || len(copied.History) != 0 || copied.Managed {
+		t.Errorf("Copy should have its own key and history and keep the labels, but not be managed, got %+v", copied)
+	}
+	if value, err := v.GetSecret("staging/chatbot"); err != nil || value != "value-3" {
+		t.Errorf("GetSecret() = %q, %v for copy", value, err)
+	}
+
+	if err := v.SetStaticSecret("db", "postgres://one"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+	if err := v.SetStaticSecret("db", "postgres://two"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+	if err := v.CopyProvisionedSecret("db", "db-copy", "value", "id"); err == nil {
+		t.Error("CopyProvisionedSecret should refuse a static secret")
+	}
+	if err := v.CopySecret("db", "db-copy"); err != nil {
+		t.Fatalf("Failed to copy static secret: %v", err)
+	}
+	if entry, _ := v.GetSecretEntry("db-copy"); len(entry.History) != 1 {
+		t.Errorf("Static copy should keep the history, got %+v", entry.History)
+	}
+	if value, err := v.GetSecret("db-copy"); err != nil || value != "postgres://two" {
+		t.Errorf("GetSecret() = %q, %v for static copy", value, err)
+	}
+	for _, d := range v.Diagnose() {
+		if strings.Contains(d.Message, "is shared by") {
+			t.Errorf("Copies should not share provider keys: %s", d.Message)
+		}
+	}
+
+	// Changing the copy leaves the original alone
+	if err := v.SetMetadata("staging/chatbot", Metadata{Tags: map[string]string{"team": "ops"}}); err != nil {
+		t.Fatalf("Failed to set metadata: %v", err)
+	}
+	if entry, _ := v.GetSecretEntry("prod/chatbot"); entry.Tags["team"] != "ml" {
+		t.Errorf("Original tags changed with the copy: %v", entry.Tags)
+	}
+
+	tests := []struct {
+		name     string
+		from, to string
+	}{
+		{name: "Missing source", from: "missing", to: "new"},
+		{name: "Existing destination", from: "other", to: "prod/chatbot"},
+		{name: "Invalid destination", from: "other", to: "bad//name"},
+		{name: "System source", from: MainProvisioningKeyName, to: "stolen"},
+		{name: "System destination", from: "other", to: MainProvisioningKeyName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := v.RenameSecret(tt.from, tt.to); err == nil {
+				t.Error("RenameSecret should fail")
+			}
+			if err := v.CopySecret(tt.from, tt.to); err == nil {
+				t.Error("CopySecret should fail")
+			}
+		})
+	}
+}
+
+func TestTrash(t *testing.T) {
+	v, cleanup := setupTestVault(t)
+	defer cleanup()
+
+	if err := v.Init("test-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+	if err := v.AddSecret("chatbot", "value-1", "id-1"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+	if err := v.SetMetadata("chatbot", Metadata{Owner: "alice"}); err != nil {
+		t.Fatalf("Failed to set metadata: %v", err)
+	}
+	if err := v.SetStaticSecret("db", "postgres://"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+
+	if err := v.TrashSecret("chatbot", false, false); err != nil {
+		t.Fatalf("Failed to trash secret: %v", err)
+	}
+	if err := v.TrashSecret("db", true, false); err != nil {
+		t.Fatalf("Failed to trash secret: %v", err)
+	}
+	if err := v.TrashSecret(MainProvisioningKeyName, false, false); err == nil {
+		t.Error("TrashSecret should reject a system name")
+	}
+	if _, err := v.GetSecretEntry("chatbot"); err == nil {
+		t.Error("Trashed secret should be gone from the vault")
+	}
+
+	trash, err := v.ListTrash()
+	if err != nil {
+		t.Fatalf("Failed to list trash: %v", err)
+	}
+	if len(trash) != 2 || trash[0].Name != "chatbot" || trash[0].Entry.ID != "id-1" || trash[0].Entry.Owner != "alice" {
+		t.Fatalf("Trash should keep the entries with their metadata, got %+v", trash)
+	}
+	if !trash[0].NeedsRevocation() || trash[1].NeedsRevocation() {
+		t.Error("Only the unrevoked provisioned key needs revocation")
+	}
+
+	// Trashed values survive a master key rotation
+	if err := v.RotateMasterKey(); err != nil {
+		t.Fatalf("Failed to rotate master key: %v", err)
+	}
+
+	if err := v.AddSecret("chatbot", "value-2", "id-2"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+	if _, err := v.RestoreSecret("chatbot", ""); err == nil {
+		t.Error("Restore should refuse to overwrite an existing secret")
+	}
+	if _, err := v.RestoreSecret("chatbot", "chatbot-old"); err != nil {
+		t.Fatalf("Failed to restore secret: %v", err)
+	}
+	if value, err := v.GetSecret("chatbot-old"); err != nil || value != "value-1" {
+		t.Errorf("GetSecret() = %q, %v after restore", value, err)
+	}
+	if _, err := v.RestoreSecret("chatbot", ""); err == nil {
+		t.Error("A restored secret should leave the trash")
+	}
+
+	now := time.Now()
+	if expired, err := v.ExpiredTrash(time.Hour, now); err != nil || len(expired) != 0 {
+		t.Errorf("ExpiredTrash() = %v, %v before the retention passed", expired, err)
+	}
+	if expired, err := v.ExpiredTrash(0, now.Add(time.Hour)); err != nil || len(expired) != 0 {
+		t.Errorf("ExpiredTrash() = %v, %v with no retention", expired, err)
+	}
+	expired, err := v.ExpiredTrash(time.Hour, now.Add(2*time.Hour))
+	if err != nil || len(expired) != 1 || expired[0].Name != "db" {
+		t.Fatalf("ExpiredTrash() = %v, %v after the retention passed", expired, err)
+	}
+
+	purged, err := v.PurgeTrash(expired[0].Same)
+	if err != nil || len(purged) != 1 {
+		t.Fatalf("PurgeTrash() = %v, %v", purged, err)
+	}
+	if trash, _ := v.ListTrash(); len(trash) != 0 {
+		t.Errorf("Trash should be empty, got %+v", trash)
+	}
+}
+
+func TestSetProvisioningKey(t *testing.T) {
+	v, cleanup := setupTestVault(t)
+	defer cleanup()
+
+	if err := v.Init("old-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+	if err := v.AddSecret("chatbot", "value", "id-1"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+
+	if err := v.SetProvisioningKey(DefaultAccount, "", ReasonSet); err == nil {
+		t.Error("An empty provisioning key should be rejected")
+	}
+	if err := v.SetProvisioningKey(DefaultAccount, "new-provisioning-key", ReasonRotate); err != nil {
+		t.Fatalf("Failed to set provisioning key: %v", err)
+	}
+
+	if key, err := v.GetMainProvisioningKey(); err != nil || key != "new-provisioning-key" {
+		t.Errorf("GetMainProvisioningKey() = %q, %v", key, err)
+	}
+	entry, err := v.GetSecretEntry(MainProvisioningKeyName)
+	if err != nil {
+		t.Fatalf("Failed to get provisioning key entry: %v", err)
+	}
+	if entry.CurrentVersion() != 2 || len(entry.History) != 1 || entry.History[0].Reason != ReasonRotate {
+		t.Errorf("Old provisioning key should be kept in history, got %+v", entry)
+	}
+	if value, err := v.GetSecret("chatbot"); err != nil || value != "value" {
+		t.Errorf("Stored keys should be untouched, got %q, %v", value, err)
+	}
+
+	// The old key stays detectable by leak scans
+	fingerprints, err := v.SecretFingerprints()
+	if err != nil {
+		t.Fatalf("Failed to get fingerprints: %v", err)
+	}
+	found := false
+	for _, fp := range fingerprints {
+		if fp.Hash == HashValue([]byte("old-provisioning-key")) {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("The replaced provisioning key should keep its fingerprint")
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	v, cleanup := setupTestVault(t)
+	defer cleanup()
+
+	if err := v.Init("default-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+
+	for _, name := range []string{"", "..", "a/b", "has space"} {
+		if err := v.AddAccount(name, "key"); err == nil {
+			t.Errorf("AddAccount(%q) should be rejected", name)
+		}
+	}
+	if err := v.AddAccountSecret("acme/ci", "value", "id-1", "acme"); err == nil {
+		t.Error("A secret can't belong to an account that doesn't exist")
+	}
+
+	if err := v.AddAccount("acme", "acme-provisioning-key"); err != nil {
+		t.Fatalf("Failed to add account: %v", err)
+	}
+	if err := v.AddAccount("acme", "other-key"); err == nil {
+		t.Error("Adding an existing account should fail")
+	}
+	if key, err := v.GetProvisioningKey("acme"); err != nil || key != "acme-provisioning-key" {
+		t.Errorf("GetProvisioningKey(acme) = %q, %v", key, err)
+	}
+	if key, err := v.GetProvisioningKey(DefaultAccount); err != nil || key != "default-provisioning-key" {
+		t.Errorf("GetProvisioningKey(default) = %q, %v", key, err)
+	}
+
+	if err := v.AddAccountSecret("acme/ci", "value", "id-1", "acme"); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+	if err := v.AddAccountSecret("chatbot", "value", "id-2", DefaultAccount); err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+	entry, err := v.GetSecretEntry("acme/ci")
+	if err != nil || entry.AccountName() != "acme" {
+		t.Fatalf("GetSecretEntry() = %+v, %v; want account acme", entry, err)
+	}
+	if entry, _ := v.GetSecretEntry("chatbot"); entry.Account != "" || entry.AccountName() != DefaultAccount {
+		t.Errorf("Default account keys should not record an account, got %q", entry.Account)
+	}
+
+	accounts, err := v.ListAccounts()
+	if err != nil {
+		t.Fatalf("Failed to list accounts: %v", err)
+	}
+	if len(accounts) != 2 || accounts[0].Name != "acme" || accounts[0].Keys != 1 ||
+		accounts[1].Name != DefaultAccount || accounts[1].Keys != 1 {
+		t.Errorf("ListAccounts() = %+v", accounts)
+	}
+
+	if err := v.RemoveAccount(DefaultAccount); err == nil {
+		t.Error("The default account can't be removed")
+	}
+	if err := v.RemoveAccount("acme"); err == nil {
+		t.Error("An account in use can't be removed")
+	}
+	if err := v.TrashSecret("acme/ci", false, false); err != nil {
+		t.Fatalf("Failed to trash secret: %v", err)
+	}
+	if err := v.RemoveAccount("acme"); err == nil {
+		t.Error("An account with unrevoked keys in the trash can't be removed")
+	}
+	if _, err := v.PurgeTrash(func(TrashedSecret) bool { return true }); err != nil {
+		t.Fatalf("Failed to purge trash: %v", err)
+	}
+	if err := v.RemoveAccount("acme"); err != nil {
+		t.Fatalf("Failed to remove account: %v", err)
+	}
+	if _, err := v.GetProvisioningKey("acme"); err == nil {
+		t.Error("A removed account should have no provisioning key")
+	}
+}
+
+func TestInitWith(t *testing.T) {
+	v, cleanup := setupTestVault(t)
+	defer cleanup()
+
+	if err := v.InitWith(InitOptions{MasterKey: []byte("too short")}); err == nil {
+		t.Error("A master key of the wrong size should be rejected")
+	}
+
+	// A pre-generated key already in place is used as is
+	masterKey := bytes.Repeat([]byte{7}, crypto.KeySize)
+	if err := os.MkdirAll(v.vaultDir, DefaultDirMode); err != nil {
+		t.Fatalf("Failed to create vault dir: %v", err)
+	}
+	if err := os.WriteFile(v.keyFile, []byte(strings.Repeat("x", crypto.KeySize)), DefaultFileMode); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+	if err := v.InitWith(InitOptions{MasterKey: masterKey}); err == nil {
+		t.Error("A different key file should not be overwritten")
+	}
+	if err := os.WriteFile(v.keyFile, masterKey, DefaultFileMode); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+	if err := v.InitWith(InitOptions{MasterKey: masterKey}); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+	if err := v.InitWith(InitOptions{MasterKey: masterKey}); err == nil {
+		t.Error("An existing vault should not be overwritten")
+	}
+
+	if key, err := os.ReadFile(v.keyFile); err != nil || !bytes.Equal(key, masterKey) {
+		t.Errorf("Key file should hold the given master key, got %v, %v", key, err)
+	}
+	if _, err := v.GetMainProvisioningKey(); err == nil {
+		t.Error("A vault created without a provisioning key should not have one")
+	}
+	if names, err := v.ListSecrets(); err != nil || len(names) != 0 {
+		t.Errorf("ListSecrets() = %v, %v for an empty vault", names, err)
+	}
+	if err := v.SetStaticSecret("db", "postgres://"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+	for _, d := range v.Diagnose() {
+		if d.Severity == SeverityProblem {
+			t.Errorf("Static-only vault should have no problems, got %s", d.Message)
+		}
+	}
+}
+
+func TestRotateMasterKeyKeepsVersions(t *testing.T) {
+	v, cleanup := setupTestVault(t)
+	defer cleanup()
+
+	if err := v.Init("test-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+	for _, value := range []string{"postgres://one", "postgres://two"} {
+		if err := v.SetStaticSecret("db", value); err != nil {
+			t.Fatalf("Failed to set secret: %v", err)
+		}
+		if err := v.SetStaticSecret("old-db", value); err != nil {
+			t.Fatalf("Failed to set secret: %v", err)
+		}
+	}
+	if err := v.TrashSecret("old-db", true, false); err != nil {
+		t.Fatalf("Failed to trash secret: %v", err)
+	}
+
+	if err := v.RotateMasterKey(); err != nil {
+		t.Fatalf("Failed to rotate master key: %v", err)
+	}
+
+	if _, err := v.RollbackSecret("db", 1); err != nil {
+		t.Fatalf("Failed to roll back: %v", err)
+	}
+	if value, err := v.GetSecret("db"); err != nil || value != "postgres://one" {
+		t.Errorf("GetSecret() = %q, %v after rollback", value, err)
+	}
+
+	if _, err := v.RestoreSecret("old-db", ""); err != nil {
+		t.Fatalf("Failed to restore secret: %v", err)
+	}
+	if _, err := v.RollbackSecret("old-db", 1); err != nil {
+		t.Fatalf("Failed to roll back restored secret: %v", err)
+	}
+	if value, err := v.GetSecret("old-db"); err != nil || value != "postgres://one" {
+		t.Errorf("GetSecret() = %q, %v after rolling back a restored secret", value, err)
+	}
+}
+
+func TestRotateMasterKeyKeepsPrevious(t *testing.T) {
+	v, cleanup := setupTestVault(t)
+	defer cleanup()
+
+	if err := v.Init("test-provisioning-key"); err != nil {
+		t.Fatalf("Failed to initialize vault: %v", err)
+	}
+	revokeAt := time.Now().Add(time.Hour)
+	for _, name := range []string{"api-key", "old-key"} {
+		if err := v.AddSecret(name, "sk-old", name+"-old"); err != nil {
+			t.Fatalf("Failed to add secret: %v", err)
+		}
+		if err := v.RotateSecretWithGrace(name, "sk-new", name+"-new", revokeAt); err != nil {
+			t.Fatalf("Failed to rotate with grace: %v", err)
+		}
+	}
+	if err := v.TrashSecret("old-key", false, false); err != nil {
+		t.Fatalf("Failed to trash secret: %v", err)
+	}
+
+	if err := v.RotateMasterKey(); err != nil {
+		t.Fatalf("Failed to rotate master key: %v", err)
+	}
+
+	if previous, err := v.GetPreviousSecret("api-key"); err != nil || previous != "sk-old" {
+		t.Errorf("GetPreviousSecret() = %q, %v after master key rotation", previous, err)
+	}
+	if _, err := v.RestoreSecret("old-key", ""); err != nil {
+		t.Fatalf("Failed to restore secret: %v", err)
+	}
+	if previous, err := v.GetPreviousSecret("old-key"); err != nil || previous != "sk-old" {
+		t.Errorf("GetPreviousSecret() = %q, %v for a restored secret", previous, err)
+	}
+	for _, d := range v.Diagnose() {
+		if strings.Contains(d.Message, "does not decrypt") {
+			t.Errorf("Diagnose() reported %q after master key rotation", d.Message)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package vault
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spacebarlabs/lean_vault/pkg/crypto"
+)
+
+// MaxHistory is how many replaced versions are kept per secret
+const MaxHistory = 10
+
+// Reasons recorded when a version is replaced
+const (
+	ReasonUpdate   = "update"
+	ReasonSet      = "set"
+	ReasonRotate   = "rotate"
+	ReasonGrace    = "rotate with grace period"
+	ReasonIncident = "incident"
+	ReasonRollback = "rollback"
+)
+
+// SecretVersion is a value a secret held before it was replaced
+type SecretVersion struct {
+	Version int    `yaml:"version,omitempty"`
+	Value   string `yaml:"value"`
+	ID      string `yaml:"id,omitempty"`
+	// CreatedAt is when the value was stored, if known
+	CreatedAt time.Time `yaml:"created_at,omitempty"`
+	// RetiredAt is when the version was replaced
+	RetiredAt time.Time `yaml:"retired_at"`
+	// Reason says why the version was replaced
+	Reason string `yaml:"reason,omitempty"`
+	// RevokeAt is when a version kept for a grace period should be revoked
+	RevokeAt *time.Time `yaml:"revoke_at,omitempty"`
+}
+
+// CurrentVersion returns the version number of the entry's value. Entries
+// written before versions were tracked are version 1.
+func (e SecretEntry) CurrentVersion() int {
+	if e.Version == 0 {
+		return 1
+	}
+	return e.Version
+}
+
+// retire moves the entry's current value into its history, dropping the
+// oldest versions beyond MaxHistory, and advances its version number. The
+// caller sets the new value.
+func (e *SecretEntry) retire(reason string, now time.Time) SecretVersion {
+	retired := SecretVersion{
+		Version:   e.CurrentVersion(),
+		Value:     e.Value,
+		ID:        e.ID,
+		CreatedAt: e.UpdatedAt,
+		RetiredAt: now,
+		Reason:    reason,
+	}
+
+	history := append([]SecretVersion(nil), e.History...)
+	history = append(history, retired)
+	if len(history) > MaxHistory {
+		history = history[len(history)-MaxHistory:]
+	}
+	e.History = history
+	e.Version = retired.Version + 1
+	return retired
+}
+
+// RotateSecretWithGrace replaces a secret's value but keeps the old one as
+// its previous version until revokeAt, so that services still using it keep
+// working. It fails if an earlier previous version is still waiting to be
+// revoked, since replacing it would leave that key active untracked.
+func (v *Vault) RotateSecretWithGrace(name, value, id string, revokeAt time.Time) error {
+	vaultData, masterKey, err := v.load()
+	if err != nil {
+		return err
+	}
+
+	secret, exists := vaultData.Secrets[name]
+	if !exists {
+		return fmt.Errorf("secret %s not found", name)
+	}
+	if secret.Previous != nil {
+		return fmt.Errorf("the previous value of %s is still in its grace period; rotate again once it has been revoked", name)
+	}
+
+	// Encrypt the secret value
+	encryptedValue, err := crypto.Encrypt(masterKey, []byte(value))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+
+	now := time.Now().UTC()
+	revokeAt = revokeAt.UTC()
+	previous := secret.retire(ReasonGrace, now)
+	previous.RevokeAt = &revokeAt
+	secret.Previous = &previous
+	secret.Value = encryptedValue
+	secret.ID = id
+	secret.UpdatedAt = now
+	vaultData.Secrets[name] = secret
+	recordFingerprint(vaultData, name, value)
+
+	return v.save(vaultData, masterKey)
+}
+
+// GetPreviousSecret retrieves the value a secret held before a rotation with
+// a grace period, while that value is still valid
+func (v *Vault) GetPreviousSecret(name string) (string, error) {
+	vaultData, masterKey, err := v.load()
+	if err != nil {
+		return "", err
+	}
+
+	secret, exists := vaultData.Secrets[name]
+	if !exists {
+		return "", fmt.Errorf("secret %s not found", name)
+	}
+	if secret.Previous == nil {
+		return "", fmt.Errorf("secret %s has no previous value in a grace period", name)
+	}
+
+	decrypted, err := crypto.Decrypt(masterKey, secret.Previous.Value)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+
+	return string(decrypted), nil
+}
+
+// GetPreviousSecrets returns the decrypted previous value of every secret
+// that still holds one. Until it is revoked, a previous value is a working
+// credential.
+func (v *Vault) GetPreviousSecrets() (map[string]string, error) {
+	vaultData, masterKey, err := v.load()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	for name, secret := range vaultData.Secrets {
+		if secret.Previous == nil {
+			continue
+		}
+		plaintext, err := crypto.Decrypt(masterKey, secret.Previous.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt previous value of %s: %w", name, err)
+		}
+		values[name] = string(plaintext)
+	}
+	return values, nil
+}
+
+// PendingRevocations returns the previous versions whose grace period has ended
+func (v *Vault) PendingRevocations(now time.Time) (map[string]SecretVersion, error) {
+	entries, err := v.ListSecretEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make(map[string]SecretVersion)
+	for name, entry := range entries {
+		if p := entry.Previous; p != nil && p.RevokeAt != nil && !now.Before(*p.RevokeAt) {
+			pending[name] = *p
+		}
+	}
+	return pending, nil
+}
+
+// ClearPrevious drops a secret's previous version once it has been revoked.
+// The ID guards against clearing a newer previous version.
+func (v *Vault) ClearPrevious(name, id string) error {
+	vaultData, masterKey, err := v.load()
+	if err != nil {
+		return err
+	}
+
+	secret, exists := vaultData.Secrets[name]
+	if !exists {
+		return fmt.Errorf("secret %s not found", name)
+	}
+	if secret.Previous == nil || secret.Previous.ID != id {
+		return nil
+	}
+
+	secret.Previous = nil
+	vaultData.Secrets[name] = secret
+	return v.save(vaultData, masterKey)
+}
+
+// RollbackSecret makes an earlier version from the secret's history current
+// again. The replaced value is kept in the history like any other update. If
+// the target is the previous version waiting out a grace period, its
+// scheduled revocation is cancelled.
+func (v *Vault) RollbackSecret(name string, version int) (SecretVersion, error) {
+	vaultData, masterKey, err := v.load()
+	if err != nil {
+		return SecretVersion{}, err
+	}
+
+	secret, exists := vaultData.Secrets[name]
+	if !exists {
+		return SecretVersion{}, fmt.Errorf("secret %s not found", name)
+	}
+	if version == secret.CurrentVersion() {
+		return SecretVersion{}, fmt.Errorf("version %d is already the current version of %s", version, name)
+	}
+
+	var target *SecretVersion
+	for i := range secret.History {
+		if secret.History[i].Version == version {
+			target = &secret.History[i]
+			break
+		}
+	}
+	if target == nil {
+		return SecretVersion{}, fmt.Errorf("version %d of %s is not in its history", version, name)
+	}
+	restored := *target
+
+	now := time.Now().UTC()
+	secret.retire(fmt.Sprintf("%s to version %d", ReasonRollback, version), now)
+	secret.Value = restored.Value
+	secret.ID = restored.ID
+	secret.UpdatedAt = now
+	if secret.Previous != nil && secret.Previous.Version == version {
+		secret.Previous = nil
+	}
+	vaultData.Secrets[name] = secret
+
+	return restored, v.save(vaultData, masterKey)
+}